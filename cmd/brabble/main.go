@@ -29,11 +29,18 @@ and fires a configurable hook (default: ../warelay send "Voice brabble from ${ho
 Key commands:
   start|stop|restart        Daemon lifecycle
   status [--json]           Uptime + last transcripts
+  watch [--json] [--filter] Stream live events (transcripts, hooks, mic)
+  pause [--for 30m]|resume  Close/reopen the mic without stopping
+  mute-hooks|unmute-hooks   Keep transcribing, suppress hooks
+  ptt start|stop|toggle     Push-to-talk (wake.mode = "push_to_talk")
   mic list|set              Select microphone (alias: microphone, mics)
   doctor|setup              Check deps / download default model
-  models list|download|set  Manage whisper.cpp models
+  models list|download|set|use  Manage whisper.cpp models
   service install|uninstall|status   launchd helper (macOS)
   health|tail-log|test-hook Liveness, log tail, manual hook
+  hook render "text"        Preview a hook's rendered args/body
+  hook dlq list|replay|purge  Failed hook jobs (dead letters)
+  inject "text"             Route text through the running daemon
 
 Notable flags/env:
   --metrics-addr <addr>     Enable /metrics (Prometheus text)
@@ -46,6 +53,7 @@ Notable flags/env:
   brabble mic set --index 1
   brabble models download ggml-medium-q5_1.bin
   brabble models set ggml-medium-q5_1.bin
+  brabble models use ggml-small-q5_1.bin --live
   brabble service install --env BRABBLE_METRICS_ADDR=127.0.0.1:9317
   brabble health
   brabble test-hook "make it so"`,
@@ -65,12 +73,20 @@ Notable flags/env:
 	root.AddCommand(control.NewTailLogCmd(cfgPath))
 	root.AddCommand(control.NewMicCmd(cfgPath))
 	root.AddCommand(control.NewTestHookCmd(cfgPath))
+	root.AddCommand(control.NewHookCmd(cfgPath))
+	root.AddCommand(control.NewInjectCmd(cfgPath))
 	root.AddCommand(control.NewDoctorCmd(cfgPath))
 	root.AddCommand(control.NewServiceRootCmd(cfgPath))
 	root.AddCommand(control.NewSetupCmd(cfgPath))
 	root.AddCommand(control.NewHealthCmd(cfgPath))
 	root.AddCommand(control.NewTranscribeCmd(cfgPath))
 	root.AddCommand(control.NewModelsCmd(cfgPath))
+	root.AddCommand(control.NewPauseCmd(cfgPath))
+	root.AddCommand(control.NewResumeCmd(cfgPath))
+	root.AddCommand(control.NewMuteHooksCmd(cfgPath))
+	root.AddCommand(control.NewUnmuteHooksCmd(cfgPath))
+	root.AddCommand(control.NewPTTCmd(cfgPath))
+	root.AddCommand(control.NewWatchCmd(cfgPath))
 
 	// Hidden internal serve command used by start.
 	root.AddCommand(daemon.NewServeCmd(cfgPath))
@@ -105,14 +121,21 @@ func applyColorHelp(root *cobra.Command) {
 		write("%sKey commands%s\n", bold, reset)
 		writeln("  start|stop|restart          daemon lifecycle")
 		writeln("  status [--json]             uptime + last transcripts")
+		writeln("  watch [--json] [--filter T]  stream live events (transcripts, hooks, mic)")
+		writeln("  pause [--for 30m]|resume    close/reopen the mic; model stays loaded")
+		writeln("  mute-hooks|unmute-hooks     keep transcribing, suppress hook dispatch")
+		writeln("  ptt start|stop|toggle       push-to-talk capture (wake.mode = push_to_talk)")
 		writeln("  mic list|set                select input device (alias: microphone, mics)")
 		writeln("  doctor                      check deps/model/hook/portaudio")
 		writeln("  setup                       download default whisper model")
-		writeln("  models list|download|set    manage whisper.cpp models")
+		writeln("  models list|download|set|use manage whisper.cpp models (use --live hot-swaps)")
 		writeln("  service install|uninstall|status manage launchd plist (macOS)")
 		writeln("  health                      control-socket liveness ping")
 		writeln("  tail-log                    show last log lines")
 		writeln("  test-hook \"text\"           invoke hook manually")
+		writeln("  hook render \"text\"         preview rendered hook args/env/body")
+		writeln("  hook dlq list|replay|purge  inspect/retry/drop hook jobs that failed all retries")
+		writeln("  inject \"text\" [--raw]      route text through the running daemon")
 		writeln("")
 
 		write("%sNotable flags & env%s\n", bold, reset)
@@ -131,6 +154,7 @@ func applyColorHelp(root *cobra.Command) {
 		writeln("  brabble models download ggml-medium-q5_1.bin")
 		writeln("  brabble models download ggml-large-v3-turbo-q8_0.bin")
 		writeln("  brabble models set ggml-large-v3-turbo-q8_0.bin")
+		writeln("  brabble models use ggml-medium-q5_1.bin --live")
 		writeln("  brabble service install --env BRABBLE_METRICS_ADDR=127.0.0.1:9317")
 		writeln("  brabble health")
 		writeln("  brabble test-hook \"make it so\"")
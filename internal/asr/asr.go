@@ -10,11 +10,21 @@ import (
 
 // Segment is a recognized piece of text.
 type Segment struct {
-	Text       string
-	Start      time.Time
-	End        time.Time
+	Text  string
+	Start time.Time // capture time of the first sample
+	End   time.Time // capture time of the last voiced sample
+	// Transcribed is when ASR finished; End→Transcribed covers queueing and
+	// decoding.
+	Transcribed time.Time
+	// Confidence is the mean probability of the decoded text tokens, 0 when
+	// unknown; Language is the language decoded, as detected when
+	// asr.language is "auto".
 	Confidence float64
+	Language   string
 	Partial    bool
+	// PushToTalk marks speech captured while push-to-talk was held; it is
+	// dispatched without requiring the wake word.
+	PushToTalk bool
 }
 
 // Recognizer converts audio into segments.
@@ -0,0 +1,83 @@
+package asr
+
+import (
+	"sync/atomic"
+
+	"brabble/internal/logging"
+)
+
+// segmentQueueSize bounds the capture→ASR backlog. Capture never blocks on it;
+// chunks are shed instead (partials first).
+const segmentQueueSize = 8
+
+// BacklogStats describes the capture→ASR queue.
+type BacklogStats struct {
+	Depth           int
+	Capacity        int
+	DroppedPartials int64
+	DroppedFinals   int64
+}
+
+// backlog is the capture→ASR queue plus its shedding counters.
+type backlog struct {
+	queue           chan segmentChunk
+	droppedPartials atomic.Int64
+	droppedFinals   atomic.Int64
+}
+
+func newBacklog(size int) *backlog {
+	return &backlog{queue: make(chan segmentChunk, size)}
+}
+
+func (b *backlog) stats() BacklogStats {
+	return BacklogStats{
+		Depth:           len(b.queue),
+		Capacity:        cap(b.queue),
+		DroppedPartials: b.droppedPartials.Load(),
+		DroppedFinals:   b.droppedFinals.Load(),
+	}
+}
+
+// enqueue queues c without blocking. When the queue is full, a partial is
+// dropped outright; a final first evicts any queued partials, since a final
+// supersedes the live feedback they carry. Only the capture goroutine sends,
+// so the evicted slots cannot be taken by another producer.
+func (b *backlog) enqueue(c segmentChunk, logger *logging.Logger) bool {
+	select {
+	case b.queue <- c:
+		return true
+	default:
+	}
+	if c.partial {
+		b.droppedPartials.Add(1)
+		logger.Warn("segment queue full, dropping partial")
+		return false
+	}
+	n := len(b.queue)
+	kept := make([]segmentChunk, 0, n)
+	for i := 0; i < n; i++ {
+		select {
+		case q := <-b.queue:
+			if q.partial {
+				b.droppedPartials.Add(1)
+				continue
+			}
+			kept = append(kept, q)
+		default:
+		}
+	}
+	if evicted := n - len(kept); evicted > 0 {
+		logger.Warnf("segment queue full, shed %d partial(s) for a final", evicted)
+	}
+	for _, q := range kept {
+		b.queue <- q // cannot block: at least len(kept) slots were just freed
+	}
+	select {
+	case b.queue <- c:
+		return true
+	default:
+		b.droppedFinals.Add(1)
+		logger.Warn("segment queue full, dropping segment")
+		return false
+	}
+}
@@ -0,0 +1,45 @@
+package asr
+
+import (
+	"testing"
+
+	"brabble/internal/logging"
+)
+
+func TestBacklogShedsPartialsBeforeFinals(t *testing.T) {
+	b := newBacklog(3)
+	logger := logging.NewTestLogger()
+	b.enqueue(segmentChunk{partial: true}, logger)
+	b.enqueue(segmentChunk{pcm: []int16{1}}, logger)
+	b.enqueue(segmentChunk{partial: true}, logger)
+
+	if b.enqueue(segmentChunk{partial: true}, logger) {
+		t.Fatal("partial should not fit in a full queue")
+	}
+	if !b.enqueue(segmentChunk{pcm: []int16{2}}, logger) {
+		t.Fatal("final should evict queued partials")
+	}
+	st := b.stats()
+	if st.Depth != 2 || st.DroppedPartials != 3 || st.DroppedFinals != 0 {
+		t.Fatalf("stats=%+v", st)
+	}
+	// Finals keep their order.
+	if first := <-b.queue; first.pcm[0] != 1 {
+		t.Fatalf("first final=%v", first.pcm)
+	}
+	if second := <-b.queue; second.pcm[0] != 2 {
+		t.Fatalf("second final=%v", second.pcm)
+	}
+}
+
+func TestBacklogDropsFinalWhenOnlyFinalsQueued(t *testing.T) {
+	b := newBacklog(1)
+	logger := logging.NewTestLogger()
+	b.enqueue(segmentChunk{pcm: []int16{1}}, logger)
+	if b.enqueue(segmentChunk{pcm: []int16{2}}, logger) {
+		t.Fatal("final should be dropped when no partial can be shed")
+	}
+	if st := b.stats(); st.DroppedFinals != 1 || st.Depth != 1 {
+		t.Fatalf("stats=%+v", st)
+	}
+}
@@ -0,0 +1,123 @@
+package asr
+
+import (
+	"fmt"
+	"strings"
+)
+
+// Channel modes for multi-channel capture.
+const (
+	channelDownmix = "downmix"
+	channelSelect  = "select"
+	channelLoudest = "loudest"
+)
+
+// loudestSmoothing is the per-frame weight of new energy in the "loudest"
+// tracker (roughly a 200 ms window at 20 ms frames).
+const loudestSmoothing = 0.1
+
+// channelMixer folds interleaved multi-channel frames into the mono stream
+// VAD and whisper expect. In "loudest" mode the choice is re-evaluated every
+// frame while idle and held for the duration of a speech segment so a segment
+// is never stitched together from different mics.
+type channelMixer struct {
+	mode     string
+	channels int
+	channel  int
+	energy   []float64
+	locked   bool
+	mono     []int16
+}
+
+func validateChannels(mode string, channels, channel int) error {
+	if channels < 1 {
+		return fmt.Errorf("audio.channels must be >= 1 (got %d)", channels)
+	}
+	switch normalizeChannelMode(mode) {
+	case channelDownmix, channelLoudest:
+	case channelSelect:
+		if channel < 0 || channel >= channels {
+			return fmt.Errorf("audio.channel %d out of range for %d channels", channel, channels)
+		}
+	default:
+		return fmt.Errorf("audio.channel_mode must be downmix, select, or loudest (got %q)", mode)
+	}
+	return nil
+}
+
+func normalizeChannelMode(mode string) string {
+	mode = strings.ToLower(strings.TrimSpace(mode))
+	if mode == "" {
+		return channelDownmix
+	}
+	return mode
+}
+
+func newChannelMixer(mode string, channels, channel int) *channelMixer {
+	m := &channelMixer{
+		mode:     normalizeChannelMode(mode),
+		channels: channels,
+		channel:  channel,
+		energy:   make([]float64, channels),
+	}
+	if m.mode == channelLoudest {
+		m.channel = 0
+	}
+	return m
+}
+
+// mix returns the mono frame for interleaved input. Mono input is returned
+// as-is; otherwise the result aliases an internal buffer reused per call.
+func (m *channelMixer) mix(in []int16) []int16 {
+	if m.channels == 1 {
+		return in
+	}
+	frames := len(in) / m.channels
+	if cap(m.mono) < frames {
+		m.mono = make([]int16, frames)
+	}
+	out := m.mono[:frames]
+	switch m.mode {
+	case channelDownmix:
+		for i := 0; i < frames; i++ {
+			var sum int
+			for c := 0; c < m.channels; c++ {
+				sum += int(in[i*m.channels+c])
+			}
+			out[i] = int16(sum / m.channels)
+		}
+		return out
+	case channelLoudest:
+		m.trackEnergy(in, frames)
+	}
+	for i := 0; i < frames; i++ {
+		out[i] = in[i*m.channels+m.channel]
+	}
+	return out
+}
+
+func (m *channelMixer) trackEnergy(in []int16, frames int) {
+	if frames == 0 {
+		return
+	}
+	best := m.channel
+	for c := 0; c < m.channels; c++ {
+		var sum float64
+		for i := 0; i < frames; i++ {
+			s := float64(in[i*m.channels+c])
+			sum += s * s
+		}
+		m.energy[c] += loudestSmoothing * (sum/float64(frames) - m.energy[c])
+		if m.energy[c] > m.energy[best] {
+			best = c
+		}
+	}
+	if !m.locked {
+		m.channel = best
+	}
+}
+
+// hold pins the current channel choice while a segment is being captured.
+func (m *channelMixer) hold(locked bool) {
+	m.locked = locked
+}
@@ -0,0 +1,70 @@
+package asr
+
+import "testing"
+
+func TestValidateChannels(t *testing.T) {
+	cases := []struct {
+		mode     string
+		channels int
+		channel  int
+		ok       bool
+	}{
+		{"", 1, 0, true},
+		{"downmix", 4, 0, true},
+		{"select", 2, 1, true},
+		{"select", 2, 2, false},
+		{"loudest", 4, 0, true},
+		{"beamform", 2, 0, false},
+		{"downmix", 0, 0, false},
+	}
+	for _, c := range cases {
+		err := validateChannels(c.mode, c.channels, c.channel)
+		if (err == nil) != c.ok {
+			t.Fatalf("validateChannels(%q,%d,%d) err=%v want ok=%v", c.mode, c.channels, c.channel, err, c.ok)
+		}
+	}
+}
+
+func TestChannelMixerMonoPassthrough(t *testing.T) {
+	m := newChannelMixer("", 1, 0)
+	in := []int16{1, 2, 3, 4}
+	if out := m.mix(in); &out[0] != &in[0] {
+		t.Fatal("mono input should not be copied")
+	}
+}
+
+func TestChannelMixerDownmixAndSelect(t *testing.T) {
+	in := []int16{100, 300, -200, 0} // two stereo frames
+	down := newChannelMixer("downmix", 2, 0).mix(in)
+	if down[0] != 200 || down[1] != -100 {
+		t.Fatalf("downmix=%v", down)
+	}
+	sel := newChannelMixer("select", 2, 1).mix(in)
+	if sel[0] != 300 || sel[1] != 0 {
+		t.Fatalf("select=%v", sel)
+	}
+}
+
+func TestChannelMixerLoudestHoldsDuringSegment(t *testing.T) {
+	m := newChannelMixer("loudest", 2, 0)
+	right := []int16{10, 5000, -10, -5000}
+	left := []int16{5000, 10, -5000, -10}
+	for i := 0; i < 20; i++ {
+		m.mix(right)
+	}
+	if m.channel != 1 {
+		t.Fatalf("loudest channel=%d want 1", m.channel)
+	}
+	m.hold(true)
+	for i := 0; i < 50; i++ {
+		m.mix(left)
+	}
+	if m.channel != 1 {
+		t.Fatal("channel switched mid-segment")
+	}
+	m.hold(false)
+	m.mix(left)
+	if m.channel != 0 {
+		t.Fatalf("channel=%d after segment, want 0", m.channel)
+	}
+}
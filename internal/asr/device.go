@@ -0,0 +1,163 @@
+package asr
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+
+	"brabble/internal/config"
+
+	"github.com/gordonklaus/portaudio"
+)
+
+// errRescan stops the capture loop so devices can be re-enumerated.
+var errRescan = errors.New("device rescan")
+
+// Device selection sources reported by pickDevice, highest priority first.
+const (
+	viaPreferred = "preferred_devices"
+	viaIdentity  = "device_id"
+	viaIndex     = "device_index"
+	viaName      = "device_name"
+	viaDefault   = "system default"
+	viaFirst     = "first input"
+)
+
+// deviceSelection is the subset of [audio] config that picks an input.
+type deviceSelection struct {
+	preferred []string
+	id        config.DeviceID
+	name      string
+	index     int
+}
+
+func selectionFromConfig(cfg *config.Config) deviceSelection {
+	return deviceSelection{
+		preferred: cfg.Audio.PreferredDevices,
+		id:        cfg.Audio.DeviceID,
+		name:      cfg.Audio.DeviceName,
+		index:     cfg.Audio.DeviceIndex,
+	}
+}
+
+// selectDevice enumerates PortAudio inputs and picks one per config. It also
+// reports which setting made the choice.
+func selectDevice(cfg *config.Config) (*portaudio.DeviceInfo, string, error) {
+	devs, err := portaudio.Devices()
+	if err != nil {
+		return nil, "", fmt.Errorf("list devices: %w", err)
+	}
+	def, _ := portaudio.DefaultInputDevice()
+	return pickDevice(devs, def, selectionFromConfig(cfg))
+}
+
+// pickDevice chooses an input device in priority order: the first entry of
+// preferred that matches a present device, then the saved device identity,
+// then the configured index, then the configured name, then the system
+// default, then any input at all. Names match case-insensitively by substring.
+func pickDevice(devs []*portaudio.DeviceInfo, def *portaudio.DeviceInfo, sel deviceSelection) (*portaudio.DeviceInfo, string, error) {
+	for _, want := range sel.preferred {
+		if d := findInputByName(devs, want); d != nil {
+			return d, viaPreferred, nil
+		}
+	}
+	if d := MatchDevice(devs, sel.id); d != nil {
+		return d, viaIdentity, nil
+	}
+	if sel.index >= 0 && sel.index < len(devs) {
+		d := devs[sel.index]
+		if d.MaxInputChannels > 0 {
+			return d, viaIndex, nil
+		}
+	}
+	if d := findInputByName(devs, sel.name); d != nil {
+		return d, viaName, nil
+	}
+	if def != nil {
+		return def, viaDefault, nil
+	}
+	for _, d := range devs {
+		if d.MaxInputChannels > 0 {
+			return d, viaFirst, nil
+		}
+	}
+	return nil, "", fmt.Errorf("no input devices found")
+}
+
+// DeviceIdentity describes d in the form persisted by `mic set`.
+func DeviceIdentity(d *portaudio.DeviceInfo) config.DeviceID {
+	id := config.DeviceID{Name: d.Name, Channels: d.MaxInputChannels}
+	if d.HostApi != nil {
+		id.HostAPI = d.HostApi.Name
+	}
+	return id
+}
+
+// MatchDevice resolves a saved identity against the current input devices.
+// The name must match exactly (ignoring case); among those, a matching host
+// API and then channel count break ties, so an identically named device on
+// another backend is only used when nothing better exists. Returns nil when
+// id is empty or no input device has that name.
+func MatchDevice(devs []*portaudio.DeviceInfo, id config.DeviceID) *portaudio.DeviceInfo {
+	if strings.TrimSpace(id.Name) == "" {
+		return nil
+	}
+	var best *portaudio.DeviceInfo
+	bestScore := -1
+	for _, d := range devs {
+		if d.MaxInputChannels < 1 || !strings.EqualFold(d.Name, id.Name) {
+			continue
+		}
+		score := 0
+		got := DeviceIdentity(d)
+		if id.HostAPI != "" && strings.EqualFold(got.HostAPI, id.HostAPI) {
+			score += 2
+		}
+		if id.Channels > 0 && got.Channels == id.Channels {
+			score++
+		}
+		if score > bestScore {
+			best, bestScore = d, score
+		}
+	}
+	return best
+}
+
+// preferenceRank is the position in preferred of the first entry matching
+// name, or len(preferred) when none does. Rank 0 cannot be outranked, so a
+// rescan would only reopen the same device.
+func preferenceRank(preferred []string, name string) int {
+	name = strings.ToLower(name)
+	for i, want := range preferred {
+		if want = strings.ToLower(strings.TrimSpace(want)); want != "" && strings.Contains(name, want) {
+			return i
+		}
+	}
+	return len(preferred)
+}
+
+func findInputByName(devs []*portaudio.DeviceInfo, name string) *portaudio.DeviceInfo {
+	name = strings.ToLower(strings.TrimSpace(name))
+	if name == "" {
+		return nil
+	}
+	for _, d := range devs {
+		if d.MaxInputChannels > 0 && strings.Contains(strings.ToLower(d.Name), name) {
+			return d
+		}
+	}
+	return nil
+}
+
+// refreshDevices re-initializes PortAudio; its device list is a snapshot
+// taken at init, so this is the only way to see hot-plugged devices. Any open
+// stream must be closed first.
+func refreshDevices() error {
+	if err := portaudio.Terminate(); err != nil {
+		return fmt.Errorf("portaudio terminate: %w", err)
+	}
+	if err := portaudio.Initialize(); err != nil {
+		return fmt.Errorf("portaudio init: %w", err)
+	}
+	return nil
+}
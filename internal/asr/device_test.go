@@ -0,0 +1,108 @@
+package asr
+
+import (
+	"testing"
+
+	"brabble/internal/config"
+
+	"github.com/gordonklaus/portaudio"
+)
+
+func testDevices() []*portaudio.DeviceInfo {
+	coreAudio := &portaudio.HostApiInfo{Name: "Core Audio"}
+	return []*portaudio.DeviceInfo{
+		{Name: "MacBook Pro Microphone", MaxInputChannels: 1, HostApi: coreAudio},
+		{Name: "MacBook Pro Speakers", MaxInputChannels: 0, HostApi: coreAudio},
+		{Name: "Jo's AirPods Pro", MaxInputChannels: 1, HostApi: coreAudio},
+		{Name: "Studio Display Microphone", MaxInputChannels: 1, HostApi: coreAudio},
+	}
+}
+
+func TestPickDevicePreferredOrder(t *testing.T) {
+	devs := testDevices()
+	sel := deviceSelection{preferred: []string{"airpods", "MacBook Pro Microphone"}, name: "Studio", index: 3}
+	d, via, err := pickDevice(devs, devs[0], sel)
+	if err != nil || d != devs[2] || via != viaPreferred {
+		t.Fatalf("got %v via %q err=%v, want AirPods", d, via, err)
+	}
+	// AirPods unplugged: next preference wins over device_name/device_index.
+	without := append([]*portaudio.DeviceInfo{}, devs[:2]...)
+	without = append(without, devs[3])
+	sel.index = 2
+	d, _, err = pickDevice(without, without[2], sel)
+	if err != nil || d != without[0] {
+		t.Fatalf("got %v err=%v, want built-in mic", d, err)
+	}
+}
+
+func TestPreferenceRank(t *testing.T) {
+	preferred := []string{"airpods", " MacBook Pro Microphone "}
+	cases := map[string]int{
+		"Jo's AirPods Pro":          0,
+		"MacBook Pro Microphone":    1,
+		"Studio Display Microphone": 2,
+	}
+	for name, want := range cases {
+		if got := preferenceRank(preferred, name); got != want {
+			t.Fatalf("%s: rank %d, want %d", name, got, want)
+		}
+	}
+}
+
+func TestPickDeviceFallbacks(t *testing.T) {
+	devs := testDevices()
+	cases := []struct {
+		sel  deviceSelection
+		def  *portaudio.DeviceInfo
+		want *portaudio.DeviceInfo
+		via  string
+	}{
+		{deviceSelection{preferred: []string{"Yeti"}, index: 3}, devs[0], devs[3], viaIndex},
+		{deviceSelection{name: "studio", index: 1}, devs[0], devs[3], viaName}, // output-only index skipped
+		{deviceSelection{name: "speakers", index: -1}, devs[2], devs[2], viaDefault},
+		{deviceSelection{index: -1}, nil, devs[0], viaFirst},
+	}
+	for i, c := range cases {
+		d, via, err := pickDevice(devs, c.def, c.sel)
+		if err != nil || d != c.want || via != c.via {
+			t.Fatalf("case %d: got %v via %q err=%v", i, d, via, err)
+		}
+	}
+	if _, _, err := pickDevice(devs[1:2], nil, deviceSelection{index: -1}); err == nil {
+		t.Fatal("expected error with no input devices")
+	}
+}
+
+func TestPickDeviceIdentitySurvivesIndexShift(t *testing.T) {
+	devs := testDevices()
+	id := DeviceIdentity(devs[3])
+	// A new device enumerates first and shifts every index by one.
+	shifted := append([]*portaudio.DeviceInfo{{Name: "USB Audio", MaxInputChannels: 2}}, devs...)
+	d, via, err := pickDevice(shifted, shifted[1], deviceSelection{id: id, index: 3})
+	if err != nil || d != devs[3] || via != viaIdentity {
+		t.Fatalf("got %v via %q err=%v, want Studio Display", d, via, err)
+	}
+}
+
+func TestMatchDevicePrefersHostAPIAndChannels(t *testing.T) {
+	coreAudio := &portaudio.HostApiInfo{Name: "Core Audio"}
+	jack := &portaudio.HostApiInfo{Name: "JACK Audio Connection Kit"}
+	devs := []*portaudio.DeviceInfo{
+		{Name: "Scarlett 2i2", MaxInputChannels: 2, HostApi: jack},
+		{Name: "Scarlett 2i2", MaxInputChannels: 1, HostApi: coreAudio},
+		{Name: "Scarlett 2i2", MaxInputChannels: 2, HostApi: coreAudio},
+	}
+	want := config.DeviceID{Name: "scarlett 2i2", HostAPI: "Core Audio", Channels: 2}
+	if d := MatchDevice(devs, want); d != devs[2] {
+		t.Fatalf("got %+v, want Core Audio 2ch", d)
+	}
+	if d := MatchDevice(devs, config.DeviceID{Name: "Scarlett 2i2", HostAPI: "ALSA", Channels: 1}); d != devs[1] {
+		t.Fatalf("channel tie-break got %+v", d)
+	}
+	if d := MatchDevice(devs, config.DeviceID{Name: "Scarlett"}); d != nil {
+		t.Fatalf("identity names must match exactly, got %+v", d)
+	}
+	if d := MatchDevice(devs, config.DeviceID{}); d != nil {
+		t.Fatalf("empty identity matched %+v", d)
+	}
+}
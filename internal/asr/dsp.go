@@ -0,0 +1,318 @@
+package asr
+
+import (
+	"math"
+	"math/cmplx"
+
+	"brabble/internal/config"
+)
+
+const (
+	defaultHighPassHz    = 80.0
+	defaultAGCTargetDBFS = -20.0
+	defaultAGCMaxGainDB  = 30.0
+	// agcGateDBFS keeps the AGC from chasing room tone: frames quieter than
+	// this hold the current gain instead of pulling it up.
+	agcGateDBFS = -65.0
+	// agcMaxCutDB bounds how far loud input is attenuated.
+	agcMaxCutDB = 20.0
+)
+
+// dspChain is the optional cleanup applied to every captured frame before VAD.
+// Stages run in a fixed order: high-pass (DC removal), noise suppression, then
+// AGC, so the gain stage never amplifies noise the suppressor could remove.
+// Disabled stages are nil.
+type dspChain struct {
+	highPass *highPassFilter
+	denoise  *noiseSuppressor
+	agc      *gainControl
+	scratch  []float64
+}
+
+// newDSPChain builds the stages enabled in [audio]; it returns nil when all
+// stages are off so the capture loop can skip conversion entirely.
+func newDSPChain(cfg *config.Config, sampleRate, frameSamples int) *dspChain {
+	a := cfg.Audio
+	c := &dspChain{}
+	if a.HighPass {
+		c.highPass = newHighPassFilter(a.HighPassHz, sampleRate)
+	}
+	if a.NoiseSuppression {
+		c.denoise = newNoiseSuppressor(frameSamples)
+	}
+	if a.AGC {
+		c.agc = newGainControl(a.AGCTargetDBFS, a.AGCMaxGainDB, sampleRate)
+	}
+	if c.highPass == nil && c.denoise == nil && c.agc == nil {
+		return nil
+	}
+	return c
+}
+
+// process filters frame in place.
+func (c *dspChain) process(frame []int16) {
+	if cap(c.scratch) < len(frame) {
+		c.scratch = make([]float64, len(frame))
+	}
+	x := c.scratch[:len(frame)]
+	for i, s := range frame {
+		x[i] = float64(s) / 32768.0
+	}
+	if c.highPass != nil {
+		c.highPass.process(x)
+	}
+	if c.denoise != nil {
+		c.denoise.process(x)
+	}
+	if c.agc != nil {
+		c.agc.process(x)
+	}
+	for i, v := range x {
+		frame[i] = floatToInt16(v)
+	}
+}
+
+func floatToInt16(v float64) int16 {
+	v *= 32768.0
+	switch {
+	case v > math.MaxInt16:
+		return math.MaxInt16
+	case v < math.MinInt16:
+		return math.MinInt16
+	default:
+		return int16(math.Round(v))
+	}
+}
+
+// highPassFilter is a first-order RC high-pass; at the default 80 Hz corner it
+// removes DC offset and handling rumble without touching speech.
+type highPassFilter struct {
+	alpha  float64
+	prevIn float64
+	prevY  float64
+}
+
+func newHighPassFilter(cutoffHz float64, sampleRate int) *highPassFilter {
+	if cutoffHz <= 0 {
+		cutoffHz = defaultHighPassHz
+	}
+	rc := 1 / (2 * math.Pi * cutoffHz)
+	dt := 1 / float64(sampleRate)
+	return &highPassFilter{alpha: rc / (rc + dt)}
+}
+
+func (f *highPassFilter) process(x []float64) {
+	for i, in := range x {
+		y := f.alpha * (f.prevY + in - f.prevIn)
+		f.prevIn = in
+		f.prevY = y
+		x[i] = y
+	}
+}
+
+// gainControl normalizes frame level toward a target dBFS. Gain drops quickly
+// (attack) when input gets loud and rises slowly (release) when it gets quiet,
+// and is interpolated across each frame to avoid zipper noise.
+type gainControl struct {
+	targetDB  float64
+	maxGainDB float64
+	gainDB    float64
+	attack    float64 // time constants in seconds
+	release   float64
+	rate      float64
+}
+
+func newGainControl(targetDBFS, maxGainDB float64, sampleRate int) *gainControl {
+	if targetDBFS == 0 {
+		targetDBFS = defaultAGCTargetDBFS
+	}
+	if maxGainDB <= 0 {
+		maxGainDB = defaultAGCMaxGainDB
+	}
+	return &gainControl{
+		targetDB:  targetDBFS,
+		maxGainDB: maxGainDB,
+		attack:    0.01,
+		release:   0.4,
+		rate:      float64(sampleRate),
+	}
+}
+
+func (g *gainControl) process(x []float64) {
+	if len(x) == 0 {
+		return
+	}
+	level := floatRMSDb(x)
+	prev := g.gainDB
+	if level > agcGateDBFS {
+		want := math.Max(-agcMaxCutDB, math.Min(g.maxGainDB, g.targetDB-level))
+		tau := g.release
+		if want < g.gainDB {
+			tau = g.attack
+		}
+		coef := math.Exp(-float64(len(x)) / g.rate / tau)
+		g.gainDB = want + (g.gainDB-want)*coef
+	}
+	from, to := dbToLinear(prev), dbToLinear(g.gainDB)
+	step := (to - from) / float64(len(x))
+	for i := range x {
+		x[i] *= from + step*float64(i+1)
+	}
+}
+
+// noiseSuppressor performs spectral subtraction with weighted overlap-add:
+// each frame is analysed together with the previous one through a sqrt-Hann
+// window (hop = one frame), so output lags input by exactly one frame. The
+// noise floor is tracked per bin: it falls quickly, follows noise-like rises,
+// and ignores bins well above the floor so speech barely moves it.
+type noiseSuppressor struct {
+	n       int // hop (frame) size
+	window  []float64
+	prevIn  []float64
+	tail    []float64
+	spec    []complex128
+	noise   []float64
+	gain    []float64
+	frames  int
+	overSub float64
+	floor   float64
+}
+
+const noiseInitFrames = 10
+
+func newNoiseSuppressor(frameSamples int) *noiseSuppressor {
+	n := frameSamples
+	size := nextPow2(2 * n)
+	window := make([]float64, 2*n)
+	for i := range window {
+		window[i] = math.Sqrt(0.5 * (1 - math.Cos(2*math.Pi*float64(i)/float64(2*n))))
+	}
+	bins := size/2 + 1
+	gain := make([]float64, bins)
+	for i := range gain {
+		gain[i] = 1
+	}
+	return &noiseSuppressor{
+		n:       n,
+		window:  window,
+		prevIn:  make([]float64, n),
+		tail:    make([]float64, n),
+		spec:    make([]complex128, size),
+		noise:   make([]float64, bins),
+		gain:    gain,
+		overSub: 1.5,
+		floor:   0.1,
+	}
+}
+
+func (ns *noiseSuppressor) process(x []float64) {
+	if len(x) != ns.n {
+		return
+	}
+	n := ns.n
+	for i := range ns.spec {
+		ns.spec[i] = 0
+	}
+	for i := 0; i < n; i++ {
+		ns.spec[i] = complex(ns.prevIn[i]*ns.window[i], 0)
+		ns.spec[n+i] = complex(x[i]*ns.window[n+i], 0)
+	}
+	copy(ns.prevIn, x)
+	fft(ns.spec, false)
+
+	size := len(ns.spec)
+	ns.frames++
+	for k := range ns.noise {
+		mag := cmplx.Abs(ns.spec[k])
+		switch {
+		case ns.frames <= noiseInitFrames:
+			ns.noise[k] += (mag - ns.noise[k]) / float64(ns.frames)
+		case mag < ns.noise[k]:
+			ns.noise[k] = 0.9*ns.noise[k] + 0.1*mag
+		case mag < 3*ns.noise[k]:
+			// Noise-like bin: follow a rising floor (fan turning on) within ~1s.
+			ns.noise[k] = 0.98*ns.noise[k] + 0.02*mag
+		default:
+			// Likely speech: barely move so sustained vowels are not eaten.
+			ns.noise[k] = 0.9995*ns.noise[k] + 0.0005*mag
+		}
+		g := ns.floor
+		if mag > 0 {
+			g = math.Max(ns.floor, 1-ns.overSub*ns.noise[k]/mag)
+		}
+		// Temporal smoothing keeps isolated bins from "chirping".
+		ns.gain[k] = 0.5*ns.gain[k] + 0.5*g
+		ns.spec[k] *= complex(ns.gain[k], 0)
+		if k > 0 && k < size/2 {
+			ns.spec[size-k] = cmplx.Conj(ns.spec[k])
+		}
+	}
+	fft(ns.spec, true)
+	for i := 0; i < n; i++ {
+		x[i] = ns.tail[i] + real(ns.spec[i])*ns.window[i]
+		ns.tail[i] = real(ns.spec[n+i]) * ns.window[n+i]
+	}
+}
+
+// fft is an in-place iterative radix-2 transform; len(a) must be a power of
+// two. The inverse transform is scaled by 1/len(a).
+func fft(a []complex128, inverse bool) {
+	n := len(a)
+	for i, j := 1, 0; i < n; i++ {
+		bit := n >> 1
+		for ; j&bit != 0; bit >>= 1 {
+			j ^= bit
+		}
+		j ^= bit
+		if i < j {
+			a[i], a[j] = a[j], a[i]
+		}
+	}
+	sign := -1.0
+	if inverse {
+		sign = 1.0
+	}
+	for length := 2; length <= n; length <<= 1 {
+		w := cmplx.Rect(1, sign*2*math.Pi/float64(length))
+		for start := 0; start < n; start += length {
+			wn := complex(1, 0)
+			for k := 0; k < length/2; k++ {
+				u := a[start+k]
+				v := a[start+k+length/2] * wn
+				a[start+k] = u + v
+				a[start+k+length/2] = u - v
+				wn *= w
+			}
+		}
+	}
+	if inverse {
+		scale := complex(1/float64(n), 0)
+		for i := range a {
+			a[i] *= scale
+		}
+	}
+}
+
+func nextPow2(n int) int {
+	p := 1
+	for p < n {
+		p <<= 1
+	}
+	return p
+}
+
+func dbToLinear(db float64) float64 {
+	return math.Pow(10, db/20)
+}
+
+func floatRMSDb(x []float64) float64 {
+	var sum float64
+	for _, v := range x {
+		sum += v * v
+	}
+	rms := math.Sqrt(sum / float64(len(x)))
+	if rms == 0 {
+		return -120
+	}
+	return 20 * math.Log10(rms)
+}
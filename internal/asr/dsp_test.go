@@ -0,0 +1,181 @@
+package asr
+
+import (
+	"math"
+	"math/cmplx"
+	"math/rand"
+	"testing"
+
+	"brabble/internal/config"
+)
+
+const testRate = 16000
+
+func sine(freq, amp float64, n, offset int) []float64 {
+	out := make([]float64, n)
+	for i := range out {
+		out[i] = amp * math.Sin(2*math.Pi*freq*float64(offset+i)/testRate)
+	}
+	return out
+}
+
+// goertzel returns the amplitude of freq in x.
+func goertzel(x []float64, freq float64) float64 {
+	k := 2 * math.Cos(2*math.Pi*freq/testRate)
+	var s1, s2 float64
+	for _, v := range x {
+		s0 := v + k*s1 - s2
+		s2, s1 = s1, s0
+	}
+	power := s1*s1 + s2*s2 - k*s1*s2
+	return 2 * math.Sqrt(power) / float64(len(x))
+}
+
+func TestFFTRoundTrip(t *testing.T) {
+	in := []complex128{1, 2, 3, 4, -1, -2, 0.5, 0}
+	a := append([]complex128(nil), in...)
+	fft(a, false)
+	fft(a, true)
+	for i := range in {
+		if cmplx.Abs(a[i]-in[i]) > 1e-9 {
+			t.Fatalf("round trip[%d]=%v want %v", i, a[i], in[i])
+		}
+	}
+}
+
+func TestHighPassRemovesDC(t *testing.T) {
+	f := newHighPassFilter(80, testRate)
+	frame := make([]float64, 320)
+	for n := 0; n < 50; n++ {
+		for i := range frame {
+			frame[i] = 0.25
+		}
+		f.process(frame)
+	}
+	if db := floatRMSDb(frame); db > -60 {
+		t.Fatalf("DC not removed: %.1f dBFS remaining", db)
+	}
+}
+
+func TestHighPassKeepsSpeechBand(t *testing.T) {
+	f := newHighPassFilter(80, testRate)
+	var last []float64
+	for n := 0; n < 50; n++ {
+		last = sine(1000, 0.5, 320, n*320)
+		f.process(last)
+	}
+	if amp := goertzel(last, 1000); amp < 0.48 {
+		t.Fatalf("1 kHz attenuated to %.3f", amp)
+	}
+}
+
+func TestGainControlRaisesQuietInput(t *testing.T) {
+	g := newGainControl(-20, 30, testRate)
+	var last []float64
+	for n := 0; n < 150; n++ { // 3 s
+		last = sine(440, 0.01*math.Sqrt2, 320, n*320) // -40 dBFS RMS
+		g.process(last)
+	}
+	if db := floatRMSDb(last); math.Abs(db-(-20)) > 1.5 {
+		t.Fatalf("agc settled at %.1f dBFS, want about -20", db)
+	}
+}
+
+func TestGainControlCapsGain(t *testing.T) {
+	g := newGainControl(-20, 12, testRate)
+	var last []float64
+	for n := 0; n < 150; n++ {
+		last = sine(440, 0.001*math.Sqrt2, 320, n*320) // -60 dBFS RMS
+		g.process(last)
+	}
+	if db := floatRMSDb(last); math.Abs(db-(-48)) > 1.5 {
+		t.Fatalf("agc gain not capped: %.1f dBFS, want about -48", db)
+	}
+}
+
+func TestGainControlHoldsBelowGate(t *testing.T) {
+	g := newGainControl(-20, 30, testRate)
+	frame := sine(440, 0.0001, 320, 0) // about -83 dBFS
+	before := floatRMSDb(frame)
+	g.process(frame)
+	if after := floatRMSDb(frame); math.Abs(after-before) > 0.01 {
+		t.Fatalf("agc amplified room tone: %.1f -> %.1f dBFS", before, after)
+	}
+}
+
+func TestNoiseSuppressorAttenuatesNoise(t *testing.T) {
+	ns := newNoiseSuppressor(320)
+	rng := rand.New(rand.NewSource(1))
+	var inDB, outDB float64
+	for n := 0; n < 100; n++ {
+		frame := make([]float64, 320)
+		for i := range frame {
+			frame[i] = rng.NormFloat64() * 0.02
+		}
+		in := floatRMSDb(frame)
+		ns.process(frame)
+		if n >= 50 {
+			inDB += in / 50
+			outDB += floatRMSDb(frame) / 50
+		}
+	}
+	if inDB-outDB < 8 {
+		t.Fatalf("noise reduced by only %.1f dB", inDB-outDB)
+	}
+}
+
+func TestNoiseSuppressorKeepsTone(t *testing.T) {
+	ns := newNoiseSuppressor(320)
+	rng := rand.New(rand.NewSource(2))
+	noisy := func() []float64 {
+		frame := make([]float64, 320)
+		for i := range frame {
+			frame[i] = rng.NormFloat64() * 0.01
+		}
+		return frame
+	}
+	// Learn the floor on noise alone, then add a tone.
+	for n := 0; n < 30; n++ {
+		ns.process(noisy())
+	}
+	var out []float64
+	for n := 30; n < 80; n++ {
+		frame := noisy()
+		tone := sine(1000, 0.3, 320, n*320)
+		for i := range frame {
+			frame[i] += tone[i]
+		}
+		ns.process(frame)
+		if n >= 40 {
+			out = append(out, frame...)
+		}
+	}
+	if amp := goertzel(out, 1000); math.Abs(amp-0.3) > 0.03 {
+		t.Fatalf("tone amplitude %.3f after suppression, want about 0.3", amp)
+	}
+}
+
+func TestDSPChainDisabledByDefault(t *testing.T) {
+	cfg, err := config.Default()
+	if err != nil {
+		t.Fatalf("default: %v", err)
+	}
+	if c := newDSPChain(cfg, testRate, 320); c != nil {
+		t.Fatalf("expected nil chain when all stages are off")
+	}
+	cfg.Audio.HighPass = true
+	c := newDSPChain(cfg, testRate, 320)
+	if c == nil || c.highPass == nil || c.agc != nil || c.denoise != nil {
+		t.Fatalf("unexpected chain: %+v", c)
+	}
+	frame := make([]int16, 320)
+	for n := 0; n < 50; n++ {
+		for i := range frame {
+			frame[i] = 4000
+		}
+		c.process(frame)
+	}
+	if frame[len(frame)-1] > 10 {
+		t.Fatalf("chain did not filter DC: %d", frame[len(frame)-1])
+	}
+}
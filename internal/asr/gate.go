@@ -0,0 +1,163 @@
+package asr
+
+import (
+	"context"
+	"errors"
+	"sync"
+	"time"
+)
+
+// errPaused stops the capture loop so the stream can be closed.
+var errPaused = errors.New("capture paused")
+
+// captureGate lets the control plane stop and restart capture. While the
+// gate is closed the audio stream is shut, releasing the mic. In
+// push-to-talk mode the gate opens only while talk is held.
+type captureGate struct {
+	mu         sync.Mutex
+	paused     bool
+	pushToTalk bool // fixed at construction
+	talking    bool
+	ducked     bool          // stream stays open but frames are discarded
+	change     chan struct{} // closed and replaced on every state change
+}
+
+func newCaptureGate(pushToTalk bool) *captureGate {
+	return &captureGate{pushToTalk: pushToTalk, change: make(chan struct{})}
+}
+
+// setPaused updates the gate and reports whether the state changed.
+func (g *captureGate) setPaused(paused bool) bool {
+	g.mu.Lock()
+	defer g.mu.Unlock()
+	if g.paused == paused {
+		return false
+	}
+	g.paused = paused
+	g.notifyLocked()
+	return true
+}
+
+// setTalking holds or releases push-to-talk and reports whether the state
+// changed.
+func (g *captureGate) setTalking(talking bool) bool {
+	g.mu.Lock()
+	defer g.mu.Unlock()
+	if g.talking == talking {
+		return false
+	}
+	g.talking = talking
+	g.notifyLocked()
+	return true
+}
+
+func (g *captureGate) isTalking() bool {
+	g.mu.Lock()
+	defer g.mu.Unlock()
+	return g.talking
+}
+
+func (g *captureGate) isPaused() bool {
+	g.mu.Lock()
+	defer g.mu.Unlock()
+	return g.paused
+}
+
+func (g *captureGate) setDucked(ducked bool) bool {
+	g.mu.Lock()
+	defer g.mu.Unlock()
+	if g.ducked == ducked {
+		return false
+	}
+	g.ducked = ducked
+	return true
+}
+
+func (g *captureGate) isDucked() bool {
+	g.mu.Lock()
+	defer g.mu.Unlock()
+	return g.ducked
+}
+
+func (g *captureGate) notifyLocked() {
+	close(g.change)
+	g.change = make(chan struct{})
+}
+
+func (g *captureGate) closedLocked() bool {
+	return g.paused || (g.pushToTalk && !g.talking)
+}
+
+func (g *captureGate) closed() bool {
+	g.mu.Lock()
+	defer g.mu.Unlock()
+	return g.closedLocked()
+}
+
+// waitOpen blocks until capture may run or ctx ends.
+func (g *captureGate) waitOpen(ctx context.Context) error {
+	for {
+		if err := ctx.Err(); err != nil {
+			return err
+		}
+		g.mu.Lock()
+		closed, change := g.closedLocked(), g.change
+		g.mu.Unlock()
+		if !closed {
+			return nil
+		}
+		select {
+		case <-change:
+		case <-ctx.Done():
+			return ctx.Err()
+		}
+	}
+}
+
+// SetPaused stops capture and closes the audio stream (true) or reopens it
+// (false). The model stays loaded, and speech in progress is discarded.
+func (r *whisperRecognizer) SetPaused(paused bool) {
+	if r.gate.setPaused(paused) {
+		if paused {
+			r.logger.Info("capture paused")
+		} else {
+			r.logger.Info("capture resumed")
+		}
+	}
+}
+
+// SetDucked ignores captured audio (true) without closing the stream, so
+// the daemon does not transcribe its own earcons and speech. Speech in
+// progress when ducking starts is discarded.
+func (r *whisperRecognizer) SetDucked(ducked bool) {
+	if r.gate.setDucked(ducked) {
+		r.logger.Debugf("capture ducked: %v", ducked)
+	}
+}
+
+// errNotPushToTalk is returned by SetTalking when wake.mode is "always".
+var errNotPushToTalk = errors.New(`push-to-talk disabled (set wake.mode = "push_to_talk")`)
+
+// SetTalking opens the mic and starts a segment (true) or ends the segment
+// and closes the mic (false). Segments captured this way skip VAD
+// end-of-speech and the wake word. It reports whether the state changed.
+func (r *whisperRecognizer) SetTalking(talking bool) (bool, error) {
+	if !r.gate.pushToTalk {
+		return false, errNotPushToTalk
+	}
+	if !r.gate.setTalking(talking) {
+		return false, nil
+	}
+	if talking {
+		r.logger.Info("push-to-talk: talking")
+		r.noteActivity(time.Now()) // start an idle reload while the mic opens
+	} else {
+		r.logger.Info("push-to-talk: released")
+	}
+	return true, nil
+}
+
+// Talking reports whether push-to-talk is held.
+func (r *whisperRecognizer) Talking() bool {
+	return r.gate.isTalking()
+}
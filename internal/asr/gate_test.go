@@ -0,0 +1,120 @@
+package asr
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"brabble/internal/config"
+)
+
+func TestCaptureGateWaitOpen(t *testing.T) {
+	g := newCaptureGate(false)
+	if err := g.waitOpen(context.Background()); err != nil {
+		t.Fatalf("open gate blocked: %v", err)
+	}
+	if !g.setPaused(true) || g.setPaused(true) {
+		t.Fatal("setPaused should report only real changes")
+	}
+	done := make(chan error, 1)
+	go func() { done <- g.waitOpen(context.Background()) }()
+	select {
+	case <-done:
+		t.Fatal("waitOpen returned while paused")
+	case <-time.After(30 * time.Millisecond):
+	}
+	g.setPaused(false)
+	select {
+	case err := <-done:
+		if err != nil {
+			t.Fatalf("waitOpen: %v", err)
+		}
+	case <-time.After(time.Second):
+		t.Fatal("waitOpen did not wake on resume")
+	}
+
+	g.setPaused(true)
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	if err := g.waitOpen(ctx); err == nil {
+		t.Fatal("waitOpen ignored cancellation")
+	}
+}
+
+func TestPushToTalkGateOpensOnlyWhileTalking(t *testing.T) {
+	g := newCaptureGate(true)
+	if !g.closed() {
+		t.Fatal("push-to-talk gate open before talk")
+	}
+	g.setTalking(true)
+	if g.closed() {
+		t.Fatal("gate closed while talking")
+	}
+	g.setPaused(true)
+	if !g.closed() {
+		t.Fatal("pause should override talk")
+	}
+	g.setPaused(false)
+	g.setTalking(false)
+	if !g.closed() {
+		t.Fatal("gate open after release")
+	}
+}
+
+func TestTalkFramesFlushAsOneFinal(t *testing.T) {
+	cfg, _ := config.Default()
+	cfg.VAD.MaxSegmentMS = 0
+	r := newTestRecognizer("m.bin", &fakeModel{})
+	r.cfg = cfg
+	r.mixer = newChannelMixer("downmix", 1, 0)
+	r.gate = newCaptureGate(true)
+
+	frame := make([]int16, asrSampleRate/50) // 20 ms
+	for i := range frame {
+		frame[i] = 8000
+	}
+	var st captureState
+	r.processTalkFrame(&st, frame, 0)
+	r.flushTalk(&st, time.Now())
+	if len(r.backlog.queue) != 0 {
+		t.Fatal("hold shorter than min_speech_ms was queued")
+	}
+	for i := 0; i < 25; i++ { // 500 ms, silence detection not involved
+		r.processTalkFrame(&st, frame, 0)
+	}
+	r.flushTalk(&st, time.Now())
+	if len(r.backlog.queue) != 1 {
+		t.Fatalf("queued %d chunks, want 1", len(r.backlog.queue))
+	}
+	c := <-r.backlog.queue
+	if !c.ptt || c.partial || len(c.pcm) != 25*len(frame) {
+		t.Fatalf("chunk ptt=%v partial=%v samples=%d", c.ptt, c.partial, len(c.pcm))
+	}
+}
+
+func TestDuckedFramesAreDiscarded(t *testing.T) {
+	cfg, _ := config.Default()
+	r := newTestRecognizer("m.bin", &fakeModel{})
+	r.cfg = cfg
+	r.mixer = newChannelMixer("downmix", 1, 0)
+	r.gate = newCaptureGate(true)
+	r.gate.setTalking(true)
+
+	frame := make([]int16, asrSampleRate/50)
+	var st captureState
+	r.SetDucked(true)
+	r.processFrame(&st, frame)
+	if st.inSpeech || len(st.chunk) != 0 {
+		t.Fatal("frame kept while ducked")
+	}
+	r.SetDucked(false)
+	r.processFrame(&st, frame)
+	if !st.inSpeech || len(st.chunk) != len(frame) {
+		t.Fatal("frame dropped after unducking")
+	}
+	r.SetDucked(true)
+	r.processFrame(&st, frame)
+	if st.inSpeech || len(st.chunk) != 0 {
+		t.Fatal("speech in progress survived ducking")
+	}
+}
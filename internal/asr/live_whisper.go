@@ -6,71 +6,128 @@ import (
 	"fmt"
 	"io"
 	"math"
+	"runtime"
 	"strings"
+	"sync"
+	"sync/atomic"
 	"time"
 	"unsafe"
 
 	"brabble/internal/config"
 	"brabble/internal/logging"
+	"brabble/internal/resample"
 
 	"github.com/ggerganov/whisper.cpp/bindings/go/pkg/whisper"
 	"github.com/gordonklaus/portaudio"
 	vad "github.com/maxhawkins/go-webrtcvad"
 )
 
+// asrSampleRate is the rate whisper.cpp expects; VAD and DSP run at it too,
+// so capture at any other device rate is resampled first.
+const asrSampleRate = 16000
+
 // whisperRecognizer captures audio, runs VAD, then transcribes with whisper.cpp.
 type whisperRecognizer struct {
-	cfg    *config.Config
-	logger *logging.Logger
-	model  whisper.Model
-	vad    *vad.VAD
+	cfg       *config.Config
+	logger    *logging.Logger
+	modelMu   sync.RWMutex // guards the fields below; see acquireModels
+	models    *modelSet    // nil while unloaded for idleness
+	modelPath string
+	loading   chan struct{} // non-nil while a reload runs
+	loadErr   error
+
+	swapMu  sync.Mutex // serializes loads, swaps, unloads, and shutdown
+	stopped bool       // guarded by swapMu
+	workers int
+
+	lastActivity atomic.Int64 // unix ns of the last voiced frame
+	modelStats   modelStats
+	vad          *vad.VAD
+	dsp          *dspChain
+	mixer        *channelMixer
+
+	onDevice func(name string)
+	backlog  *backlog
+	pool     *transcribePool
+	gate     *captureGate
 }
 
 type segmentChunk struct {
 	pcm     []int16
 	partial bool
+	ptt     bool // captured while push-to-talk was held
+	// start and end are the capture times of the first and last sample.
+	start, end time.Time
 }
 
 func newWhisperRecognizer(cfg *config.Config, logger *logging.Logger) (Recognizer, error) {
-	if cfg.Audio.Channels != 1 {
-		return nil, fmt.Errorf("only mono input supported; set audio.channels = 1")
+	if err := validateChannels(cfg.Audio.ChannelMode, cfg.Audio.Channels, cfg.Audio.Channel); err != nil {
+		return nil, err
 	}
 	if cfg.Audio.FrameMS != 10 && cfg.Audio.FrameMS != 20 && cfg.Audio.FrameMS != 30 {
 		return nil, fmt.Errorf("audio.frame_ms must be 10, 20, or 30 (got %d)", cfg.Audio.FrameMS)
 	}
-	switch cfg.Audio.SampleRate {
-	case 8000, 16000, 32000, 48000:
-	default:
-		return nil, fmt.Errorf("sample_rate must be 8k/16k/32k/48k for webrtc VAD (got %d)", cfg.Audio.SampleRate)
+	if cfg.Audio.SampleRate < 0 {
+		return nil, fmt.Errorf("audio.sample_rate must be >= 0; 0 uses the device default (got %d)", cfg.Audio.SampleRate)
 	}
 	if err := portaudio.Initialize(); err != nil {
 		return nil, fmt.Errorf("portaudio init: %w", err)
 	}
-	model, err := whisper.New(cfg.ASR.ModelPath)
+	if m := cfg.Wake.Mode; m != "" && m != config.WakeModeAlways && m != config.WakeModePushToTalk {
+		return nil, fmt.Errorf("wake.mode must be %s or %s (got %q)", config.WakeModeAlways, config.WakeModePushToTalk, m)
+	}
+	if s := cfg.ASR.IdleUnloadSec; s < 0 || (s > 0 && s < 1) {
+		return nil, fmt.Errorf("asr.idle_unload_sec must be 0 (keep resident) or >= 1 (got %g)", s)
+	}
+	loadStart := time.Now()
+	models, err := loadModelSet(cfg.ASR.ModelPath, logger, workerCount(cfg))
 	if err != nil {
 		_ = portaudio.Terminate()
-		return nil, fmt.Errorf("load model: %w", err)
-	}
-	if err := warmup(model, cfg, logger); err != nil {
-		logger.Warnf("warmup: %v", err)
+		return nil, err
 	}
 	v, err := vad.New()
 	if err != nil {
-		_ = model.Close()
+		models.close(logger)
 		_ = portaudio.Terminate()
 		return nil, fmt.Errorf("vad init: %w", err)
 	}
 	if err := v.SetMode(cfg.VAD.Aggressiveness); err != nil {
-		_ = model.Close()
+		models.close(logger)
 		_ = portaudio.Terminate()
 		return nil, fmt.Errorf("vad mode: %w", err)
 	}
-	return &whisperRecognizer{
-		cfg:    cfg,
-		logger: logger,
-		model:  model,
-		vad:    v,
-	}, nil
+	r := &whisperRecognizer{
+		cfg:       cfg,
+		logger:    logger,
+		models:    models,
+		modelPath: cfg.ASR.ModelPath,
+		workers:   len(models.models),
+		vad:       v,
+		dsp:       newDSPChain(cfg, asrSampleRate, asrSampleRate*cfg.Audio.FrameMS/1000),
+		mixer:     newChannelMixer(cfg.Audio.ChannelMode, cfg.Audio.Channels, cfg.Audio.Channel),
+		backlog:   newBacklog(segmentQueueSize),
+		gate:      newCaptureGate(cfg.Wake.Mode == config.WakeModePushToTalk),
+	}
+	r.modelStats.recordLoad(time.Since(loadStart))
+	r.lastActivity.Store(time.Now().UnixNano())
+	r.pool = newTranscribePool(r.workers, r.backlog, logger, r.decode)
+	return r, nil
+}
+
+// OnDeviceChange registers fn to be called with the device name whenever
+// capture starts on a different input device. Call before Run.
+func (r *whisperRecognizer) OnDeviceChange(fn func(name string)) {
+	r.onDevice = fn
+}
+
+// Backlog reports the capture→ASR queue depth and shedding counters.
+func (r *whisperRecognizer) Backlog() BacklogStats {
+	return r.backlog.stats()
+}
+
+// WorkerStats reports cumulative busy time per transcription worker.
+func (r *whisperRecognizer) WorkerStats() []WorkerStats {
+	return r.pool.workerStats()
 }
 
 func (r *whisperRecognizer) Run(ctx context.Context, out chan<- Segment) error {
@@ -79,18 +136,14 @@ func (r *whisperRecognizer) Run(ctx context.Context, out chan<- Segment) error {
 			r.logger.Warnf("portaudio terminate: %v", err)
 		}
 	}()
-	defer func() {
-		if err := r.model.Close(); err != nil {
-			r.logger.Warnf("close model: %v", err)
-		}
-	}()
+	defer r.closeModels()
 
-	frameSamples := r.cfg.Audio.SampleRate * r.cfg.Audio.FrameMS / 1000
-	if ok := r.vad.ValidRateAndFrameLength(r.cfg.Audio.SampleRate, frameSamples); !ok {
-		return fmt.Errorf("invalid frame_ms %d for sample_rate %d", r.cfg.Audio.FrameMS, r.cfg.Audio.SampleRate)
+	frameSamples := asrSampleRate * r.cfg.Audio.FrameMS / 1000
+	if ok := r.vad.ValidRateAndFrameLength(asrSampleRate, frameSamples); !ok {
+		return fmt.Errorf("invalid frame_ms %d", r.cfg.Audio.FrameMS)
 	}
 
-	segments := make(chan segmentChunk, 8)
+	segments := r.backlog.queue
 	workerDone := make(chan struct{})
 	go func() {
 		defer close(workerDone)
@@ -98,12 +151,24 @@ func (r *whisperRecognizer) Run(ctx context.Context, out chan<- Segment) error {
 	}()
 	defer stopTranscribeWorker(segments, workerDone)
 
-	// retry loop for device/stream failures
+	if r.cfg.ASR.IdleUnloadSec > 0 {
+		go r.idleUnloader(ctx, time.Duration(r.cfg.ASR.IdleUnloadSec*float64(time.Second)))
+	}
+
+	var rescan <-chan time.Time
+	if len(r.cfg.Audio.PreferredDevices) > 0 && r.cfg.Audio.DeviceRescanSec > 0 {
+		ticker := time.NewTicker(time.Duration(r.cfg.Audio.DeviceRescanSec * float64(time.Second)))
+		defer ticker.Stop()
+		rescan = ticker.C
+	}
+
+	// retry loop for device/stream failures and rescans
+	current := ""
 	for {
-		if ctx.Err() != nil {
-			return ctx.Err()
+		if err := r.gate.waitOpen(ctx); err != nil {
+			return err
 		}
-		dev, err := selectDevice(r.cfg.Audio.DeviceName, r.cfg.Audio.DeviceIndex)
+		dev, via, err := selectDevice(r.cfg)
 		if err != nil {
 			r.logger.Warnf("select device: %v; retrying in 2s", err)
 			if err := waitForRetry(ctx, 2*time.Second); err != nil {
@@ -111,15 +176,17 @@ func (r *whisperRecognizer) Run(ctx context.Context, out chan<- Segment) error {
 			}
 			continue
 		}
-		buf := make([]int16, frameSamples)
+		captureRate := r.captureRate(dev)
+		deviceFrames := captureRate * r.cfg.Audio.FrameMS / 1000
+		buf := make([]int16, deviceFrames*r.cfg.Audio.Channels) // interleaved
 		stream, err := portaudio.OpenStream(portaudio.StreamParameters{
 			Input: portaudio.StreamDeviceParameters{
 				Device:   dev,
 				Channels: r.cfg.Audio.Channels,
 				Latency:  dev.DefaultLowInputLatency,
 			},
-			SampleRate:      float64(r.cfg.Audio.SampleRate),
-			FramesPerBuffer: frameSamples,
+			SampleRate:      float64(captureRate),
+			FramesPerBuffer: deviceFrames,
 		}, &buf)
 		if err != nil {
 			r.logger.Warnf("open stream: %v; retrying in 2s", err)
@@ -128,12 +195,54 @@ func (r *whisperRecognizer) Run(ctx context.Context, out chan<- Segment) error {
 			}
 			continue
 		}
-		r.logger.Infof("listening on mic: %s @ %d Hz", dev.Name, r.cfg.Audio.SampleRate)
-		if err := r.captureLoop(ctx, stream, buf, segments); err != nil && !errors.Is(err, context.Canceled) {
+		var rs *resample.Resampler
+		if captureRate != asrSampleRate {
+			rs = resample.New(captureRate, asrSampleRate)
+		}
+		if dev.Name != current {
+			if current != "" {
+				r.logger.Infof("mic changed: %s -> %s", current, dev.Name)
+			}
+			current = dev.Name
+			if id := r.cfg.Audio.DeviceID; id.Name != "" && via != viaPreferred && via != viaIdentity {
+				r.logger.Warnf("saved mic %s not found; using %s (%s)", id, dev.Name, via)
+			}
+			if r.onDevice != nil {
+				r.onDevice(dev.Name)
+			}
+		}
+		r.logger.Infof("listening on mic: %s @ %d Hz, %d ch (%s)", dev.Name, captureRate, r.cfg.Audio.Channels, r.mixer.mode)
+		// PortAudio only sees hot-plugged devices after a re-init, so every
+		// rescan reopens the stream; skip them while nothing can outrank dev.
+		tick := rescan
+		if preferenceRank(r.cfg.Audio.PreferredDevices, dev.Name) == 0 {
+			tick = nil
+		}
+		err = r.captureLoop(ctx, stream, buf, rs, tick)
+		if errors.Is(err, errPaused) {
+			if closeErr := stream.Close(); closeErr != nil {
+				r.logger.Warnf("close stream: %v", closeErr)
+			}
+			r.logger.Infof("mic closed: %s", dev.Name)
+			continue
+		}
+		if errors.Is(err, errRescan) {
+			if closeErr := stream.Close(); closeErr != nil {
+				r.logger.Warnf("close stream: %v", closeErr)
+			}
+			if err := refreshDevices(); err != nil {
+				r.logger.Warnf("refresh devices: %v", err)
+			}
+			continue
+		}
+		if err != nil && !errors.Is(err, context.Canceled) {
 			r.logger.Warnf("stream ended: %v; restarting in 2s", err)
 			if closeErr := stream.Close(); closeErr != nil {
 				r.logger.Warnf("close stream: %v", closeErr)
 			}
+			if err := refreshDevices(); err != nil {
+				r.logger.Warnf("refresh devices: %v", err)
+			}
 			if err := waitForRetry(ctx, 2*time.Second); err != nil {
 				return err
 			}
@@ -162,31 +271,51 @@ func waitForRetry(ctx context.Context, delay time.Duration) error {
 	}
 }
 
-func (r *whisperRecognizer) captureLoop(ctx context.Context, stream *portaudio.Stream, buf []int16, segments chan<- segmentChunk) error {
+// captureRate is the rate the device is opened at: the configured
+// audio.sample_rate, or the device's native rate when that is 0.
+func (r *whisperRecognizer) captureRate(dev *portaudio.DeviceInfo) int {
+	if r.cfg.Audio.SampleRate > 0 {
+		return r.cfg.Audio.SampleRate
+	}
+	if rate := int(math.Round(dev.DefaultSampleRate)); rate > 0 {
+		return rate
+	}
+	return asrSampleRate
+}
+
+// captureLoop reads device buffers, folds them to mono, resamples to the ASR
+// rate when rs is set, and feeds fixed-size VAD frames to processFrame. It
+// returns errRescan on a rescan tick that arrives between utterances.
+func (r *whisperRecognizer) captureLoop(ctx context.Context, stream *portaudio.Stream, raw []int16, rs *resample.Resampler, rescan <-chan time.Time) error {
 	if err := stream.Start(); err != nil {
 		return fmt.Errorf("start stream: %w", err)
 	}
 	defer func() { _ = stream.Stop() }()
 
 	var (
-		chunk           []int16
-		inSpeech        bool
-		lastVoice       time.Time
-		speechBegan     time.Time
-		lastPartialSent time.Time
-		silenceDur      = time.Duration(r.cfg.VAD.SilenceMS) * time.Millisecond
-		maxSegDur       = time.Duration(r.cfg.VAD.MaxSegmentMS) * time.Millisecond
-		partialFlush    = time.Duration(r.cfg.VAD.PartialFlushMS) * time.Millisecond
-		sampleRate      = r.cfg.Audio.SampleRate
-		minSpeech       = time.Duration(r.cfg.VAD.MinSpeechMS) * time.Millisecond
+		st           captureState
+		pending      []int16
+		floatIn      []float32
+		floatOut     []float32
+		frameSamples = asrSampleRate * r.cfg.Audio.FrameMS / 1000
 	)
-
 	for {
 		select {
 		case <-ctx.Done():
 			return ctx.Err()
+		case <-rescan:
+			if !st.inSpeech {
+				return errRescan
+			}
 		default:
 		}
+		if r.gate.closed() {
+			if r.gate.pushToTalk && !r.gate.isPaused() {
+				r.flushTalk(&st, time.Now()) // released: the held audio is one segment
+			}
+			st.endSpeech(r.mixer) // discard speech in progress
+			return errPaused
+		}
 		if err := stream.Read(); err != nil {
 			if errors.Is(err, portaudio.InputOverflowed) {
 				r.logger.Warn("input overflow")
@@ -194,114 +323,205 @@ func (r *whisperRecognizer) captureLoop(ctx context.Context, stream *portaudio.S
 			}
 			return fmt.Errorf("stream read: %w", err)
 		}
-		active, err := r.vad.Process(r.cfg.Audio.SampleRate, int16ToBytes(buf))
-		if err != nil {
-			r.logger.Warnf("vad process: %v", err)
-			continue
-		}
-
-		if active {
-			if !inSpeech {
-				inSpeech = true
-				speechBegan = time.Now()
-				lastPartialSent = time.Now()
-				chunk = chunk[:0]
-			}
-			chunk = append(chunk, buf...)
-			lastVoice = time.Now()
-
-			if partialFlush > 0 && time.Since(lastPartialSent) >= partialFlush && len(chunk) > 0 {
-				chunkDur := time.Duration(len(chunk)) * time.Second / time.Duration(sampleRate)
-				if chunkDur < minSpeech {
-					continue
-				}
-				if skipForEnergy(chunk, r.cfg.VAD.EnergyThresh) {
-					inSpeech = false
-					chunk = chunk[:0]
-					continue
-				}
-				cpy := make([]int16, len(chunk))
-				copy(cpy, chunk)
-				select {
-				case segments <- segmentChunk{pcm: cpy, partial: true}:
-					lastPartialSent = time.Now()
-					chunk = chunk[:0]
-					speechBegan = time.Now()
-				default:
-					r.logger.Warn("segment queue full, dropping partial")
-				}
+		mono := r.mixer.mix(raw)
+		if rs == nil {
+			pending = append(pending, mono...)
+		} else {
+			floatIn = floatIn[:0]
+			for _, v := range mono {
+				floatIn = append(floatIn, float32(v)/32768.0)
 			}
-		} else if inSpeech {
-			now := time.Now()
-			if (now.Sub(lastVoice) >= silenceDur && len(chunk) > 0) ||
-				(maxSegDur > 0 && now.Sub(speechBegan) >= maxSegDur) {
-				chunkDur := time.Duration(len(chunk)) * time.Second / time.Duration(sampleRate)
-				if chunkDur >= minSpeech {
-					if skipForEnergy(chunk, r.cfg.VAD.EnergyThresh) {
-						inSpeech = false
-						chunk = chunk[:0]
-						continue
-					}
-					cpy := make([]int16, len(chunk))
-					copy(cpy, chunk)
-					select {
-					case segments <- segmentChunk{pcm: cpy, partial: false}:
-					default:
-						r.logger.Warn("segment queue full, dropping segment")
-					}
-				}
-				inSpeech = false
-				chunk = chunk[:0]
+			floatOut = rs.Process(floatOut[:0], floatIn)
+			for _, v := range floatOut {
+				pending = append(pending, floatToInt16(float64(v)))
 			}
 		}
+		consumed := 0
+		for len(pending)-consumed >= frameSamples {
+			r.processFrame(&st, pending[consumed:consumed+frameSamples])
+			consumed += frameSamples
+		}
+		pending = append(pending[:0], pending[consumed:]...)
 	}
 }
 
-func (r *whisperRecognizer) transcribeWorker(ctx context.Context, segs <-chan segmentChunk, out chan<- Segment) {
-	for {
-		select {
-		case <-ctx.Done():
-			return
-		case data, ok := <-segs:
-			if !ok {
+// captureState carries VAD segmentation across frames of one stream.
+type captureState struct {
+	chunk           []int16
+	inSpeech        bool
+	lastVoice       time.Time
+	speechBegan     time.Time
+	lastPartialSent time.Time
+}
+
+func (st *captureState) endSpeech(m *channelMixer) {
+	st.inSpeech = false
+	st.chunk = st.chunk[:0]
+	m.hold(false)
+}
+
+// processFrame runs one ASR-rate frame through DSP and VAD and emits partial
+// or final segments as speech starts, pauses, and ends.
+func (r *whisperRecognizer) processFrame(st *captureState, buf []int16) {
+	var (
+		silenceDur   = time.Duration(r.cfg.VAD.SilenceMS) * time.Millisecond
+		maxSegDur    = time.Duration(r.cfg.VAD.MaxSegmentMS) * time.Millisecond
+		partialFlush = time.Duration(r.cfg.VAD.PartialFlushMS) * time.Millisecond
+		minSpeech    = time.Duration(r.cfg.VAD.MinSpeechMS) * time.Millisecond
+	)
+	if r.gate.isDucked() {
+		if st.inSpeech {
+			st.endSpeech(r.mixer)
+		}
+		return
+	}
+	if r.dsp != nil {
+		r.dsp.process(buf)
+	}
+	if r.gate.pushToTalk {
+		r.processTalkFrame(st, buf, maxSegDur)
+		return
+	}
+	active, err := r.vad.Process(asrSampleRate, int16ToBytes(buf))
+	if err != nil {
+		r.logger.Warnf("vad process: %v", err)
+		return
+	}
+
+	if active {
+		r.noteActivity(time.Now())
+		if !st.inSpeech {
+			st.inSpeech = true
+			r.mixer.hold(true)
+			st.speechBegan = time.Now()
+			st.lastPartialSent = time.Now()
+			st.chunk = st.chunk[:0]
+		}
+		st.chunk = append(st.chunk, buf...)
+		st.lastVoice = time.Now()
+
+		if partialFlush > 0 && time.Since(st.lastPartialSent) >= partialFlush && len(st.chunk) > 0 {
+			if pcmDuration(st.chunk) < minSpeech {
 				return
 			}
-			if len(data.pcm) == 0 {
-				continue
-			}
-			text, err := r.transcribe(ctx, data.pcm)
-			if err != nil {
-				r.logger.Errorf("transcribe: %v", err)
-				continue
-			}
-			if strings.TrimSpace(text) == "" {
-				continue
+			if skipForEnergy(st.chunk, r.cfg.VAD.EnergyThresh) {
+				st.endSpeech(r.mixer)
+				return
 			}
-			seg := Segment{
-				Text:       strings.TrimSpace(text),
-				Start:      time.Now(), // approximate; audio timestamps not tracked
-				End:        time.Now(),
-				Confidence: 0.0,
-				Partial:    data.partial,
+			if r.backlog.enqueue(newSegmentChunk(st.chunk, true, time.Now()), r.logger) {
+				st.lastPartialSent = time.Now()
+				st.chunk = st.chunk[:0]
+				st.speechBegan = time.Now()
 			}
-			select {
-			case out <- seg:
-			case <-ctx.Done():
-				return
+		}
+	} else if st.inSpeech {
+		now := time.Now()
+		if (now.Sub(st.lastVoice) >= silenceDur && len(st.chunk) > 0) ||
+			(maxSegDur > 0 && now.Sub(st.speechBegan) >= maxSegDur) {
+			if pcmDuration(st.chunk) >= minSpeech && !skipForEnergy(st.chunk, r.cfg.VAD.EnergyThresh) {
+				r.backlog.enqueue(newSegmentChunk(st.chunk, false, st.lastVoice), r.logger)
 			}
+			st.endSpeech(r.mixer)
 		}
 	}
 }
 
-func (r *whisperRecognizer) transcribe(ctx context.Context, pcm []int16) (string, error) {
+// processTalkFrame appends a frame captured while push-to-talk is held. VAD
+// is bypassed: the whole hold becomes one final segment, split only at
+// max_segment_ms, and no partials are sent.
+func (r *whisperRecognizer) processTalkFrame(st *captureState, buf []int16, maxSegDur time.Duration) {
+	now := time.Now()
+	r.noteActivity(now)
+	if !st.inSpeech {
+		st.inSpeech = true
+		r.mixer.hold(true)
+		st.speechBegan = now
+		st.chunk = st.chunk[:0]
+	}
+	st.chunk = append(st.chunk, buf...)
+	st.lastVoice = now
+	if maxSegDur > 0 && now.Sub(st.speechBegan) >= maxSegDur {
+		r.flushTalk(st, now)
+		st.speechBegan = now
+	}
+}
+
+// flushTalk queues the audio held so far as a final push-to-talk segment.
+func (r *whisperRecognizer) flushTalk(st *captureState, end time.Time) {
+	minSpeech := time.Duration(r.cfg.VAD.MinSpeechMS) * time.Millisecond
+	if len(st.chunk) > 0 && pcmDuration(st.chunk) >= minSpeech && !skipForEnergy(st.chunk, r.cfg.VAD.EnergyThresh) {
+		c := newSegmentChunk(st.chunk, false, end)
+		c.ptt = true
+		r.backlog.enqueue(c, r.logger)
+	}
+	st.chunk = st.chunk[:0]
+}
+
+// newSegmentChunk copies pcm whose last sample was captured at end.
+func newSegmentChunk(pcm []int16, partial bool, end time.Time) segmentChunk {
+	cpy := make([]int16, len(pcm))
+	copy(cpy, pcm)
+	return segmentChunk{pcm: cpy, partial: partial, start: end.Add(-pcmDuration(pcm)), end: end}
+}
+
+// pcmDuration converts an ASR-rate sample count to wall time.
+func pcmDuration(pcm []int16) time.Duration {
+	return time.Duration(len(pcm)) * time.Second / asrSampleRate
+}
+
+func (r *whisperRecognizer) transcribeWorker(ctx context.Context, segs <-chan segmentChunk, out chan<- Segment) {
+	r.pool.run(ctx, segs, out)
+}
+
+// decode transcribes one chunk on the given worker's model.
+func (r *whisperRecognizer) decode(ctx context.Context, worker int, data segmentChunk) (Segment, bool) {
+	set, err := r.acquireModels(ctx)
+	if err != nil {
+		r.logger.Errorf("transcribe: %v", err)
+		return Segment{}, false
+	}
+	defer set.release()
+	tr, err := r.transcribe(ctx, set.models[worker], len(set.models), data.pcm)
+	if err != nil {
+		r.logger.Errorf("transcribe: %v", err)
+		return Segment{}, false
+	}
+	text := strings.TrimSpace(tr.text)
+	if text == "" {
+		return Segment{}, false
+	}
+	return Segment{
+		Text:        text,
+		Start:       data.start,
+		End:         data.end,
+		Transcribed: time.Now(),
+		Confidence:  tr.confidence,
+		Language:    tr.language,
+		Partial:     data.partial,
+		PushToTalk:  data.ptt,
+	}, true
+}
+
+// transcription is whisper's output for one chunk.
+type transcription struct {
+	text       string
+	language   string
+	confidence float64
+}
+
+func (r *whisperRecognizer) transcribe(ctx context.Context, model whisper.Model, workers int, pcm []int16) (transcription, error) {
 	samples := make([]float32, len(pcm))
 	for i, s := range pcm {
 		samples[i] = float32(s) / 32768.0
 	}
 
-	ctxWhisper, err := r.model.NewContext()
+	ctxWhisper, err := model.NewContext()
 	if err != nil {
-		return "", err
+		return transcription{}, err
+	}
+	if workers > 1 {
+		// Split cores between workers instead of oversubscribing.
+		ctxWhisper.SetThreads(uint(max(1, runtime.NumCPU()/workers)))
 	}
 
 	if lang := strings.TrimSpace(r.cfg.ASR.Language); lang != "" {
@@ -311,31 +531,54 @@ func (r *whisperRecognizer) transcribe(ctx context.Context, pcm []int16) (string
 	}
 
 	if err := ctxWhisper.Process(samples, nil, nil, nil); err != nil {
-		return "", err
+		return transcription{}, err
 	}
 	var b strings.Builder
+	var tokens []whisper.Token
 	for {
 		seg, err := ctxWhisper.NextSegment()
 		if err != nil {
 			if errors.Is(err, io.EOF) {
 				break
 			}
-			return "", err
+			return transcription{}, err
 		}
 		b.WriteString(seg.Text)
 		if !strings.HasSuffix(seg.Text, " ") {
 			b.WriteRune(' ')
 		}
+		tokens = append(tokens, seg.Tokens...)
 	}
-	return b.String(), nil
+	return transcription{
+		text:       b.String(),
+		language:   ctxWhisper.DetectedLanguage(),
+		confidence: meanProbability(tokens, ctxWhisper.IsText),
+	}, nil
 }
 
-func warmup(model whisper.Model, cfg *config.Config, logger *logging.Logger) error {
+// meanProbability averages the probabilities of the text tokens, skipping
+// timestamps and other special tokens. It is 0 when there are none.
+func meanProbability(tokens []whisper.Token, isText func(whisper.Token) bool) float64 {
+	var sum float64
+	n := 0
+	for _, tok := range tokens {
+		if isText(tok) {
+			sum += float64(tok.P)
+			n++
+		}
+	}
+	if n == 0 {
+		return 0
+	}
+	return sum / float64(n)
+}
+
+func warmup(model whisper.Model, logger *logging.Logger) error {
 	ctx, err := model.NewContext()
 	if err != nil {
 		return err
 	}
-	samples := make([]float32, cfg.Audio.SampleRate/2) // 0.5s silence
+	samples := make([]float32, asrSampleRate/2) // 0.5s silence
 	if err := ctx.Process(samples, nil, nil, nil); err != nil {
 		return err
 	}
@@ -343,35 +586,6 @@ func warmup(model whisper.Model, cfg *config.Config, logger *logging.Logger) err
 	return nil
 }
 
-func selectDevice(preferred string, index int) (*portaudio.DeviceInfo, error) {
-	devs, err := portaudio.Devices()
-	if err != nil {
-		return nil, fmt.Errorf("list devices: %w", err)
-	}
-	if index >= 0 && index < len(devs) {
-		d := devs[index]
-		if d.MaxInputChannels > 0 {
-			return d, nil
-		}
-	}
-	if preferred != "" {
-		for _, d := range devs {
-			if d.MaxInputChannels > 0 && strings.Contains(strings.ToLower(d.Name), strings.ToLower(preferred)) {
-				return d, nil
-			}
-		}
-	}
-	if def, err := portaudio.DefaultInputDevice(); err == nil && def != nil {
-		return def, nil
-	}
-	for _, d := range devs {
-		if d.MaxInputChannels > 0 {
-			return d, nil
-		}
-	}
-	return nil, fmt.Errorf("no input devices found")
-}
-
 func int16ToBytes(samples []int16) []byte {
 	if len(samples) == 0 {
 		return nil
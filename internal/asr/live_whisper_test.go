@@ -5,6 +5,8 @@ import (
 	"errors"
 	"testing"
 	"time"
+
+	"github.com/ggerganov/whisper.cpp/bindings/go/pkg/whisper"
 )
 
 func TestStopTranscribeWorkerWaitsForExit(t *testing.T) {
@@ -53,3 +55,14 @@ func TestWaitForRetryStopsOnCancellation(t *testing.T) {
 		t.Fatalf("waitForRetry took %s after cancellation", elapsed)
 	}
 }
+
+func TestMeanProbabilitySkipsSpecialTokens(t *testing.T) {
+	isText := func(tok whisper.Token) bool { return tok.Id < 100 }
+	tokens := []whisper.Token{{Id: 1, P: 0.9}, {Id: 500, P: 0.1}, {Id: 2, P: 0.7}}
+	if got := meanProbability(tokens, isText); got < 0.799 || got > 0.801 {
+		t.Fatalf("mean = %v, want 0.8", got)
+	}
+	if got := meanProbability(tokens[1:2], isText); got != 0 {
+		t.Fatalf("no text tokens: %v", got)
+	}
+}
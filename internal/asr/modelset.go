@@ -0,0 +1,274 @@
+package asr
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"brabble/internal/config"
+	"brabble/internal/logging"
+
+	"github.com/ggerganov/whisper.cpp/bindings/go/pkg/whisper"
+)
+
+var errStopped = errors.New("recognizer stopped")
+
+// modelSet is one generation of loaded models, one per transcription worker.
+// The Go binding keeps decode state on the model, so contexts created from
+// one model cannot run concurrently; parallel workers each load a copy.
+// Decodes hold a reference, so a swapped-out or unloaded set is closed only
+// after the segments in flight on it finish.
+type modelSet struct {
+	path     string
+	models   []whisper.Model
+	inflight sync.WaitGroup
+}
+
+func (m *modelSet) release() { m.inflight.Done() }
+
+// close waits for in-flight decodes, then frees the models.
+func (m *modelSet) close(logger *logging.Logger) {
+	m.inflight.Wait()
+	for _, model := range m.models {
+		if err := model.Close(); err != nil {
+			logger.Warnf("close model: %v", err)
+		}
+	}
+}
+
+// ModelStats reports model residency and load cost.
+type ModelStats struct {
+	Loaded   bool
+	Loads    int64         // includes the initial load, swaps, and idle reloads
+	Unloads  int64         // idle unloads
+	LastLoad time.Duration // wall time of the most recent load incl. warmup
+}
+
+type modelStats struct {
+	loads    atomic.Int64
+	unloads  atomic.Int64
+	lastLoad atomic.Int64 // ns
+}
+
+func (s *modelStats) recordLoad(d time.Duration) {
+	s.loads.Add(1)
+	s.lastLoad.Store(int64(d))
+}
+
+// workerCount is asr.workers, at least 1.
+func workerCount(cfg *config.Config) int {
+	return max(1, cfg.ASR.Workers)
+}
+
+// loadModelSet loads and warms n copies of the model at path.
+func loadModelSet(path string, logger *logging.Logger, n int) (*modelSet, error) {
+	set := &modelSet{path: path, models: make([]whisper.Model, 0, n)}
+	for i := 0; i < n; i++ {
+		model, err := whisper.New(path)
+		if err != nil {
+			set.close(logger)
+			return nil, fmt.Errorf("load model: %w", err)
+		}
+		if err := warmup(model, logger); err != nil {
+			logger.Warnf("warmup: %v", err)
+		}
+		set.models = append(set.models, model)
+	}
+	if n > 1 {
+		logger.Infof("asr: %d transcription workers", n)
+	}
+	return set, nil
+}
+
+// acquireModels returns the current model set with a reference held; the
+// caller must release it. If the model was unloaded for idleness it is
+// reloaded first, so the caller (and the segments queued behind it) wait
+// out the load instead of losing audio.
+func (r *whisperRecognizer) acquireModels(ctx context.Context) (*modelSet, error) {
+	for {
+		r.modelMu.Lock()
+		if set := r.models; set != nil {
+			set.inflight.Add(1)
+			r.modelMu.Unlock()
+			return set, nil
+		}
+		if r.loadErr != nil && r.loading == nil {
+			// The previous reload failed; report it once and retry next time.
+			err := r.loadErr
+			r.loadErr = nil
+			r.modelMu.Unlock()
+			return nil, err
+		}
+		wait := r.startReloadLocked()
+		r.modelMu.Unlock()
+		select {
+		case <-wait:
+		case <-ctx.Done():
+			return nil, ctx.Err()
+		}
+	}
+}
+
+// prefetchModels starts reloading an unloaded model without waiting, so the
+// load overlaps with the speech that triggered it.
+func (r *whisperRecognizer) prefetchModels() {
+	r.modelMu.Lock()
+	defer r.modelMu.Unlock()
+	if r.models == nil && r.loading == nil {
+		r.startReloadLocked()
+	}
+}
+
+// startReloadLocked starts a background reload unless one is running and
+// returns a channel closed when it finishes. modelMu must be held.
+func (r *whisperRecognizer) startReloadLocked() <-chan struct{} {
+	if r.loading != nil {
+		return r.loading
+	}
+	done := make(chan struct{})
+	r.loading = done
+	r.loadErr = nil
+	go func() {
+		err := r.reload()
+		r.modelMu.Lock()
+		r.loadErr = err
+		r.loading = nil
+		r.modelMu.Unlock()
+		close(done)
+	}()
+	return done
+}
+
+func (r *whisperRecognizer) reload() error {
+	r.swapMu.Lock()
+	defer r.swapMu.Unlock()
+	if r.stopped {
+		return errStopped
+	}
+	r.modelMu.RLock()
+	loaded, path := r.models != nil, r.modelPath
+	r.modelMu.RUnlock()
+	if loaded {
+		return nil // a swap got there first
+	}
+	r.logger.Infof("speech detected; reloading model %s", path)
+	start := time.Now()
+	set, err := loadModelSet(path, r.logger, r.workers)
+	if err != nil {
+		r.logger.Errorf("reload model: %v", err)
+		return err
+	}
+	r.modelStats.recordLoad(time.Since(start))
+	r.logger.Infof("model reloaded in %s", time.Since(start).Round(time.Millisecond))
+	r.modelMu.Lock()
+	r.models = set
+	r.modelMu.Unlock()
+	return nil
+}
+
+// unloadIdle frees the model if nothing has been heard for idle and no
+// audio is waiting to be transcribed. It reports whether it unloaded.
+func (r *whisperRecognizer) unloadIdle(idle time.Duration, now time.Time) bool {
+	if now.Sub(time.Unix(0, r.lastActivity.Load())) < idle || len(r.backlog.queue) > 0 {
+		return false
+	}
+	r.swapMu.Lock()
+	defer r.swapMu.Unlock()
+	r.modelMu.Lock()
+	set := r.models
+	if set == nil || r.stopped {
+		r.modelMu.Unlock()
+		return false
+	}
+	r.models = nil
+	r.modelMu.Unlock()
+	set.close(r.logger)
+	r.modelStats.unloads.Add(1)
+	r.logger.Infof("no speech for %s; unloaded model %s", idle, set.path)
+	return true
+}
+
+// idleUnloader unloads the model after idle without voice activity.
+func (r *whisperRecognizer) idleUnloader(ctx context.Context, idle time.Duration) {
+	ticker := time.NewTicker(min(idle/2, 5*time.Second))
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case now := <-ticker.C:
+			r.unloadIdle(idle, now)
+		}
+	}
+}
+
+// noteActivity records voice activity and warms an unloaded model.
+func (r *whisperRecognizer) noteActivity(now time.Time) {
+	r.lastActivity.Store(now.UnixNano())
+	r.prefetchModels()
+}
+
+// ActiveModel returns the path of the model used for transcription, whether
+// or not it is currently resident.
+func (r *whisperRecognizer) ActiveModel() string {
+	r.modelMu.RLock()
+	defer r.modelMu.RUnlock()
+	return r.modelPath
+}
+
+// ModelStats reports whether the model is resident and what loading cost.
+func (r *whisperRecognizer) ModelStats() ModelStats {
+	r.modelMu.RLock()
+	loaded := r.models != nil
+	r.modelMu.RUnlock()
+	return ModelStats{
+		Loaded:   loaded,
+		Loads:    r.modelStats.loads.Load(),
+		Unloads:  r.modelStats.unloads.Load(),
+		LastLoad: time.Duration(r.modelStats.lastLoad.Load()),
+	}
+}
+
+// SwapModel loads and warms the model at path while the current one keeps
+// transcribing, switches new segments to it, and closes the old model once
+// its in-flight segments finish. On load failure the current model stays.
+func (r *whisperRecognizer) SwapModel(path string) error {
+	r.swapMu.Lock()
+	defer r.swapMu.Unlock()
+	if r.stopped {
+		return errStopped
+	}
+	start := time.Now()
+	next, err := loadModelSet(path, r.logger, r.workers)
+	if err != nil {
+		return err
+	}
+	r.modelStats.recordLoad(time.Since(start))
+	r.modelMu.Lock()
+	old := r.models
+	r.models = next
+	r.modelPath = path
+	r.modelMu.Unlock()
+	r.logger.Infof("model swapped to %s in %s", path, time.Since(start).Round(time.Millisecond))
+	if old != nil {
+		old.close(r.logger)
+	}
+	return nil
+}
+
+// closeModels frees the active set at shutdown and refuses later loads.
+func (r *whisperRecognizer) closeModels() {
+	r.swapMu.Lock()
+	defer r.swapMu.Unlock()
+	r.stopped = true
+	r.modelMu.Lock()
+	set := r.models
+	r.models = nil
+	r.modelMu.Unlock()
+	if set != nil {
+		set.close(r.logger)
+	}
+}
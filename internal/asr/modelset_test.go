@@ -0,0 +1,119 @@
+package asr
+
+import (
+	"context"
+	"path/filepath"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"brabble/internal/logging"
+
+	"github.com/ggerganov/whisper.cpp/bindings/go/pkg/whisper"
+)
+
+type fakeModel struct {
+	whisper.Model
+	closed atomic.Bool
+}
+
+func (m *fakeModel) Close() error {
+	m.closed.Store(true)
+	return nil
+}
+
+func newTestRecognizer(path string, model whisper.Model) *whisperRecognizer {
+	return &whisperRecognizer{
+		logger:    logging.NewTestLogger(),
+		models:    &modelSet{path: path, models: []whisper.Model{model}},
+		modelPath: path,
+		workers:   1,
+		backlog:   newBacklog(segmentQueueSize),
+	}
+}
+
+func TestModelSetCloseWaitsForInflightDecodes(t *testing.T) {
+	model := &fakeModel{}
+	r := newTestRecognizer("old.bin", model)
+	set, err := r.acquireModels(context.Background())
+	if err != nil {
+		t.Fatalf("acquire: %v", err)
+	}
+
+	done := make(chan struct{})
+	go func() {
+		r.closeModels()
+		close(done)
+	}()
+	select {
+	case <-done:
+		t.Fatal("model closed while a decode still held it")
+	case <-time.After(50 * time.Millisecond):
+	}
+	set.release()
+	select {
+	case <-done:
+	case <-time.After(time.Second):
+		t.Fatal("close did not finish after release")
+	}
+	if !model.closed.Load() {
+		t.Fatal("model not closed")
+	}
+	if err := r.SwapModel("new.bin"); err == nil {
+		t.Fatal("swap after shutdown should fail")
+	}
+}
+
+func TestSwapModelKeepsCurrentOnLoadFailure(t *testing.T) {
+	model := &fakeModel{}
+	r := newTestRecognizer("old.bin", model)
+	if err := r.SwapModel(filepath.Join(t.TempDir(), "missing.bin")); err == nil {
+		t.Fatal("expected load error")
+	}
+	if got := r.ActiveModel(); got != "old.bin" || model.closed.Load() {
+		t.Fatalf("active=%q closed=%v; current model should stay", got, model.closed.Load())
+	}
+}
+
+func TestUnloadIdleRespectsActivityAndBacklog(t *testing.T) {
+	model := &fakeModel{}
+	r := newTestRecognizer("model.bin", model)
+	now := time.Now()
+	r.lastActivity.Store(now.Add(-time.Minute).UnixNano())
+
+	r.backlog.queue <- segmentChunk{pcm: []int16{1}}
+	if r.unloadIdle(30*time.Second, now) {
+		t.Fatal("unloaded with audio waiting for ASR")
+	}
+	<-r.backlog.queue
+	if r.unloadIdle(2*time.Minute, now) {
+		t.Fatal("unloaded before idle timeout")
+	}
+	if !r.unloadIdle(30*time.Second, now) || !model.closed.Load() {
+		t.Fatal("idle model not unloaded")
+	}
+	st := r.ModelStats()
+	if st.Loaded || st.Unloads != 1 {
+		t.Fatalf("stats=%+v", st)
+	}
+	if r.ActiveModel() != "model.bin" {
+		t.Fatalf("unloading forgot the model path: %q", r.ActiveModel())
+	}
+}
+
+func TestAcquireAfterUnloadReloadsAndReportsFailure(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "gone.bin")
+	r := newTestRecognizer(path, &fakeModel{})
+	if !r.unloadIdle(0, time.Now()) {
+		t.Fatal("unload failed")
+	}
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+	if _, err := r.acquireModels(ctx); err == nil {
+		t.Fatal("expected reload error for missing model file")
+	}
+	// A failed reload is retried on the next acquire rather than cached.
+	if _, err := r.acquireModels(ctx); err == nil || ctx.Err() != nil {
+		t.Fatalf("second acquire: err=%v ctx=%v", err, ctx.Err())
+	}
+}
@@ -0,0 +1,161 @@
+package asr
+
+import (
+	"context"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"brabble/internal/logging"
+)
+
+// WorkerStats reports one transcription worker's cumulative load.
+type WorkerStats struct {
+	Busy     time.Duration
+	Segments int64
+}
+
+type workerStat struct {
+	busyNanos atomic.Int64
+	segments  atomic.Int64
+}
+
+// decodeFunc transcribes c on the given worker. ok is false when the chunk
+// produced no text (or failed, already logged).
+type decodeFunc func(ctx context.Context, worker int, c segmentChunk) (seg Segment, ok bool)
+
+type seqChunk struct {
+	seq   uint64
+	chunk segmentChunk
+}
+
+type seqResult struct {
+	seq uint64
+	seg Segment
+	ok  bool
+}
+
+// transcribePool fans segment chunks out to a fixed set of workers and
+// re-serializes their results in capture order, so a long segment on one
+// worker delays delivery of later ones but not their decoding.
+type transcribePool struct {
+	stats   []workerStat
+	backlog *backlog
+	logger  *logging.Logger
+	decode  decodeFunc
+}
+
+func newTranscribePool(workers int, b *backlog, logger *logging.Logger, decode decodeFunc) *transcribePool {
+	return &transcribePool{
+		stats:   make([]workerStat, max(1, workers)),
+		backlog: b,
+		logger:  logger,
+		decode:  decode,
+	}
+}
+
+func (p *transcribePool) workerStats() []WorkerStats {
+	out := make([]WorkerStats, len(p.stats))
+	for i := range p.stats {
+		out[i] = WorkerStats{
+			Busy:     time.Duration(p.stats[i].busyNanos.Load()),
+			Segments: p.stats[i].segments.Load(),
+		}
+	}
+	return out
+}
+
+// run consumes segs until it is closed or ctx ends, then waits for in-flight
+// decodes to finish.
+func (p *transcribePool) run(ctx context.Context, segs <-chan segmentChunk, out chan<- Segment) {
+	work := make(chan seqChunk)
+	results := make(chan seqResult, len(p.stats))
+	var wg sync.WaitGroup
+	for i := range p.stats {
+		wg.Add(1)
+		go func(i int) {
+			defer wg.Done()
+			p.worker(ctx, i, work, results)
+		}(i)
+	}
+	collectDone := make(chan struct{})
+	go func() {
+		defer close(collectDone)
+		collectInOrder(ctx, results, out)
+	}()
+
+	p.dispatch(ctx, segs, work)
+	close(work)
+	wg.Wait()
+	close(results)
+	<-collectDone
+}
+
+func (p *transcribePool) dispatch(ctx context.Context, segs <-chan segmentChunk, work chan<- seqChunk) {
+	var seq uint64
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case data, ok := <-segs:
+			if !ok {
+				return
+			}
+			if len(data.pcm) == 0 {
+				continue
+			}
+			if data.partial && len(segs) > 0 {
+				// Behind: newer audio is waiting, so this live preview is
+				// already stale. Spend the decode on what follows.
+				p.backlog.droppedPartials.Add(1)
+				p.logger.Debug("asr backlog, skipping partial")
+				continue
+			}
+			select {
+			case work <- seqChunk{seq: seq, chunk: data}:
+				seq++
+			case <-ctx.Done():
+				return
+			}
+		}
+	}
+}
+
+func (p *transcribePool) worker(ctx context.Context, id int, work <-chan seqChunk, results chan<- seqResult) {
+	for item := range work {
+		start := time.Now()
+		seg, ok := p.decode(ctx, id, item.chunk)
+		p.stats[id].busyNanos.Add(int64(time.Since(start)))
+		p.stats[id].segments.Add(1)
+		select {
+		case results <- seqResult{seq: item.seq, seg: seg, ok: ok}:
+		case <-ctx.Done():
+		}
+	}
+}
+
+// collectInOrder emits results by sequence number, holding early finishers
+// until every earlier segment has been emitted or discarded.
+func collectInOrder(ctx context.Context, results <-chan seqResult, out chan<- Segment) {
+	pending := map[uint64]seqResult{}
+	var next uint64
+	for res := range results {
+		pending[res.seq] = res
+		for {
+			r, ok := pending[next]
+			if !ok {
+				break
+			}
+			delete(pending, next)
+			next++
+			if !r.ok {
+				continue
+			}
+			select {
+			case out <- r.seg:
+			case <-ctx.Done():
+				return
+			}
+		}
+	}
+}
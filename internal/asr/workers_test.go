@@ -0,0 +1,80 @@
+package asr
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"brabble/internal/logging"
+)
+
+func TestTranscribePoolPreservesCaptureOrder(t *testing.T) {
+	// The first segment decodes slowest, so later ones finish first.
+	decode := func(_ context.Context, _ int, c segmentChunk) (Segment, bool) {
+		time.Sleep(time.Duration(40-10*int(c.pcm[0])) * time.Millisecond)
+		if c.pcm[0] == 2 {
+			return Segment{}, false // no speech in this one
+		}
+		return Segment{Text: string(rune('a' + c.pcm[0]))}, true
+	}
+	p := newTranscribePool(3, newBacklog(8), logging.NewTestLogger(), decode)
+	segs := make(chan segmentChunk, 4)
+	for i := int16(0); i < 4; i++ {
+		segs <- segmentChunk{pcm: []int16{i}}
+	}
+	close(segs)
+	out := make(chan Segment, 4)
+	p.run(context.Background(), segs, out)
+	close(out)
+
+	var got string
+	for seg := range out {
+		got += seg.Text
+	}
+	if got != "abd" {
+		t.Fatalf("order=%q want abd", got)
+	}
+	var total int64
+	for _, st := range p.workerStats() {
+		total += st.Segments
+		if st.Segments > 0 && st.Busy <= 0 {
+			t.Fatalf("worker busy time not tracked: %+v", st)
+		}
+	}
+	if total != 4 {
+		t.Fatalf("decoded %d segments, want 4", total)
+	}
+}
+
+func TestTranscribePoolRunsWorkersInParallel(t *testing.T) {
+	started := make(chan struct{}, 2)
+	release := make(chan struct{})
+	decode := func(_ context.Context, _ int, c segmentChunk) (Segment, bool) {
+		started <- struct{}{}
+		<-release
+		return Segment{Text: "x"}, true
+	}
+	p := newTranscribePool(2, newBacklog(8), logging.NewTestLogger(), decode)
+	segs := make(chan segmentChunk, 2)
+	segs <- segmentChunk{pcm: []int16{1}}
+	segs <- segmentChunk{pcm: []int16{2}}
+	close(segs)
+	out := make(chan Segment, 2)
+	done := make(chan struct{})
+	go func() {
+		p.run(context.Background(), segs, out)
+		close(done)
+	}()
+	for i := 0; i < 2; i++ {
+		select {
+		case <-started:
+		case <-time.After(time.Second):
+			t.Fatal("second worker never started while the first was busy")
+		}
+	}
+	close(release)
+	<-done
+	if len(out) != 2 {
+		t.Fatalf("got %d segments", len(out))
+	}
+}
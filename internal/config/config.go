@@ -23,14 +23,50 @@ const (
 	defaultConfigDir     = ".config/brabble"
 )
 
+// Wake modes decide when the mic produces segments.
+const (
+	WakeModeAlways     = "always"       // VAD segments, gated by the wake word
+	WakeModePushToTalk = "push_to_talk" // only between ptt start and stop
+)
+
+// DeviceID identifies an input device across restarts and hot-plugs, unlike a
+// PortAudio index.
+type DeviceID struct {
+	Name     string `toml:"name"`
+	HostAPI  string `toml:"host_api"`
+	Channels int    `toml:"channels"`
+}
+
+func (d DeviceID) String() string {
+	if d.HostAPI == "" {
+		return fmt.Sprintf("%q (%d ch)", d.Name, d.Channels)
+	}
+	return fmt.Sprintf("%q (%s, %d ch)", d.Name, d.HostAPI, d.Channels)
+}
+
 // Config holds user configuration loaded from TOML.
 type Config struct {
 	Audio struct {
-		DeviceName  string `toml:"device_name"`
-		DeviceIndex int    `toml:"device_index"`
-		SampleRate  int    `toml:"sample_rate"`
-		Channels    int    `toml:"channels"`
-		FrameMS     int    `toml:"frame_ms"`
+		DeviceName  string   `toml:"device_name"`
+		DeviceIndex int      `toml:"device_index"` // legacy; indices shift as devices come and go
+		DeviceID    DeviceID `toml:"device_id"`    // written by mic set; preferred over name/index
+		// PreferredDevices is a priority list of device name substrings; the
+		// first present one wins over device_name/device_index.
+		PreferredDevices []string `toml:"preferred_devices"`
+		DeviceRescanSec  float64  `toml:"device_rescan_sec"` // re-evaluate preferred_devices while idle; 0 = off
+		SampleRate       int      `toml:"sample_rate"`       // capture rate; 0 = device native, resampled to 16 kHz
+		Channels         int      `toml:"channels"`
+		ChannelMode      string   `toml:"channel_mode"` // downmix, select, loudest
+		Channel          int      `toml:"channel"`      // 0-based; used by channel_mode = "select"
+		FrameMS          int      `toml:"frame_ms"`
+
+		// Optional pre-VAD cleanup, applied in this order.
+		HighPass         bool    `toml:"high_pass"`
+		HighPassHz       float64 `toml:"high_pass_hz"`
+		NoiseSuppression bool    `toml:"noise_suppression"`
+		AGC              bool    `toml:"agc"`
+		AGCTargetDBFS    float64 `toml:"agc_target_dbfs"`
+		AGCMaxGainDB     float64 `toml:"agc_max_gain_db"`
 	} `toml:"audio"`
 
 	VAD struct {
@@ -48,6 +84,11 @@ type Config struct {
 		Language    string `toml:"language"`
 		ComputeType string `toml:"compute_type"` // q5_1, q8_0, float16
 		Device      string `toml:"device"`       // auto, cpu, metal
+		Workers     int    `toml:"workers"`      // parallel transcription workers; each loads its own model copy
+		// IdleUnloadSec frees the model after this long without voice
+		// activity; it reloads on the next speech onset. 0 keeps it resident;
+		// otherwise it must be at least 1.
+		IdleUnloadSec float64 `toml:"idle_unload_sec"`
 	} `toml:"asr"`
 
 	Wake struct {
@@ -55,19 +96,32 @@ type Config struct {
 		Word        string   `toml:"word"`
 		Aliases     []string `toml:"aliases"`
 		Sensitivity float64  `toml:"sensitivity"`
+		Mode        string   `toml:"mode"` // always or push_to_talk
+		// ConversationSec is how long a hook's {"continue": true} response
+		// lets follow-ups skip the wake word and go to the same hook.
+		ConversationSec float64 `toml:"conversation_sec"`
 	} `toml:"wake"`
 
 	Hook struct {
-		Command      string            `toml:"command"`
-		Args         []string          `toml:"args"`
-		Prefix       string            `toml:"prefix"`
-		CooldownSec  float64           `toml:"cooldown_sec"`
-		MinChars     int               `toml:"min_chars"`
-		MaxLatencyMS int               `toml:"max_latency_ms"`
-		QueueSize    int               `toml:"queue_size"`
-		TimeoutSec   float64           `toml:"timeout_sec"`
-		Env          map[string]string `toml:"env"`
-		RedactPII    bool              `toml:"redact_pii"`
+		Command           string            `toml:"command"`
+		Args              []string          `toml:"args"`
+		Payload           string            `toml:"payload"`
+		Prefix            string            `toml:"prefix"`
+		CooldownSec       float64           `toml:"cooldown_sec"`
+		MinChars          int               `toml:"min_chars"`
+		MaxLatencyMS      int               `toml:"max_latency_ms"`
+		StalePolicy       string            `toml:"stale_policy"`
+		QueueSize         int               `toml:"queue_size"`
+		Concurrency       int               `toml:"concurrency"`
+		TimeoutSec        float64           `toml:"timeout_sec"`
+		Retries           int               `toml:"retries"`
+		RetryBackoffMS    int               `toml:"retry_backoff_ms"`
+		RetryMaxBackoffMS int               `toml:"retry_max_backoff_ms"`
+		RetryExitCodes    []int             `toml:"retry_exit_codes"`
+		Env               map[string]string `toml:"env"`
+		RedactPII         bool              `toml:"redact_pii"`
+		Output            string            `toml:"output"`
+		OutputMaxBytes    int               `toml:"output_max_bytes"`
 	} `toml:"hook"`
 
 	Hooks []HookConfig `toml:"hooks"`
@@ -84,6 +138,8 @@ type Config struct {
 		TranscriptPath string `toml:"transcript_path"`
 		SocketPath     string `toml:"socket_path"`
 		PidPath        string `toml:"pid_path"`
+		DeadLetterPath string `toml:"dead_letter_path"` // hook jobs that failed all retries
+		JournalPath    string `toml:"journal_path"`     // persistent hook queue (queue.persist)
 		ConfigPath     string `toml:"-"`
 	} `toml:"paths"`
 
@@ -100,6 +156,37 @@ type Config struct {
 		Addr    string `toml:"addr"`
 	} `toml:"metrics"`
 
+	// API serves the control ops over HTTP and events over WebSocket.
+	API struct {
+		Enabled        bool     `toml:"enabled"`
+		Addr           string   `toml:"addr"`            // loopback by default; e.g. 0.0.0.0:9318 for the LAN
+		Token          string   `toml:"token"`           // bearer token, required; BRABBLE_API_TOKEN overrides
+		AllowedOrigins []string `toml:"allowed_origins"` // CORS origins for browser dashboards ("*" = any)
+		AllowedOps     []string `toml:"allowed_ops"`     // ops reachable via POST /v1/ops/{op}
+	} `toml:"api"`
+
+	// Queue makes queued hook jobs survive restarts.
+	Queue struct {
+		Persist bool `toml:"persist"` // journal jobs to paths.journal_path; replay unfinished ones on start
+	} `toml:"queue"`
+
+	// Responder gives audible feedback: earcons and spoken hook replies.
+	Responder struct {
+		Enabled      bool     `toml:"enabled"`
+		Player       string   `toml:"player"`        // plays earcon files, given as the last argument
+		PlayerArgs   []string `toml:"player_args"`   // before the file
+		WakeSound    string   `toml:"wake_sound"`    // earcon on wake word (or push-to-talk); empty = none
+		SuccessSound string   `toml:"success_sound"` // earcon when a hook job succeeds
+		FailureSound string   `toml:"failure_sound"` // earcon when it finally fails
+		Speak        bool     `toml:"speak"`         // speak "say" from JSON hook responses
+		TTSCommand   string   `toml:"tts_command"`   // e.g. say, espeak, or a piper wrapper
+		TTSArgs      []string `toml:"tts_args"`
+		TTSStdin     bool     `toml:"tts_stdin"`   // send text on stdin instead of as the last argument
+		TimeoutSec   float64  `toml:"timeout_sec"` // per sound or utterance
+		Duck         bool     `toml:"duck"`        // ignore the mic while playing
+		DuckTailMS   int      `toml:"duck_tail_ms"`
+	} `toml:"responder"`
+
 	Transcripts struct {
 		Enabled bool `toml:"enabled"`
 	} `toml:"transcripts"`
@@ -120,9 +207,18 @@ func Default() (*Config, error) {
 
 	cfg := &Config{}
 
+	cfg.Audio.DeviceRescanSec = 10
 	cfg.Audio.SampleRate = 16000
 	cfg.Audio.Channels = 1
+	cfg.Audio.ChannelMode = "downmix"
+	cfg.Audio.Channel = 0
 	cfg.Audio.FrameMS = 20
+	cfg.Audio.HighPass = false
+	cfg.Audio.HighPassHz = 80
+	cfg.Audio.NoiseSuppression = false
+	cfg.Audio.AGC = false
+	cfg.Audio.AGCTargetDBFS = -20
+	cfg.Audio.AGCMaxGainDB = 30
 
 	cfg.VAD.Enabled = true
 	cfg.VAD.SilenceMS = defaultSilenceMS
@@ -137,22 +233,32 @@ func Default() (*Config, error) {
 	cfg.ASR.Language = "auto"
 	cfg.ASR.ComputeType = "q5_1"
 	cfg.ASR.Device = "auto"
+	cfg.ASR.Workers = 1
 
 	cfg.Wake.Enabled = true
 	cfg.Wake.Word = DefaultWakeWord
 	cfg.Wake.Aliases = []string{"claude"}
 	cfg.Wake.Sensitivity = 0.6
+	cfg.Wake.Mode = WakeModeAlways
+	cfg.Wake.ConversationSec = 10
 
 	cfg.Hook.Command = ""
 	cfg.Hook.Args = []string{}
+	cfg.Hook.Payload = PayloadArgv
 	cfg.Hook.Prefix = "Voice brabble from ${hostname}: "
 	cfg.Hook.CooldownSec = defaultCooldown
 	cfg.Hook.MinChars = defaultMinChars
 	cfg.Hook.MaxLatencyMS = 5000
+	cfg.Hook.StalePolicy = StaleDrop
 	cfg.Hook.QueueSize = 16
+	cfg.Hook.Concurrency = 1
 	cfg.Hook.TimeoutSec = 30
+	cfg.Hook.RetryBackoffMS = DefaultRetryBackoffMS
+	cfg.Hook.RetryMaxBackoffMS = DefaultRetryMaxBackoffMS
 	cfg.Hook.Env = map[string]string{}
 	cfg.Hook.RedactPII = false
+	cfg.Hook.Output = OutputText
+	cfg.Hook.OutputMaxBytes = 16 << 10
 
 	// Default hook entry mirrors single hook (users can override).
 	cfg.Hooks = []HookConfig{}
@@ -166,6 +272,8 @@ func Default() (*Config, error) {
 	cfg.Paths.TranscriptPath = filepath.Join(stateDir, "transcripts.log")
 	cfg.Paths.SocketPath = filepath.Join(stateDir, "brabble.sock")
 	cfg.Paths.PidPath = filepath.Join(stateDir, "brabble.pid")
+	cfg.Paths.DeadLetterPath = filepath.Join(stateDir, "hooks-dlq.jsonl")
+	cfg.Paths.JournalPath = filepath.Join(stateDir, "hooks-journal.jsonl")
 
 	cfg.UI.StatusTail = defaultStatusTail
 
@@ -173,6 +281,21 @@ func Default() (*Config, error) {
 
 	cfg.Metrics.Enabled = false
 	cfg.Metrics.Addr = "127.0.0.1:9317"
+	cfg.API.Addr = "127.0.0.1:9318"
+	cfg.API.AllowedOps = []string{"inject", "mute_hooks", "unmute_hooks", "ptt_start", "ptt_stop", "ptt_toggle"}
+
+	cfg.Responder.Player = "paplay"
+	cfg.Responder.TTSCommand = "espeak"
+	if isMac() {
+		cfg.Responder.Player = "afplay"
+		cfg.Responder.TTSCommand = "say"
+	}
+	cfg.Responder.PlayerArgs = []string{}
+	cfg.Responder.Speak = true
+	cfg.Responder.TTSArgs = []string{}
+	cfg.Responder.TimeoutSec = 30
+	cfg.Responder.Duck = true
+	cfg.Responder.DuckTailMS = 300
 
 	cfg.Transcripts.Enabled = true
 
@@ -238,18 +361,27 @@ func (cfg *Config) EffectiveHooks() []HookConfig {
 		return nil
 	}
 	return []HookConfig{{
-		Wake:        append([]string(nil), cfg.Wake.Word),
-		Aliases:     append([]string(nil), cfg.Wake.Aliases...),
-		Command:     cfg.Hook.Command,
-		Args:        append([]string(nil), cfg.Hook.Args...),
-		Prefix:      cfg.Hook.Prefix,
-		CooldownSec: cfg.Hook.CooldownSec,
-		MinChars:    cfg.Hook.MinChars,
-		MaxLatency:  cfg.Hook.MaxLatencyMS,
-		QueueSize:   cfg.Hook.QueueSize,
-		TimeoutSec:  cfg.Hook.TimeoutSec,
-		Env:         cfg.Hook.Env,
-		RedactPII:   cfg.Hook.RedactPII,
+		Wake:              append([]string(nil), cfg.Wake.Word),
+		Aliases:           append([]string(nil), cfg.Wake.Aliases...),
+		Command:           cfg.Hook.Command,
+		Args:              append([]string(nil), cfg.Hook.Args...),
+		Payload:           cfg.Hook.Payload,
+		Prefix:            cfg.Hook.Prefix,
+		CooldownSec:       cfg.Hook.CooldownSec,
+		MinChars:          cfg.Hook.MinChars,
+		MaxLatency:        cfg.Hook.MaxLatencyMS,
+		StalePolicy:       cfg.Hook.StalePolicy,
+		QueueSize:         cfg.Hook.QueueSize,
+		Concurrency:       cfg.Hook.Concurrency,
+		TimeoutSec:        cfg.Hook.TimeoutSec,
+		Retries:           cfg.Hook.Retries,
+		RetryBackoffMS:    cfg.Hook.RetryBackoffMS,
+		RetryMaxBackoffMS: cfg.Hook.RetryMaxBackoffMS,
+		RetryExitCodes:    append([]int(nil), cfg.Hook.RetryExitCodes...),
+		Env:               cfg.Hook.Env,
+		RedactPII:         cfg.Hook.RedactPII,
+		Output:            cfg.Hook.Output,
+		OutputMaxBytes:    cfg.Hook.OutputMaxBytes,
 	}}
 }
 
@@ -259,7 +391,7 @@ func isMac() bool {
 
 // MustStatePaths ensures state dirs exist.
 func MustStatePaths(cfg *Config) error {
-	for _, p := range []string{cfg.Paths.StateDir, filepath.Dir(cfg.Paths.LogPath), filepath.Dir(cfg.Paths.TranscriptPath)} {
+	for _, p := range []string{cfg.Paths.StateDir, filepath.Dir(cfg.Paths.LogPath), filepath.Dir(cfg.Paths.TranscriptPath), filepath.Dir(cfg.Paths.DeadLetterPath), filepath.Dir(cfg.Paths.JournalPath)} {
 		if p == "" {
 			continue
 		}
@@ -278,6 +410,9 @@ func applyEnvOverrides(cfg *Config) {
 		cfg.Metrics.Addr = v
 		cfg.Metrics.Enabled = true
 	}
+	if v := os.Getenv("BRABBLE_API_TOKEN"); v != "" {
+		cfg.API.Token = v
+	}
 	if v := os.Getenv("BRABBLE_LOG_LEVEL"); v != "" {
 		cfg.Logging.Level = v
 	}
@@ -40,6 +40,7 @@ func TestSaveAndLoadRoundTrip(t *testing.T) {
 	}
 	cfg.Paths.ConfigPath = path
 	cfg.Hook.Command = "/bin/echo"
+	cfg.Audio.DeviceID = DeviceID{Name: "USB Mic", HostAPI: "Core Audio", Channels: 2}
 
 	if err := Save(cfg, path); err != nil {
 		t.Fatalf("save: %v", err)
@@ -51,6 +52,9 @@ func TestSaveAndLoadRoundTrip(t *testing.T) {
 	if loaded.Hook.Command != "/bin/echo" {
 		t.Fatalf("expected hook command to persist")
 	}
+	if loaded.Audio.DeviceID != cfg.Audio.DeviceID {
+		t.Fatalf("device_id=%+v want %+v", loaded.Audio.DeviceID, cfg.Audio.DeviceID)
+	}
 
 	// cleanup to avoid residue
 	_ = os.Remove(path)
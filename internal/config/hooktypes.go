@@ -1,17 +1,109 @@
 package config
 
+// Stale policies for hook jobs that exceed max_latency_ms.
+const (
+	StaleDrop = "drop" // discard the job (default)
+	StaleMark = "mark" // run it with BRABBLE_STALE=1
+)
+
+// Retry backoff defaults, used by Default and when a hook entry sets
+// retries but not the backoff.
+const (
+	DefaultRetryBackoffMS    = 500
+	DefaultRetryMaxBackoffMS = 30000
+)
+
+// Hook types.
+const (
+	HookTypeCommand = "command" // run a local binary (default)
+	HookTypeWebhook = "webhook" // send an HTTP request
+)
+
+// Payload modes: how a command hook receives the transcript. BRABBLE_TEXT
+// is set in every mode.
+const (
+	PayloadArgv      = "argv"       // last argument (default)
+	PayloadStdin     = "stdin"      // plain text on stdin
+	PayloadStdinJSON = "stdin_json" // JSON document on stdin
+	PayloadEnvOnly   = "env_only"   // environment only
+)
+
+// Output modes: how a hook's stdout (or webhook response body) is read.
+const (
+	OutputText = "text" // logged and kept with the result (default)
+	OutputJSON = "json" // also parsed as a response, e.g. {"say": "...", "continue": true}
+)
+
+// Hook match kinds: how an entry is selected for an utterance.
+const (
+	MatchWake   = "wake"   // wake/alias tokens appear in the text (default)
+	MatchRegex  = "regex"  // a pattern matches; named groups become slots
+	MatchIntent = "intent" // a pattern like "turn {state:on|off} the {device}" matches the whole command
+)
+
+// Chain step failure policies.
+const (
+	ChainStop     = "stop"     // the chain ends and the job fails (default)
+	ChainContinue = "continue" // the next step gets this step's input
+)
+
+// ChainStep is one step of a hook's chain, e.g.
+// chain = [{hook = 2, on_failure = "continue"}].
+type ChainStep struct {
+	Hook      int    `toml:"hook"`       // index of the hook to run
+	OnFailure string `toml:"on_failure"` // stop (default) or continue
+}
+
 // HookConfig defines a per-wake hook invocation entry.
 type HookConfig struct {
-	Wake        []string          `toml:"wake"`    // tokens to match (case-insensitive)
-	Aliases     []string          `toml:"aliases"` // optional extra tokens
-	Command     string            `toml:"command"`
-	Args        []string          `toml:"args"`
-	Prefix      string            `toml:"prefix"`
-	CooldownSec float64           `toml:"cooldown_sec"`
-	MinChars    int               `toml:"min_chars"`
-	MaxLatency  int               `toml:"max_latency_ms"`
-	QueueSize   int               `toml:"queue_size"`
-	TimeoutSec  float64           `toml:"timeout_sec"`
-	Env         map[string]string `toml:"env"`
-	RedactPII   bool              `toml:"redact_pii"`
+	Type        string   `toml:"type"`     // command (default) or webhook
+	Match       string   `toml:"match"`    // wake (default), regex, or intent
+	Patterns    []string `toml:"patterns"` // for match = regex or intent
+	Wake        []string `toml:"wake"`     // tokens to match (case-insensitive)
+	Aliases     []string `toml:"aliases"`  // optional extra tokens
+	Command     string   `toml:"command"`
+	Args        []string `toml:"args"`
+	Payload     string   `toml:"payload"` // argv (default), stdin, stdin_json, or env_only
+	Prefix      string   `toml:"prefix"`
+	CooldownSec float64  `toml:"cooldown_sec"`
+	MinChars    int      `toml:"min_chars"`
+	MaxLatency  int      `toml:"max_latency_ms"` // end of speech → hook start; 0 = unlimited
+	StalePolicy string   `toml:"stale_policy"`   // drop (default) or mark
+	QueueSize   int      `toml:"queue_size"`
+	Concurrency int      `toml:"concurrency"` // parallel runs of this hook; default 1
+	TimeoutSec  float64  `toml:"timeout_sec"`
+	Retries     int      `toml:"retries"` // extra attempts after a retryable failure
+	// Backoff doubles from RetryBackoffMS up to RetryMaxBackoffMS, jittered.
+	RetryBackoffMS    int               `toml:"retry_backoff_ms"`
+	RetryMaxBackoffMS int               `toml:"retry_max_backoff_ms"`
+	RetryExitCodes    []int             `toml:"retry_exit_codes"` // empty = any non-zero exit
+	Env               map[string]string `toml:"env"`
+	RedactPII         bool              `toml:"redact_pii"`
+
+	// Output handling; see hook.Result.
+	Output         string `toml:"output"`           // text (default) or json
+	OutputMaxBytes int    `toml:"output_max_bytes"` // kept per stream; 0 = hook.DefaultOutputMaxBytes
+	ReplyHook      *int   `toml:"reply_hook"`       // hook index that receives a json response's "say"
+
+	// Routing to other hooks, by index. Targets and steps are ordinary
+	// [[hooks]] entries, usually without wake tokens of their own.
+	FanOut    []int       `toml:"fan_out"`    // also queue the job for these hooks
+	Chain     []ChainStep `toml:"chain"`      // then run these in order, each getting the previous output
+	OnFailure string      `toml:"on_failure"` // when this entry fails: stop its chain (default) or continue
+
+	// Webhook settings, used when Type is "webhook".
+	URL          string            `toml:"url"`
+	Method       string            `toml:"method"`        // default POST
+	Headers      map[string]string `toml:"headers"`       // values expand ${VAR} from env, then the environment
+	Body         string            `toml:"body"`          // text/template producing JSON; empty = default object
+	ExpectStatus []int             `toml:"expect_status"` // accepted codes; empty = any 2xx
+	TLS          HookTLS           `toml:"tls"`
+}
+
+// HookTLS configures TLS for webhook hooks.
+type HookTLS struct {
+	CAFile             string `toml:"ca_file"`   // extra PEM roots, e.g. a local CA
+	CertFile           string `toml:"cert_file"` // client certificate (with key_file)
+	KeyFile            string `toml:"key_file"`
+	InsecureSkipVerify bool   `toml:"insecure_skip_verify"`
 }
@@ -0,0 +1,90 @@
+package control
+
+import (
+	"bufio"
+	"encoding/json"
+	"fmt"
+	"net"
+	"strconv"
+)
+
+// Client speaks the versioned protocol over one control connection.
+type Client struct {
+	conn   net.Conn
+	enc    *json.Encoder
+	dec    *json.Decoder
+	nextID int
+}
+
+// Dial connects to the daemon's control socket.
+func Dial(socketPath string) (*Client, error) {
+	conn, err := net.Dial("unix", socketPath)
+	if err != nil {
+		return nil, fmt.Errorf("cannot connect to daemon: %w", err)
+	}
+	return &Client{conn: conn, enc: json.NewEncoder(conn), dec: json.NewDecoder(bufio.NewReader(conn))}, nil
+}
+
+// Close closes the connection.
+func (c *Client) Close() error { return c.conn.Close() }
+
+// Do sends op with args (nil for none) and decodes the result into result
+// (nil to discard). A daemon-side failure is returned as *Error.
+func (c *Client) Do(op string, args, result any) error {
+	c.nextID++
+	req := Request{V: ProtocolVersion, ID: strconv.Itoa(c.nextID), Op: op}
+	if args != nil {
+		raw, err := json.Marshal(args)
+		if err != nil {
+			return fmt.Errorf("encode %s args: %w", op, err)
+		}
+		req.Args = raw
+	}
+	if err := c.enc.Encode(req); err != nil {
+		return err
+	}
+	var resp Response
+	if err := c.dec.Decode(&resp); err != nil {
+		return fmt.Errorf("read %s response: %w", op, err)
+	}
+	if resp.ID != req.ID {
+		return fmt.Errorf("read %s response: id %q, want %q", op, resp.ID, req.ID)
+	}
+	if !resp.OK {
+		if resp.Error == nil {
+			return &Error{Code: ErrFailed, Message: op + " failed"}
+		}
+		return resp.Error
+	}
+	if result == nil || len(resp.Result) == 0 {
+		return nil
+	}
+	if err := json.Unmarshal(resp.Result, result); err != nil {
+		return fmt.Errorf("decode %s result: %w", op, err)
+	}
+	return nil
+}
+
+// Subscribe starts an event stream; the connection carries only events
+// afterwards. Read them with NextEvent.
+func (c *Client) Subscribe(types []string) error {
+	return c.Do("subscribe", SubscribeArgs{Types: types}, nil)
+}
+
+// NextEvent blocks for the next event of a subscription.
+func (c *Client) NextEvent() (Event, error) {
+	var ev Event
+	err := c.dec.Decode(&ev)
+	return ev, err
+}
+
+// Call sends a single request to the daemon's control socket and decodes
+// its result into result.
+func Call(socketPath, op string, args, result any) error {
+	c, err := Dial(socketPath)
+	if err != nil {
+		return err
+	}
+	defer func() { _ = c.Close() }()
+	return c.Do(op, args, result)
+}
@@ -0,0 +1,67 @@
+package control
+
+import (
+	"bufio"
+	"encoding/json"
+	"errors"
+	"net"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestClientDoSharesConnectionAndMapsErrors(t *testing.T) {
+	dir, err := os.MkdirTemp("", "brabble-client")
+	if err != nil {
+		t.Fatalf("temp dir: %v", err)
+	}
+	t.Cleanup(func() { _ = os.RemoveAll(dir) })
+	socketPath := filepath.Join(dir, "c.sock")
+	ln, err := net.Listen("unix", socketPath)
+	if err != nil {
+		t.Fatalf("listen: %v", err)
+	}
+	defer func() { _ = ln.Close() }()
+	accepted := make(chan int, 1)
+	go func() {
+		conn, err := ln.Accept()
+		if err != nil {
+			return
+		}
+		defer func() { _ = conn.Close() }()
+		sc := bufio.NewScanner(conn)
+		enc := json.NewEncoder(conn)
+		n := 0
+		for sc.Scan() {
+			n++
+			var req Request
+			_ = json.Unmarshal(sc.Bytes(), &req)
+			resp := Response{V: ProtocolVersion, ID: req.ID}
+			if req.Op == "health" {
+				resp.OK, resp.Result = true, json.RawMessage(`{"ok":true,"message":"ok"}`)
+			} else {
+				resp.Error = &Error{Code: ErrUnknownOp, Message: "unknown op"}
+			}
+			_ = enc.Encode(resp)
+		}
+		accepted <- n
+	}()
+
+	c, err := Dial(socketPath)
+	if err != nil {
+		t.Fatalf("dial: %v", err)
+	}
+	var health SimpleResponse
+	if err := c.Do("health", nil, &health); err != nil || health.Message != "ok" {
+		t.Fatalf("health: %+v %v", health, err)
+	}
+	err = c.Do("bogus", nil, nil)
+	var ce *Error
+	if !errors.As(err, &ce) || ce.Code != ErrUnknownOp {
+		t.Fatalf("bogus: %v", err)
+	}
+	_ = c.Close()
+	if n := <-accepted; n != 2 {
+		t.Fatalf("server saw %d requests on the connection, want 2", n)
+	}
+}
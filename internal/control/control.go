@@ -1,17 +1,162 @@
 package control
 
-import "time"
+import (
+	"encoding/json"
+	"time"
+)
 
-// Request describes an operation sent over the control socket.
+// ProtocolVersion is the control protocol spoken by this build. Requests
+// without "v" are version 0: flat arguments and bare Status/SimpleResponse
+// replies, as sent by older clients.
+const ProtocolVersion = 1
+
+// Request describes an operation sent over the control socket, one JSON
+// object per line. A connection may carry any number of requests; each gets
+// one response line, in order.
 type Request struct {
-	Op string `json:"op"`
+	V    int             `json:"v,omitempty"`
+	ID   string          `json:"id,omitempty"` // echoed in the response
+	Op   string          `json:"op"`
+	Args json.RawMessage `json:"args,omitempty"` // op-specific; see DurationArgs, ModelArgs
+
+	// Version 0 clients send arguments inline.
+	Model       string  `json:"model,omitempty"`
+	DurationSec float64 `json:"duration_sec,omitempty"`
+}
+
+// Response is the version 1 reply envelope. Result holds the op's payload
+// (Status, Capabilities, or SimpleResponse) when OK; Error is set otherwise.
+type Response struct {
+	V      int             `json:"v"`
+	ID     string          `json:"id,omitempty"`
+	OK     bool            `json:"ok"`
+	Result json.RawMessage `json:"result,omitempty"`
+	Error  *Error          `json:"error,omitempty"`
+}
+
+// Error codes carried in Response.Error.
+const (
+	ErrBadRequest         = "bad_request"         // malformed JSON or args
+	ErrUnknownOp          = "unknown_op"          // op not in capabilities
+	ErrUnsupportedVersion = "unsupported_version" // v newer than ProtocolVersion
+	ErrFailed             = "failed"              // the op ran and failed
+	ErrUnauthorized       = "unauthorized"        // HTTP API: bad or missing token
+	ErrForbidden          = "forbidden"           // HTTP API: op not in api.allowed_ops
+)
+
+// Error is a structured control error.
+type Error struct {
+	Code    string `json:"code"`
+	Message string `json:"message"`
+}
+
+func (e *Error) Error() string { return e.Message }
+
+// Capabilities lists the protocol version and ops a daemon supports.
+type Capabilities struct {
+	Version int      `json:"version"`
+	Ops     []OpInfo `json:"ops"`
+}
+
+// OpInfo describes one control op.
+type OpInfo struct {
+	Name    string `json:"name"`
+	Summary string `json:"summary"`
+}
+
+// DurationArgs are the args of pause and mute_hooks.
+type DurationArgs struct {
+	DurationSec float64 `json:"duration_sec,omitempty"` // 0 = until undone
+}
+
+// ModelArgs are the args of use_model.
+type ModelArgs struct {
+	Model string `json:"model"` // path to load
+}
+
+// SubscribeArgs are the args of subscribe.
+type SubscribeArgs struct {
+	Types []string `json:"types,omitempty"` // event types to receive; empty = all
+}
+
+// Event types streamed by subscribe.
+const (
+	EventPartial      = "partial"       // interim transcript
+	EventFinal        = "final"         // completed transcript
+	EventWake         = "wake"          // wake word matched (or push-to-talk)
+	EventHookDispatch = "hook_dispatch" // job queued for a hook
+	EventHookResult   = "hook_result"   // hook finished; Error set on failure
+	EventDevice       = "device"        // capture moved to another mic
+	EventError        = "error"         // daemon-side failure worth surfacing
+)
+
+// EventTypes lists every event type, for validating filters.
+var EventTypes = []string{EventPartial, EventFinal, EventWake, EventHookDispatch, EventHookResult, EventDevice, EventError}
+
+// Event is one line of a subscribe stream.
+type Event struct {
+	Type       string    `json:"type"`
+	Time       time.Time `json:"time"`
+	Text       string    `json:"text,omitempty"`
+	Hook       string    `json:"hook,omitempty"` // hook command, or URL for webhooks
+	Device     string    `json:"device,omitempty"`
+	Error      string    `json:"error,omitempty"`
+	DurationMS int64     `json:"duration_ms,omitempty"` // hook_result run time
+	// hook_result output; see HookResult.
+	Stdout   string `json:"stdout,omitempty"`
+	Stderr   string `json:"stderr,omitempty"`
+	Say      string `json:"say,omitempty"`
+	Continue bool   `json:"continue,omitempty"`
+	// Missed counts events dropped for this subscriber, because it read too
+	// slowly, since the previous one it received.
+	Missed int64 `json:"missed,omitempty"`
+}
+
+// TestHookArgs are the args of test_hook.
+type TestHookArgs struct {
+	Text string `json:"text"`
+}
+
+// InjectArgs are the args of inject.
+type InjectArgs struct {
+	Text    string `json:"text"`
+	Partial bool   `json:"partial,omitempty"` // deliver as an interim transcript
+	Raw     bool   `json:"raw,omitempty"`     // bypass the wake word requirement
 }
 
 // Status reports daemon health and recent transcripts.
 type Status struct {
-	Running     bool         `json:"running"`
-	UptimeSec   float64      `json:"uptime_sec"`
-	Transcripts []Transcript `json:"transcripts"`
+	Running           bool         `json:"running"`
+	UptimeSec         float64      `json:"uptime_sec"`
+	Device            string       `json:"device,omitempty"`
+	Model             string       `json:"model,omitempty"`
+	ModelUnloaded     bool         `json:"model_unloaded,omitempty"` // released while idle
+	Paused            bool         `json:"paused,omitempty"`         // mic closed via pause
+	PausedUntil       *time.Time   `json:"paused_until,omitempty"`
+	HooksMuted        bool         `json:"hooks_muted,omitempty"`
+	MutedUntil        *time.Time   `json:"muted_until,omitempty"`
+	Talking           bool         `json:"ptt_talking,omitempty"`        // push-to-talk held
+	ConversationUntil *time.Time   `json:"conversation_until,omitempty"` // follow-ups skip the wake word until then
+	Transcripts       []Transcript `json:"transcripts"`
+	HookResults       []HookResult `json:"hook_results,omitempty"` // most recent last
+}
+
+// HookResult is the outcome of one hook job, kept for status.
+type HookResult struct {
+	Time       time.Time `json:"time"`
+	Hook       int       `json:"hook"`             // index in the effective hooks
+	Target     string    `json:"target,omitempty"` // command or webhook URL
+	Text       string    `json:"text"`
+	DurationMS int64     `json:"duration_ms"`
+	Attempts   int       `json:"attempts"`
+	ExitCode   int       `json:"exit_code,omitempty"`   // command hooks
+	Status     int       `json:"http_status,omitempty"` // webhooks
+	Error      string    `json:"error,omitempty"`
+	Stdout     string    `json:"stdout,omitempty"` // webhooks: response body
+	Stderr     string    `json:"stderr,omitempty"`
+	Truncated  bool      `json:"truncated,omitempty"` // output hit output_max_bytes
+	Say        string    `json:"say,omitempty"`       // from an output = "json" response
+	Continue   bool      `json:"continue,omitempty"`
 }
 
 // SimpleResponse is a minimal OK/error envelope.
@@ -0,0 +1,179 @@
+package control
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"slices"
+	"strings"
+	"time"
+
+	"brabble/internal/config"
+	"brabble/internal/hook"
+	"brabble/internal/logging"
+
+	"github.com/spf13/cobra"
+)
+
+func newHookDLQCmd(cfgPath *string) *cobra.Command {
+	cmd := &cobra.Command{
+		Use:   "dlq",
+		Short: "List, replay, or purge hook jobs that failed all retries",
+	}
+	cmd.AddCommand(&cobra.Command{
+		Use:   "list",
+		Short: "Show dead-lettered hook jobs",
+		Args:  cobra.NoArgs,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			cfg, err := config.Load(*cfgPath)
+			if err != nil {
+				return err
+			}
+			entries, err := hook.ReadDeadLetters(cfg.Paths.DeadLetterPath)
+			if err != nil {
+				return err
+			}
+			out := cmd.OutOrStdout()
+			if len(entries) == 0 {
+				_, _ = fmt.Fprintln(out, "no dead letters")
+				return nil
+			}
+			for _, d := range entries {
+				where := fmt.Sprintf("hook #%d", d.Hook)
+				if d.ChainStep > 0 {
+					where += fmt.Sprintf(" step %d", d.ChainStep)
+				}
+				_, _ = fmt.Fprintf(out, "%s  %s  %s  %d attempt(s)  %q\n    %s\n",
+					d.ID, d.FailedAt.Local().Format(time.DateTime), where, d.Attempts, d.Text, d.Error)
+			}
+			return nil
+		},
+	})
+
+	var replayAll, replayForce bool
+	replay := &cobra.Command{
+		Use:   "replay [id...]",
+		Short: "Run dead-lettered jobs again; successes leave the file",
+		RunE: func(cmd *cobra.Command, args []string) error {
+			cfg, err := config.Load(*cfgPath)
+			if err != nil {
+				return err
+			}
+			logger, err := logging.Configure(cfg)
+			if err != nil {
+				return err
+			}
+			entries, err := selectDeadLetters(cfg, args, replayAll)
+			if err != nil {
+				return err
+			}
+			r := hook.NewRunner(cfg, logger)
+			done := map[string]bool{}
+			var failed int
+			for _, d := range entries {
+				job, err := d.Job(cfg, replayForce)
+				if err == nil {
+					err = replayJob(cmd.Context(), r, cfg.EffectiveHooks(), job)
+				}
+				if errors.Is(err, hook.ErrTargetChanged) {
+					err = fmt.Errorf("%w; pass --force to run it anyway", err)
+				}
+				if err != nil {
+					failed++
+					_, _ = fmt.Fprintf(cmd.ErrOrStderr(), "%s: %v\n", d.ID, err)
+					continue
+				}
+				done[d.ID] = true
+			}
+			if _, err := hook.RemoveDeadLetters(cfg.Paths.DeadLetterPath, func(d hook.DeadLetter) bool { return done[d.ID] }); err != nil {
+				return err
+			}
+			_, _ = fmt.Fprintf(cmd.OutOrStdout(), "replayed %d, failed %d\n", len(done), failed)
+			if failed > 0 {
+				return fmt.Errorf("%d job(s) still failing; kept in %s", failed, cfg.Paths.DeadLetterPath)
+			}
+			return nil
+		},
+	}
+	replay.Flags().BoolVar(&replayAll, "all", false, "replay every dead letter")
+	replay.Flags().BoolVar(&replayForce, "force", false, "replay even if the hook at the saved index now runs a different command or URL")
+	cmd.AddCommand(replay)
+
+	var purgeAll bool
+	purge := &cobra.Command{
+		Use:   "purge [id...]",
+		Short: "Delete dead-lettered jobs without running them",
+		RunE: func(cmd *cobra.Command, args []string) error {
+			cfg, err := config.Load(*cfgPath)
+			if err != nil {
+				return err
+			}
+			entries, err := selectDeadLetters(cfg, args, purgeAll)
+			if err != nil {
+				return err
+			}
+			ids := map[string]bool{}
+			for _, d := range entries {
+				ids[d.ID] = true
+			}
+			removed, err := hook.RemoveDeadLetters(cfg.Paths.DeadLetterPath, func(d hook.DeadLetter) bool { return ids[d.ID] })
+			if err != nil {
+				return err
+			}
+			_, _ = fmt.Fprintf(cmd.OutOrStdout(), "purged %d\n", len(removed))
+			return nil
+		},
+	}
+	purge.Flags().BoolVar(&purgeAll, "all", false, "purge every dead letter")
+	cmd.AddCommand(purge)
+	return cmd
+}
+
+// replayJob runs job and then the rest of its chain, as the daemon would:
+// each step gets the previous step's output, and a failed step with
+// on_failure = "continue" passes its own input on.
+func replayJob(ctx context.Context, r *hook.Runner, hooks []config.HookConfig, job hook.Job) error {
+	for {
+		res, err := r.Run(ctx, job)
+		if err != nil {
+			if job.OnFailure() != config.ChainContinue {
+				return err
+			}
+			res = hook.Result{}
+		}
+		if job.LastStep() {
+			return nil
+		}
+		next, err := job.AtChainStep(hooks, job.ChainStep+1)
+		if err != nil {
+			return err
+		}
+		next.Text = hook.ChainInput(job.Text, res)
+		job = next
+	}
+}
+
+// selectDeadLetters returns the entries named by ids, or all with all set.
+func selectDeadLetters(cfg *config.Config, ids []string, all bool) ([]hook.DeadLetter, error) {
+	if all == (len(ids) > 0) {
+		return nil, errors.New("pass dead-letter ids or --all")
+	}
+	entries, err := hook.ReadDeadLetters(cfg.Paths.DeadLetterPath)
+	if err != nil || all {
+		return entries, err
+	}
+	var out []hook.DeadLetter
+	var missing []string
+	for _, id := range ids {
+		i := slices.IndexFunc(entries, func(d hook.DeadLetter) bool { return d.ID == id })
+		if i < 0 {
+			missing = append(missing, id)
+			continue
+		}
+		out = append(out, entries[i])
+	}
+	if len(missing) > 0 {
+		return nil, fmt.Errorf("no dead letter with id %s", strings.Join(missing, ", "))
+	}
+	return out, nil
+}
@@ -1,9 +1,7 @@
 package control
 
 import (
-	"encoding/json"
 	"fmt"
-	"net"
 
 	"brabble/internal/config"
 
@@ -20,17 +18,8 @@ func NewHealthCmd(cfgPath *string) *cobra.Command {
 			if err != nil {
 				return err
 			}
-			conn, err := net.Dial("unix", cfg.Paths.SocketPath)
-			if err != nil {
-				return fmt.Errorf("cannot connect to daemon: %w", err)
-			}
-			defer func() { _ = conn.Close() }()
-			req := Request{Op: "health"}
-			if err := json.NewEncoder(conn).Encode(req); err != nil {
-				return err
-			}
 			var resp SimpleResponse
-			if err := json.NewDecoder(conn).Decode(&resp); err != nil {
+			if err := Call(cfg.Paths.SocketPath, "health", nil, &resp); err != nil {
 				return err
 			}
 			if !resp.OK {
@@ -0,0 +1,169 @@
+package control
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io"
+	"slices"
+	"strings"
+	"time"
+
+	"brabble/internal/config"
+	"brabble/internal/hook"
+	"brabble/internal/logging"
+
+	"github.com/spf13/cobra"
+)
+
+// NewHookCmd groups hook helpers.
+func NewHookCmd(cfgPath *string) *cobra.Command {
+	cmd := &cobra.Command{
+		Use:     "hook",
+		Aliases: []string{"hooks"},
+		Short:   "Inspect hooks and their dead letters",
+	}
+	cmd.AddCommand(newHookRenderCmd(cfgPath))
+	cmd.AddCommand(newHookDLQCmd(cfgPath))
+	return cmd
+}
+
+func newHookRenderCmd(cfgPath *string) *cobra.Command {
+	var (
+		index       int
+		raw         bool
+		showData    bool
+		showSecrets bool
+	)
+	cmd := &cobra.Command{
+		Use:   "render \"text\"",
+		Short: "Show what a hook would run for text, without running it",
+		Args:  cobra.ExactArgs(1),
+		RunE: func(cmd *cobra.Command, args []string) error {
+			cfg, err := config.Load(*cfgPath)
+			if err != nil {
+				return err
+			}
+			job, err := renderJob(cfg, args[0], index, raw, cmd.ErrOrStderr())
+			if err != nil {
+				return err
+			}
+			logger, err := logging.Configure(cfg)
+			if err != nil {
+				return err
+			}
+			rendered, err := hook.NewRunner(cfg, logger).Render(job)
+			if err != nil {
+				return err
+			}
+			return printRendered(cmd.OutOrStdout(), job, rendered, showData, showSecrets)
+		},
+	}
+	cmd.Flags().IntVar(&index, "hook", -1, "render this hook index instead of selecting by wake token")
+	cmd.Flags().BoolVar(&raw, "raw", false, "skip wake word handling, as for push-to-talk")
+	cmd.Flags().BoolVar(&showData, "data", false, "also print the template data")
+	cmd.Flags().BoolVar(&showSecrets, "show-secrets", false, "print env values instead of masking them")
+	return cmd
+}
+
+// renderJob builds the job the daemon would queue for text.
+func renderJob(cfg *config.Config, text string, index int, raw bool, warn io.Writer) (hook.Job, error) {
+	text = strings.TrimSpace(text)
+	job := hook.Job{Text: text, RawText: text, Timestamp: time.Now()}
+	if cfg.Wake.Enabled && !raw {
+		if job.Wake, job.Text = hook.SplitWake(cfg, text); job.Wake == "" {
+			_, _ = fmt.Fprintf(warn, "note: wake word %q not found; the daemon would skip this\n", cfg.Wake.Word)
+		}
+	}
+	hooks := cfg.EffectiveHooks()
+	switch {
+	case len(hooks) == 0:
+		return job, fmt.Errorf("no hook configured; add [[hooks]] entries")
+	case index >= len(hooks):
+		return job, fmt.Errorf("--hook %d out of range (%d hooks)", index, len(hooks))
+	case index >= 0:
+		job.Hook, job.HookIndex = &hooks[index], index
+	default:
+		job.Hook, job.HookIndex, job.Slots = hook.Route(cfg, text, job.Text)
+		if job.Hook == nil {
+			return job, fmt.Errorf("no hook matches and no wake entry to fall back to; pass --hook N")
+		}
+	}
+	return job, nil
+}
+
+// printHookOutput echoes what a hook run printed: stdout (or the webhook
+// response) to stdout, stderr to stderr.
+func printHookOutput(cmd *cobra.Command, res hook.Result) {
+	if res.Stdout != "" {
+		_, _ = fmt.Fprintln(cmd.OutOrStdout(), strings.TrimRight(res.Stdout, "\n"))
+	}
+	if res.Stderr != "" {
+		_, _ = fmt.Fprintln(cmd.ErrOrStderr(), strings.TrimRight(res.Stderr, "\n"))
+	}
+	if res.Truncated {
+		_, _ = fmt.Fprintln(cmd.ErrOrStderr(), "(output truncated at output_max_bytes)")
+	}
+}
+
+// printRendered prints a rendered hook. Env values usually hold tokens, so
+// they are masked unless showSecrets is set.
+func printRendered(out io.Writer, job hook.Job, r hook.Rendered, showData, showSecrets bool) error {
+	p := func(format string, args ...any) { _, _ = fmt.Fprintf(out, format, args...) }
+	kind := job.Hook.Type
+	if kind == "" {
+		kind = config.HookTypeCommand
+	}
+	p("hook #%d (%s)\n", job.HookIndex, kind)
+	if len(r.Data.Slots) > 0 {
+		names := make([]string, 0, len(r.Data.Slots))
+		for name := range r.Data.Slots {
+			names = append(names, name)
+		}
+		slices.Sort(names)
+		p("slots:\n")
+		for _, name := range names {
+			p("  %s=%q (%s)\n", name, r.Data.Slots[name], hook.SlotEnv(name))
+		}
+	}
+	if kind == config.HookTypeWebhook {
+		p("request: %s %s\n", r.Method, r.URL)
+		var body bytes.Buffer
+		if err := json.Indent(&body, r.Body, "  ", "  "); err != nil {
+			return err
+		}
+		p("body:\n  %s\n", body.String())
+	} else {
+		p("command: %s\n", r.Command)
+		p("args:\n")
+		for i, a := range r.Args {
+			p("  [%d] %q\n", i, a)
+		}
+		if r.Stdin != nil {
+			p("stdin (%s):\n  %s\n", job.Hook.Payload, strings.TrimRight(string(r.Stdin), "\n"))
+		}
+	}
+	if len(r.Env) > 0 {
+		keys := make([]string, 0, len(r.Env))
+		for k := range r.Env {
+			keys = append(keys, k)
+		}
+		slices.Sort(keys)
+		p("env:\n")
+		for _, k := range keys {
+			v := r.Env[k]
+			if !showSecrets && v != "" {
+				v = "<masked>"
+			}
+			p("  %s=%s\n", k, v)
+		}
+	}
+	if showData {
+		data, err := json.MarshalIndent(r.Data, "  ", "  ")
+		if err != nil {
+			return err
+		}
+		p("data:\n  %s\n", data)
+	}
+	return nil
+}
@@ -0,0 +1,166 @@
+package control
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"brabble/internal/config"
+	"brabble/internal/hook"
+)
+
+func TestHookRenderShowsTemplatedInvocation(t *testing.T) {
+	dir := t.TempDir()
+	cfg, _ := config.Default()
+	cfg.Paths.StateDir = dir
+	cfg.Paths.LogPath = filepath.Join(dir, "brabble.log")
+	cfg.Hooks = []config.HookConfig{
+		{Wake: []string{"clawd"}, Command: "/usr/bin/say", Args: []string{"--wake={{.Wake}}", "{{.Payload}}"}, Prefix: "{{.Hook}}: ", Env: map[string]string{"RAW": "{{.RawText}}"}},
+		{Wake: []string{"house"}, Type: config.HookTypeWebhook, URL: "http://127.0.0.1:8123/voice", Body: `{"say": {{json .Text}}}`},
+	}
+	configPath := filepath.Join(dir, "config.toml")
+	if err := config.Save(cfg, configPath); err != nil {
+		t.Fatalf("save config: %v", err)
+	}
+
+	render := func(args ...string) string {
+		t.Helper()
+		var out bytes.Buffer
+		cmd := NewHookCmd(&configPath)
+		cmd.SetOut(&out)
+		cmd.SetErr(&out)
+		cmd.SetArgs(append([]string{"render"}, args...))
+		if err := cmd.Execute(); err != nil {
+			t.Fatalf("render %v: %v", args, err)
+		}
+		return out.String()
+	}
+
+	got := render("Clawd, lights off")
+	for _, want := range []string{"hook #0 (command)", `[0] "--wake=clawd"`, `[1] "0: lights off"`, "RAW=<masked>"} {
+		if !strings.Contains(got, want) {
+			t.Fatalf("command render missing %q:\n%s", want, got)
+		}
+	}
+	if strings.Contains(got, "[2]") {
+		t.Fatalf("payload appended despite templated args:\n%s", got)
+	}
+
+	if got := render("--show-secrets", "Clawd, lights off"); !strings.Contains(got, "RAW=Clawd, lights off") {
+		t.Fatalf("--show-secrets should print env values:\n%s", got)
+	}
+
+	got = render("--hook", "1", "--raw", "open the garage")
+	for _, want := range []string{"hook #1 (webhook)", "POST http://127.0.0.1:8123/voice", `"say": "open the garage"`} {
+		if !strings.Contains(got, want) {
+			t.Fatalf("webhook render missing %q:\n%s", want, got)
+		}
+	}
+}
+
+func TestHookDLQReplayAndPurge(t *testing.T) {
+	dir := t.TempDir()
+	cfg, _ := config.Default()
+	cfg.Paths.StateDir = dir
+	cfg.Paths.LogPath = filepath.Join(dir, "brabble.log")
+	cfg.Paths.DeadLetterPath = filepath.Join(dir, "dlq.jsonl")
+	cfg.Hooks = []config.HookConfig{{Command: "/usr/bin/true"}, {Command: "/bin/sh", Args: []string{"-c", "exit 3"}}}
+	configPath := filepath.Join(dir, "config.toml")
+	if err := config.Save(cfg, configPath); err != nil {
+		t.Fatalf("save config: %v", err)
+	}
+	for i, text := range []string{"works now", "still broken", "unwanted"} {
+		job := hook.Job{Text: text, Hook: &cfg.Hooks[min(i, 1)], HookIndex: min(i, 1), Timestamp: time.Now()}
+		d := hook.NewDeadLetter(job, 1, errors.New("boom"))
+		d.ID = fmt.Sprintf("id%d", i)
+		if err := hook.AppendDeadLetter(cfg.Paths.DeadLetterPath, d); err != nil {
+			t.Fatalf("append: %v", err)
+		}
+	}
+	run := func(args ...string) (string, error) {
+		var out bytes.Buffer
+		cmd := NewHookCmd(&configPath)
+		cmd.SetOut(&out)
+		cmd.SetErr(&out)
+		cmd.SetArgs(append([]string{"dlq"}, args...))
+		err := cmd.Execute()
+		return out.String(), err
+	}
+
+	if out, err := run("list"); err != nil || !strings.Contains(out, "id1") || !strings.Contains(out, `"still broken"`) {
+		t.Fatalf("list: %v\n%s", err, out)
+	}
+	if _, err := run("replay"); err == nil {
+		t.Fatal("replay without ids or --all accepted")
+	}
+	if _, err := run("purge", "nope"); err == nil {
+		t.Fatal("purge of unknown id accepted")
+	}
+	if out, err := run("purge", "id2"); err != nil || !strings.Contains(out, "purged 1") {
+		t.Fatalf("purge: %v\n%s", err, out)
+	}
+	out, err := run("replay", "--all")
+	if err == nil || !strings.Contains(out, "replayed 1, failed 1") {
+		t.Fatalf("replay: %v\n%s", err, out)
+	}
+	left, _ := hook.ReadDeadLetters(cfg.Paths.DeadLetterPath)
+	if len(left) != 1 || left[0].ID != "id1" {
+		t.Fatalf("left %+v", left)
+	}
+
+	// Hook #1 now runs something else: replay refuses unless forced.
+	cfg.Hooks[1] = config.HookConfig{Command: "/usr/bin/true"}
+	if err := config.Save(cfg, configPath); err != nil {
+		t.Fatalf("save config: %v", err)
+	}
+	if out, err := run("replay", "id1"); err == nil || !strings.Contains(out, "--force") {
+		t.Fatalf("replay onto edited hook: %v\n%s", err, out)
+	}
+	if out, err := run("replay", "--force", "id1"); err != nil || !strings.Contains(out, "replayed 1, failed 0") {
+		t.Fatalf("forced replay: %v\n%s", err, out)
+	}
+}
+
+func TestHookDLQReplayResumesChain(t *testing.T) {
+	dir := t.TempDir()
+	ran := filepath.Join(dir, "ran")
+	cfg, _ := config.Default()
+	cfg.Paths.StateDir = dir
+	cfg.Paths.LogPath = filepath.Join(dir, "brabble.log")
+	cfg.Paths.DeadLetterPath = filepath.Join(dir, "dlq.jsonl")
+	step := func(name string) config.HookConfig {
+		return config.HookConfig{Command: "/bin/sh", Args: []string{"-c", `echo "` + name + ` $1" >> "` + ran + `"; echo "$1 ` + name + `"`, "sh"}}
+	}
+	head := step("head")
+	head.Chain = []config.ChainStep{{Hook: 1}, {Hook: 2}}
+	cfg.Hooks = []config.HookConfig{head, step("middle"), step("tail")}
+	configPath := filepath.Join(dir, "config.toml")
+	if err := config.Save(cfg, configPath); err != nil {
+		t.Fatalf("save config: %v", err)
+	}
+	// The middle step failed with this input.
+	job, err := hook.Job{Text: "go head", Hook: &cfg.Hooks[0], Timestamp: time.Now()}.AtChainStep(cfg.Hooks, 1)
+	if err != nil {
+		t.Fatalf("at step: %v", err)
+	}
+	if err := hook.AppendDeadLetter(cfg.Paths.DeadLetterPath, hook.NewDeadLetter(job, 1, errors.New("boom"))); err != nil {
+		t.Fatalf("append: %v", err)
+	}
+
+	var out bytes.Buffer
+	cmd := NewHookCmd(&configPath)
+	cmd.SetOut(&out)
+	cmd.SetErr(&out)
+	cmd.SetArgs([]string{"dlq", "replay", "--all"})
+	if err := cmd.Execute(); err != nil {
+		t.Fatalf("replay: %v\n%s", err, out.String())
+	}
+	if got, _ := os.ReadFile(ran); string(got) != "middle go head\ntail go head middle\n" {
+		t.Fatalf("replay ran %q, want the middle and tail steps", got)
+	}
+}
@@ -0,0 +1,24 @@
+package control
+
+import "github.com/spf13/cobra"
+
+// NewInjectCmd feeds text into the running daemon as if it had been heard.
+func NewInjectCmd(cfgPath *string) *cobra.Command {
+	cmd := &cobra.Command{
+		Use:   "inject \"text\"",
+		Short: "Route text through the running daemon as a transcript",
+		Long: `Route text through the running daemon as if it had just been transcribed:
+wake word gating, hook selection, cooldown, queue, metrics, and the transcript
+log all apply. Prints how the text was routed. Use test-hook to run a hook
+directly instead.`,
+		Args: cobra.ExactArgs(1),
+		RunE: func(cmd *cobra.Command, args []string) error {
+			partial, _ := cmd.Flags().GetBool("partial")
+			raw, _ := cmd.Flags().GetBool("raw")
+			return simpleOp(*cfgPath, "inject", InjectArgs{Text: args[0], Partial: partial, Raw: raw})
+		},
+	}
+	cmd.Flags().Bool("partial", false, "inject as an interim (partial) transcript")
+	cmd.Flags().Bool("raw", false, "skip the wake word requirement")
+	return cmd
+}
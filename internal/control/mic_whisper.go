@@ -1,6 +1,7 @@
 package control
 
 import (
+	"brabble/internal/asr"
 	"brabble/internal/config"
 	"encoding/json"
 	"fmt"
@@ -94,17 +95,55 @@ func newMicSetCmd(cfgPath *string) *cobra.Command {
 			if len(args) == 0 && idx < 0 {
 				return fmt.Errorf("provide a device name or --index")
 			}
+			if len(args) > 0 && cmd.Flags().Changed("index") {
+				return fmt.Errorf("provide a device name or --index, not both")
+			}
 			if len(args) > 0 {
+				// A name is matched by substring at startup; drop any saved
+				// identity so it does not take precedence.
 				cfg.Audio.DeviceName = args[0]
+				cfg.Audio.DeviceID = config.DeviceID{}
+				cfg.Audio.DeviceIndex = -1
+			} else {
+				id, err := deviceIdentityAt(idx)
+				if err != nil {
+					return err
+				}
+				// Persist the identity rather than the index, which shifts
+				// as devices are added or removed.
+				cfg.Audio.DeviceID = id
+				cfg.Audio.DeviceName = ""
+				cfg.Audio.DeviceIndex = -1
 			}
-			cfg.Audio.DeviceIndex = idx
 			if err := config.Save(cfg, cfg.Paths.ConfigPath); err != nil {
 				return err
 			}
-			fmt.Printf("mic set: name=%q index=%d in %s\n", cfg.Audio.DeviceName, cfg.Audio.DeviceIndex, cfg.Paths.ConfigPath)
+			if cfg.Audio.DeviceID.Name != "" {
+				fmt.Printf("mic set: %s in %s\n", cfg.Audio.DeviceID, cfg.Paths.ConfigPath)
+			} else {
+				fmt.Printf("mic set: name=%q in %s\n", cfg.Audio.DeviceName, cfg.Paths.ConfigPath)
+			}
 			return nil
 		},
 	}
 	cmd.Flags().Int("index", -1, "set by device index (from mic list)")
 	return cmd
 }
+
+// deviceIdentityAt resolves a `mic list` index to a stable device identity.
+func deviceIdentityAt(idx int) (config.DeviceID, error) {
+	if err := portaudio.Initialize(); err != nil {
+		return config.DeviceID{}, fmt.Errorf("portaudio init: %w", err)
+	}
+	defer func() {
+		_ = portaudio.Terminate()
+	}()
+	devs, err := portaudio.Devices()
+	if err != nil {
+		return config.DeviceID{}, err
+	}
+	if idx >= len(devs) || devs[idx].MaxInputChannels < 1 {
+		return config.DeviceID{}, fmt.Errorf("no input device at index %d (see mic list)", idx)
+	}
+	return asr.DeviceIdentity(devs[idx]), nil
+}
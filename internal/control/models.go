@@ -35,6 +35,7 @@ func NewModelsCmd(cfgPath *string) *cobra.Command {
 	cmd.AddCommand(newModelsListCmd(cfgPath))
 	cmd.AddCommand(newModelsDownloadCmd(cfgPath))
 	cmd.AddCommand(newModelsSetCmd(cfgPath))
+	cmd.AddCommand(newModelsUseCmd(cfgPath))
 	return cmd
 }
 
@@ -107,11 +108,7 @@ func newModelsSetCmd(cfgPath *string) *cobra.Command {
 			if err != nil {
 				return err
 			}
-			val := args[0]
-			// if short name, resolve in model dir
-			if !strings.Contains(val, "/") {
-				val = filepath.Join(modelDir(cfg), val)
-			}
+			val := resolveModelPath(cfg, args[0])
 			cfg.ASR.ModelPath = val
 			if err := config.Save(cfg, cfg.Paths.ConfigPath); err != nil {
 				return err
@@ -121,3 +118,48 @@ func newModelsSetCmd(cfgPath *string) *cobra.Command {
 		},
 	}
 }
+
+func newModelsUseCmd(cfgPath *string) *cobra.Command {
+	cmd := &cobra.Command{
+		Use:   "use <model-name-or-path>",
+		Short: "Switch models; --live swaps it into the running daemon",
+		Args:  cobra.ExactArgs(1),
+		RunE: func(cmd *cobra.Command, args []string) error {
+			cfg, err := config.Load(*cfgPath)
+			if err != nil {
+				return err
+			}
+			path := resolveModelPath(cfg, args[0])
+			if _, err := os.Stat(path); err != nil {
+				return fmt.Errorf("model not found: %w (models download?)", err)
+			}
+			live, _ := cmd.Flags().GetBool("live")
+			if live {
+				fmt.Printf("loading %s in daemon...\n", path)
+				if err := Call(cfg.Paths.SocketPath, "use_model", ModelArgs{Model: path}, nil); err != nil {
+					return fmt.Errorf("daemon model swap failed: %w", err)
+				}
+			}
+			cfg.ASR.ModelPath = path
+			if err := config.Save(cfg, cfg.Paths.ConfigPath); err != nil {
+				return err
+			}
+			if live {
+				fmt.Printf("now transcribing with %s (saved to config)\n", path)
+			} else {
+				fmt.Printf("model set to %s; restart or use --live to apply\n", path)
+			}
+			return nil
+		},
+	}
+	cmd.Flags().Bool("live", false, "hot-swap the model in the running daemon")
+	return cmd
+}
+
+// resolveModelPath treats bare names as files in the models directory.
+func resolveModelPath(cfg *config.Config, val string) string {
+	if !strings.Contains(val, "/") {
+		return filepath.Join(modelDir(cfg), val)
+	}
+	return val
+}
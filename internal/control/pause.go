@@ -0,0 +1,82 @@
+package control
+
+import (
+	"fmt"
+	"time"
+
+	"brabble/internal/config"
+
+	"github.com/spf13/cobra"
+)
+
+// NewPauseCmd stops listening (mic closed) without unloading the model.
+func NewPauseCmd(cfgPath *string) *cobra.Command {
+	cmd := &cobra.Command{
+		Use:   "pause",
+		Short: "Stop listening and close the mic (model stays loaded)",
+		RunE: func(cmd *cobra.Command, args []string) error {
+			d, _ := cmd.Flags().GetDuration("for")
+			return simpleOp(*cfgPath, "pause", DurationArgs{DurationSec: d.Seconds()})
+		},
+	}
+	cmd.Flags().Duration("for", 0, "resume automatically after this long (e.g. 30m)")
+	return cmd
+}
+
+// NewResumeCmd reopens the mic after pause.
+func NewResumeCmd(cfgPath *string) *cobra.Command {
+	return &cobra.Command{
+		Use:   "resume",
+		Short: "Resume listening after pause",
+		RunE: func(cmd *cobra.Command, args []string) error {
+			return simpleOp(*cfgPath, "resume", nil)
+		},
+	}
+}
+
+// NewMuteHooksCmd keeps transcribing but suppresses hook dispatch.
+func NewMuteHooksCmd(cfgPath *string) *cobra.Command {
+	cmd := &cobra.Command{
+		Use:   "mute-hooks",
+		Short: "Keep transcribing but stop dispatching hooks",
+		RunE: func(cmd *cobra.Command, args []string) error {
+			d, _ := cmd.Flags().GetDuration("for")
+			return simpleOp(*cfgPath, "mute_hooks", DurationArgs{DurationSec: d.Seconds()})
+		},
+	}
+	cmd.Flags().Duration("for", 0, "unmute automatically after this long (e.g. 1h)")
+	return cmd
+}
+
+// NewUnmuteHooksCmd re-enables hook dispatch.
+func NewUnmuteHooksCmd(cfgPath *string) *cobra.Command {
+	return &cobra.Command{
+		Use:   "unmute-hooks",
+		Short: "Resume dispatching hooks",
+		RunE: func(cmd *cobra.Command, args []string) error {
+			return simpleOp(*cfgPath, "unmute_hooks", nil)
+		},
+	}
+}
+
+// simpleOp sends op and prints the daemon's message.
+func simpleOp(cfgPath, op string, args any) error {
+	cfg, err := config.Load(cfgPath)
+	if err != nil {
+		return err
+	}
+	var resp SimpleResponse
+	if err := Call(cfg.Paths.SocketPath, op, args, &resp); err != nil {
+		return fmt.Errorf("%s failed: %w", op, err)
+	}
+	fmt.Println(resp.Message)
+	return nil
+}
+
+// formatUntil renders an optional expiry for status output.
+func formatUntil(t *time.Time) string {
+	if t == nil {
+		return ""
+	}
+	return fmt.Sprintf(" until %s (%s left)", t.Format("15:04:05"), time.Until(*t).Round(time.Second))
+}
@@ -0,0 +1,28 @@
+package control
+
+import "github.com/spf13/cobra"
+
+// NewPTTCmd drives push-to-talk (wake.mode = "push_to_talk"), e.g. from a
+// global hotkey tool.
+func NewPTTCmd(cfgPath *string) *cobra.Command {
+	cmd := &cobra.Command{
+		Use:   "ptt",
+		Short: "Push-to-talk: start, stop, or toggle capture",
+	}
+	for _, sub := range []struct{ use, op, short string }{
+		{"start", "ptt_start", "Open the mic and start an utterance"},
+		{"stop", "ptt_stop", "End the utterance, transcribe it, and close the mic"},
+		{"toggle", "ptt_toggle", "Start if stopped, stop if started"},
+	} {
+		op := sub.op
+		cmd.AddCommand(&cobra.Command{
+			Use:   sub.use,
+			Short: sub.short,
+			Args:  cobra.NoArgs,
+			RunE: func(cmd *cobra.Command, args []string) error {
+				return simpleOp(*cfgPath, op, nil)
+			},
+		})
+	}
+	return cmd
+}
@@ -3,7 +3,6 @@ package control
 import (
 	"encoding/json"
 	"fmt"
-	"net"
 	"os"
 	"strings"
 	"time"
@@ -26,17 +25,8 @@ func NewStatusCmd(cfgPath *string) *cobra.Command {
 			if err != nil {
 				return err
 			}
-			conn, err := net.Dial("unix", cfg.Paths.SocketPath)
-			if err != nil {
-				return fmt.Errorf("cannot connect to daemon: %w", err)
-			}
-			defer func() { _ = conn.Close() }()
-			req := Request{Op: "status"}
-			if err := json.NewEncoder(conn).Encode(req); err != nil {
-				return err
-			}
 			var status Status
-			if err := json.NewDecoder(conn).Decode(&status); err != nil {
+			if err := Call(cfg.Paths.SocketPath, "status", nil, &status); err != nil {
 				return err
 			}
 			jsonOut, _ := cmd.Flags().GetBool("json")
@@ -44,9 +34,34 @@ func NewStatusCmd(cfgPath *string) *cobra.Command {
 				return json.NewEncoder(cmd.OutOrStdout()).Encode(status)
 			}
 			fmt.Printf("running: %v\nuptime: %.1fs\n", status.Running, status.UptimeSec)
+			if status.Device != "" {
+				fmt.Printf("mic: %s\n", status.Device)
+			}
+			if status.Model != "" {
+				unloaded := ""
+				if status.ModelUnloaded {
+					unloaded = " (unloaded while idle)"
+				}
+				fmt.Printf("model: %s%s\n", status.Model, unloaded)
+			}
+			if status.Paused {
+				fmt.Printf("paused: mic closed%s\n", formatUntil(status.PausedUntil))
+			}
+			if status.Talking {
+				fmt.Println("ptt: talking")
+			}
+			if status.HooksMuted {
+				fmt.Printf("hooks: muted%s\n", formatUntil(status.MutedUntil))
+			}
+			if status.ConversationUntil != nil {
+				fmt.Printf("conversation: open%s\n", formatUntil(status.ConversationUntil))
+			}
 			for _, t := range status.Transcripts {
 				fmt.Printf("%s  %s\n", t.Timestamp.Format("15:04:05"), t.Text)
 			}
+			for _, r := range status.HookResults {
+				fmt.Println(formatHookResult(r))
+			}
 			return nil
 		},
 	}
@@ -102,13 +117,15 @@ func NewTestHookCmd(cfgPath *string) *cobra.Command {
 				return err
 			}
 			r := hook.NewRunner(cfg, logger)
-			hk, _ := hook.SelectHookConfig(cfg, args[0])
+			wake, text := hook.SplitWake(cfg, args[0])
+			hk, idx, slots := hook.Route(cfg, args[0], text)
 			if hk == nil {
-				return fmt.Errorf("hook command not configured")
+				return fmt.Errorf("no matching hook configured")
 			}
-			r.SelectHook(hk)
-			job := hook.Job{Text: args[0], Timestamp: time.Now()}
-			return r.Run(cmd.Context(), job)
+			job := hook.Job{Text: text, RawText: args[0], Wake: wake, Timestamp: time.Now(), Hook: hk, HookIndex: idx, Slots: slots}
+			res, err := r.Run(cmd.Context(), job)
+			printHookOutput(cmd, res)
+			return err
 		},
 	}
 }
@@ -126,12 +143,10 @@ func NewDoctorCmd(cfgPath *string) *cobra.Command {
 			results := doctor.Run(cfg)
 			exitCode := 0
 			for _, r := range results {
-				status := "ok"
 				if !r.Pass {
-					status = "fail"
 					exitCode = 1
 				}
-				fmt.Printf("%-12s %-4s %s\n", r.Name, status, r.Detail)
+				fmt.Printf("%-12s %-4s %s\n", r.Name, r.Status(), r.Detail)
 			}
 			if exitCode != 0 {
 				return fmt.Errorf("doctor found issues")
@@ -43,11 +43,7 @@ func NewSetupCmd(cfgPath *string) *cobra.Command {
 			fmt.Println("config updated with model_path =", modelPath)
 			results := doctor.Run(cfg)
 			for _, r := range results {
-				status := "ok"
-				if !r.Pass {
-					status = "fail"
-				}
-				fmt.Printf("%-12s %-4s %s\n", r.Name, status, r.Detail)
+				fmt.Printf("%-12s %-4s %s\n", r.Name, r.Status(), r.Detail)
 			}
 			return nil
 		},
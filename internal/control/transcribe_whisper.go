@@ -50,26 +50,24 @@ func NewTranscribeCmd(cfgPath *string) *cobra.Command {
 			}
 
 			// Apply wake/min_chars gating like daemon.
-			if cfg.Wake.Enabled && !noWake && !strings.Contains(strings.ToLower(txt), strings.ToLower(cfg.Wake.Word)) {
-				return fmt.Errorf("wake word %q not found; use --no-wake to override", cfg.Wake.Word)
-			}
 			if cfg.Wake.Enabled && !noWake {
-				txt = removeWakeWordLocal(txt, cfg.Wake.Word)
+				if _, ok := hook.MatchWake(txt, cfg.Wake.Word, cfg.Wake.Aliases); !ok {
+					return fmt.Errorf("wake word %q not found; use --no-wake to override", cfg.Wake.Word)
+				}
+				txt = hook.StripWake(txt, cfg.Wake.Word, cfg.Wake.Aliases)
 			}
-			hk, _ := hook.SelectHookConfig(cfg, rawTxt)
+			hk, idx, slots := hook.Route(cfg, rawTxt, txt)
 			if hk == nil {
-				return fmt.Errorf("no hook configured; add [[hooks]] entries")
+				return fmt.Errorf("no matching hook configured; add [[hooks]] entries")
 			}
 			if hk.MinChars > 0 && len(txt) < hk.MinChars {
 				return fmt.Errorf("skipped: len(text)=%d < min_chars=%d", len(txt), hk.MinChars)
 			}
 
 			r := hook.NewRunner(cfg, logger)
-			r.SelectHook(hk)
-			if !r.ShouldRun() {
-				return fmt.Errorf("hook on cooldown")
-			}
-			return r.Run(cmd.Context(), hook.Job{Text: txt, Timestamp: time.Now()})
+			res, err := r.Run(cmd.Context(), hook.Job{Text: txt, RawText: rawTxt, Timestamp: time.Now(), Hook: hk, HookIndex: idx, Slots: slots})
+			printHookOutput(cmd, res)
+			return err
 		},
 	}
 	cmd.Flags().Bool("hook", false, "also send through configured hook")
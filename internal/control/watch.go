@@ -0,0 +1,104 @@
+package control
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"strings"
+
+	"brabble/internal/config"
+
+	"github.com/spf13/cobra"
+)
+
+// NewWatchCmd follows daemon events live.
+func NewWatchCmd(cfgPath *string) *cobra.Command {
+	cmd := &cobra.Command{
+		Use:   "watch",
+		Short: "Stream transcripts, wake matches, hook runs, and device changes",
+		Args:  cobra.NoArgs,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			cfg, err := config.Load(*cfgPath)
+			if err != nil {
+				return err
+			}
+			jsonOut, _ := cmd.Flags().GetBool("json")
+			filter, _ := cmd.Flags().GetStringSlice("filter")
+			c, err := Dial(cfg.Paths.SocketPath)
+			if err != nil {
+				return err
+			}
+			defer func() { _ = c.Close() }()
+			if err := c.Subscribe(filter); err != nil {
+				return fmt.Errorf("subscribe: %w", err)
+			}
+			out := cmd.OutOrStdout()
+			enc := json.NewEncoder(out)
+			for {
+				ev, err := c.NextEvent()
+				if errors.Is(err, io.EOF) {
+					return nil // daemon stopped
+				}
+				if err != nil {
+					return err
+				}
+				if jsonOut {
+					if err := enc.Encode(ev); err != nil {
+						return err
+					}
+					continue
+				}
+				_, _ = fmt.Fprintln(out, formatEvent(ev))
+			}
+		},
+	}
+	cmd.Flags().Bool("json", false, "print raw JSON events, one per line")
+	cmd.Flags().StringSlice("filter", nil, "only these event types ("+strings.Join(EventTypes, ", ")+"); repeatable")
+	return cmd
+}
+
+// formatHookResult is one status line for a finished hook job.
+func formatHookResult(r HookResult) string {
+	line := fmt.Sprintf("%s  hook #%d %q %dms", r.Time.Format("15:04:05"), r.Hook, r.Text, r.DurationMS)
+	if r.Error != "" {
+		line += " failed: " + r.Error
+	} else {
+		line += " ok"
+	}
+	if out := strings.TrimSpace(r.Stdout); out != "" {
+		line += fmt.Sprintf(" -> %q", out)
+	}
+	return line
+}
+
+func formatEvent(ev Event) string {
+	line := fmt.Sprintf("%s %-13s", ev.Time.Format("15:04:05"), ev.Type)
+	switch ev.Type {
+	case EventHookDispatch:
+		line += fmt.Sprintf(" %s <- %q", ev.Hook, ev.Text)
+	case EventHookResult:
+		line += fmt.Sprintf(" %q %dms", ev.Text, ev.DurationMS)
+		if ev.Error != "" {
+			line += " failed: " + ev.Error
+		} else {
+			line += " ok"
+		}
+		if ev.Say != "" {
+			line += fmt.Sprintf(" say %q", ev.Say)
+		}
+		if ev.Continue {
+			line += " (continue)"
+		}
+	case EventDevice:
+		line += " " + ev.Device
+	case EventError:
+		line += " " + ev.Error
+	default:
+		line += fmt.Sprintf(" %q", ev.Text)
+	}
+	if ev.Missed > 0 {
+		line += fmt.Sprintf(" (%d events missed)", ev.Missed)
+	}
+	return line
+}
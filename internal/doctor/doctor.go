@@ -1,21 +1,36 @@
 package doctor
 
 import (
+	"cmp"
 	"fmt"
 	"os"
 	"os/exec"
 	"strings"
 
 	"brabble/internal/config"
+	"brabble/internal/hook"
 )
 
 // Result represents a diagnostic check.
 type Result struct {
 	Name   string
 	Pass   bool
+	Warn   bool // passed, but worth a look; does not fail doctor
 	Detail string
 }
 
+// Status renders r as ok, warn, or fail.
+func (r Result) Status() string {
+	switch {
+	case !r.Pass:
+		return "fail"
+	case r.Warn:
+		return "warn"
+	default:
+		return "ok"
+	}
+}
+
 // Run executes doctor checks.
 func Run(cfg *config.Config) []Result {
 	results := []Result{
@@ -27,15 +42,29 @@ func Run(cfg *config.Config) []Result {
 		results = append(results, checkHookExecutable(""))
 	} else {
 		for i := range hooks {
-			result := checkHookExecutable(hooks[i].Command)
-			if len(hooks) > 1 {
-				result.Name = fmt.Sprintf("hooks[%d].command", i)
+			var result Result
+			if hooks[i].Type == config.HookTypeWebhook {
+				result = checkWebhook(&hooks[i])
+				if len(hooks) > 1 {
+					result.Name = fmt.Sprintf("hooks[%d].url", i)
+				}
+			} else {
+				result = checkHookExecutable(hooks[i].Command)
+				if len(hooks) > 1 {
+					result.Name = fmt.Sprintf("hooks[%d].command", i)
+				}
 			}
 			results = append(results, result)
 		}
 	}
+	if cfg.Responder.Enabled {
+		results = append(results, checkResponder(cfg)...)
+	}
 	results = append(results, checkPortAudioPkgConfig())
 	results = append(results, checkPortAudio(false))
+	if cfg.Audio.DeviceID.Name != "" {
+		results = append(results, checkInputDevice(cfg.Audio.DeviceID))
+	}
 	return results
 }
 
@@ -77,6 +106,46 @@ func checkHookExecutable(cmd string) Result {
 	return Result{Name: label, Pass: true, Detail: resolved}
 }
 
+// checkWebhook validates a webhook hook's settings; it does not contact the
+// server.
+func checkWebhook(hk *config.HookConfig) Result {
+	label := "hook.url"
+	if err := hook.Validate(hk); err != nil {
+		return Result{Name: label, Pass: false, Detail: err.Error()}
+	}
+	return Result{Name: label, Pass: true, Detail: strings.ToUpper(cmp.Or(hk.Method, "POST")) + " " + hk.URL}
+}
+
+// checkResponder checks the player, earcon files, and TTS command the
+// responder will use.
+func checkResponder(cfg *config.Config) []Result {
+	rc := cfg.Responder
+	var results []Result
+	sounds := []struct{ label, path string }{
+		{"responder.wake_sound", rc.WakeSound},
+		{"responder.success_sound", rc.SuccessSound},
+		{"responder.failure_sound", rc.FailureSound},
+	}
+	player := false
+	for _, s := range sounds {
+		if s.path != "" {
+			results = append(results, checkFile(s.label, s.path))
+			player = true
+		}
+	}
+	if player {
+		result := checkHookExecutable(rc.Player)
+		result.Name = "responder.player"
+		results = append(results, result)
+	}
+	if rc.Speak {
+		result := checkHookExecutable(rc.TTSCommand)
+		result.Name = "responder.tts_command"
+		results = append(results, result)
+	}
+	return results
+}
+
 func checkPortAudioPkgConfig() Result {
 	pkg, err := exec.LookPath("pkg-config")
 	if err != nil {
@@ -6,6 +6,8 @@ import (
 	"testing"
 
 	"brabble/internal/config"
+
+	"github.com/gordonklaus/portaudio"
 )
 
 func TestCheckHookExecutablePath(t *testing.T) {
@@ -65,3 +67,40 @@ func TestRunChecksEveryPerWakeHook(t *testing.T) {
 		t.Fatalf("multi-hook doctor results missing: %+v", results)
 	}
 }
+
+func TestCheckResponder(t *testing.T) {
+	cfg, _ := config.Default()
+	cfg.Responder.Enabled = true
+	cfg.Responder.Player = "/bin/sh"
+	cfg.Responder.WakeSound = filepath.Join(t.TempDir(), "missing.wav")
+	cfg.Responder.TTSCommand = "/bin/sh"
+	got := map[string]bool{}
+	for _, r := range checkResponder(cfg) {
+		got[r.Name] = r.Pass
+	}
+	if len(got) != 3 || got["responder.wake_sound"] || !got["responder.player"] || !got["responder.tts_command"] {
+		t.Fatalf("results %v", got)
+	}
+}
+
+func TestMatchInputDeviceWarnsWhenMissing(t *testing.T) {
+	api := &portaudio.HostApiInfo{Name: "Core Audio"}
+	devs := []*portaudio.DeviceInfo{{Name: "USB Mic", MaxInputChannels: 1, HostApi: api}}
+
+	res := matchInputDevice(devs, config.DeviceID{Name: "USB Mic", HostAPI: "Core Audio", Channels: 1})
+	if !res.Pass || res.Warn {
+		t.Fatalf("exact match should be ok: %+v", res)
+	}
+	res = matchInputDevice(devs, config.DeviceID{Name: "usb mic", HostAPI: "Core Audio", Channels: 1})
+	if !res.Pass || res.Warn {
+		t.Fatalf("case-only name difference should be ok: %+v", res)
+	}
+	res = matchInputDevice(devs, config.DeviceID{Name: "USB Mic", HostAPI: "Core Audio", Channels: 2})
+	if res.Status() != "warn" {
+		t.Fatalf("partial match should warn: %+v", res)
+	}
+	res = matchInputDevice(devs, config.DeviceID{Name: "AirPods", Channels: 1})
+	if res.Status() != "warn" {
+		t.Fatalf("missing mic should warn: %+v", res)
+	}
+}
@@ -2,6 +2,10 @@ package doctor
 
 import (
 	"fmt"
+	"strings"
+
+	"brabble/internal/asr"
+	"brabble/internal/config"
 
 	"github.com/gordonklaus/portaudio"
 )
@@ -15,3 +19,32 @@ func checkPortAudio(_ bool) Result {
 	}()
 	return Result{Name: "portaudio", Pass: true, Detail: "ok"}
 }
+
+// checkInputDevice warns when the mic saved by `mic set` is not present, in
+// which case the daemon silently falls back to another input.
+func checkInputDevice(id config.DeviceID) Result {
+	if err := portaudio.Initialize(); err != nil {
+		return Result{Name: "mic", Pass: false, Detail: fmt.Sprintf("portaudio init failed: %v", err)}
+	}
+	defer func() {
+		_ = portaudio.Terminate()
+	}()
+	devs, err := portaudio.Devices()
+	if err != nil {
+		return Result{Name: "mic", Pass: false, Detail: fmt.Sprintf("list devices: %v", err)}
+	}
+	return matchInputDevice(devs, id)
+}
+
+func matchInputDevice(devs []*portaudio.DeviceInfo, id config.DeviceID) Result {
+	d := asr.MatchDevice(devs, id)
+	if d == nil {
+		return Result{Name: "mic", Pass: true, Warn: true, Detail: fmt.Sprintf("saved mic %s not found; capture falls back to preferred_devices, device_index, device_name, then the default input (mic list, then mic set)", id)}
+	}
+	// Names match ignoring case, as in MatchDevice; a case-only difference
+	// is still the same mic.
+	if got := asr.DeviceIdentity(d); !strings.EqualFold(got.Name, id.Name) || got.HostAPI != id.HostAPI || got.Channels != id.Channels {
+		return Result{Name: "mic", Pass: true, Warn: true, Detail: fmt.Sprintf("saved mic %s resolved to %s", id, got)}
+	}
+	return Result{Name: "mic", Pass: true, Detail: id.String()}
+}
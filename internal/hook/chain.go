@@ -0,0 +1,83 @@
+package hook
+
+import (
+	"cmp"
+	"fmt"
+	"strings"
+	"time"
+
+	"brabble/internal/config"
+)
+
+// ChainEntry returns the hook whose chain j belongs to, and its index; for
+// a job at step 0, or outside any chain, that is j's own hook.
+func (j Job) ChainEntry() (*config.HookConfig, int) {
+	if j.ChainStep == 0 || j.Entry == nil {
+		return j.Hook, j.HookIndex
+	}
+	return j.Entry, j.EntryIndex
+}
+
+// LastStep reports whether no chain step follows the one j runs.
+func (j Job) LastStep() bool {
+	entry, _ := j.ChainEntry()
+	return j.ChainStep >= len(entry.Chain)
+}
+
+// OnFailure is the policy for a failure of the step j runs: the chain
+// step's own, or the entry's for step 0. Outside a chain it is always stop.
+func (j Job) OnFailure() string {
+	entry, _ := j.ChainEntry()
+	switch {
+	case len(entry.Chain) == 0:
+		return config.ChainStop
+	case j.ChainStep == 0:
+		return cmp.Or(entry.OnFailure, config.ChainStop)
+	default:
+		return cmp.Or(entry.Chain[j.ChainStep-1].OnFailure, config.ChainStop)
+	}
+}
+
+// AtChainStep returns j moved to step n of its entry's chain, run by that
+// step's hook in hooks. Latency limits apply to the entry only, so later
+// steps carry no deadline.
+func (j Job) AtChainStep(hooks []config.HookConfig, n int) (Job, error) {
+	entry, idx := j.ChainEntry()
+	if n == 0 {
+		j.Hook, j.HookIndex, j.ChainStep, j.Entry = entry, idx, 0, nil
+		return j, nil
+	}
+	if n < 0 || n > len(entry.Chain) {
+		return j, fmt.Errorf("hook #%d has no chain step %d (%d configured)", idx, n, len(entry.Chain))
+	}
+	t := entry.Chain[n-1].Hook
+	if t < 0 || t >= len(hooks) {
+		return j, fmt.Errorf("hook #%d chain step %d: hook #%d does not exist", idx, n, t)
+	}
+	j.Entry, j.EntryIndex = entry, idx
+	j.Hook, j.HookIndex, j.ChainStep = &hooks[t], t, n
+	j.Deadline = time.Time{}
+	return j, nil
+}
+
+// ChainInput is the text the next chain step receives after a step that
+// ran with input and produced res: the "say" of an output = "json"
+// response, else trimmed stdout; a step that printed nothing passes its own
+// input on.
+func ChainInput(input string, res Result) string {
+	if res.Response != nil && res.Response.Say != "" {
+		return res.Response.Say
+	}
+	if out := strings.TrimSpace(res.Stdout); out != "" && res.Response == nil {
+		return out
+	}
+	return input
+}
+
+func validateOnFailure(policy string) error {
+	switch policy {
+	case "", config.ChainStop, config.ChainContinue:
+		return nil
+	}
+	return fmt.Errorf("hook on_failure must be %s or %s (got %q)", config.ChainStop, config.ChainContinue, policy)
+}
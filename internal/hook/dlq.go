@@ -0,0 +1,229 @@
+package hook
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"strconv"
+	"sync"
+	"syscall"
+	"time"
+
+	"brabble/internal/config"
+)
+
+// DeadLetter is a hook job that still failed after its retries. They are
+// kept one JSON object per line in paths.dead_letter_path.
+type DeadLetter struct {
+	ID         string            `json:"id"`
+	FailedAt   time.Time         `json:"failed_at"`
+	Hook       int               `json:"hook"`                 // the chain's entry for a chain step
+	Target     string            `json:"target"`               // Hook's command or URL at the time, to spot config changes
+	ChainStep  int               `json:"chain_step,omitempty"` // the step that failed; see Job.ChainStep
+	Text       string            `json:"text"`
+	RawText    string            `json:"raw_text,omitempty"`
+	Wake       string            `json:"wake,omitempty"`
+	Device     string            `json:"device,omitempty"`
+	Confidence float64           `json:"confidence,omitempty"`
+	Language   string            `json:"language,omitempty"`
+	Reply      bool              `json:"reply,omitempty"`
+	Slots      map[string]string `json:"slots,omitempty"`
+	Queued     time.Time         `json:"queued_at"`
+	Captured   time.Time         `json:"captured_at,omitzero"`
+	Attempts   int               `json:"attempts"`
+	Error      string            `json:"error"`
+}
+
+// NewDeadLetter records job after attempts runs ending in err.
+func NewDeadLetter(job Job, attempts int, err error) DeadLetter {
+	now := time.Now()
+	entry, idx := job.ChainEntry()
+	return DeadLetter{
+		ID:         strconv.FormatInt(now.UnixNano(), 36),
+		FailedAt:   now,
+		Hook:       idx,
+		Target:     Target(entry),
+		ChainStep:  job.ChainStep,
+		Text:       job.Text,
+		RawText:    job.RawText,
+		Wake:       job.Wake,
+		Device:     job.Device,
+		Confidence: job.Confidence,
+		Language:   job.Language,
+		Reply:      job.Reply,
+		Slots:      job.Slots,
+		Queued:     job.Timestamp,
+		Captured:   job.Captured,
+		Attempts:   attempts,
+		Error:      err.Error(),
+	}
+}
+
+// ErrTargetChanged means a saved job's hook index now names a different
+// command or URL than when the job was saved, so the config was edited.
+var ErrTargetChanged = errors.New("hook target changed")
+
+// savedHook returns hook idx of the current config, checking it still runs
+// target. Entries saved without a target are not checked.
+func savedHook(cfg *config.Config, idx int, target string) (*config.HookConfig, error) {
+	hooks := cfg.EffectiveHooks()
+	if idx < 0 || idx >= len(hooks) {
+		return nil, fmt.Errorf("hook #%d no longer exists (%d configured)", idx, len(hooks))
+	}
+	hk := &hooks[idx]
+	if now := Target(hk); target != "" && now != target {
+		return hk, fmt.Errorf("%w: hook #%d was %q, now %q", ErrTargetChanged, idx, target, now)
+	}
+	return hk, nil
+}
+
+// Job rebuilds the job for a replay against the current hooks, at the
+// chain step that failed. It fails with ErrTargetChanged if the hook at d's
+// index now runs something else, unless force is set. Latency limits do not
+// apply to replays.
+func (d DeadLetter) Job(cfg *config.Config, force bool) (Job, error) {
+	hk, err := savedHook(cfg, d.Hook, d.Target)
+	if err != nil && !(force && errors.Is(err, ErrTargetChanged)) {
+		return Job{}, err
+	}
+	job := Job{
+		Text:       d.Text,
+		RawText:    d.RawText,
+		Wake:       d.Wake,
+		Device:     d.Device,
+		Confidence: d.Confidence,
+		Language:   d.Language,
+		Reply:      d.Reply,
+		Slots:      d.Slots,
+		Timestamp:  time.Now(),
+		Captured:   d.Captured,
+		Hook:       hk,
+		HookIndex:  d.Hook,
+	}
+	return job.AtChainStep(cfg.EffectiveHooks(), d.ChainStep)
+}
+
+// Target names what hk runs: its command, or its URL for webhooks.
+func Target(hk *config.HookConfig) string {
+	if hk == nil {
+		return ""
+	}
+	if hk.Type == config.HookTypeWebhook {
+		return hk.URL
+	}
+	return hk.Command
+}
+
+// dlqMu serializes writers in this process; flock covers the daemon and
+// CLI commands touching the file at the same time.
+var dlqMu sync.Mutex
+
+func openDLQ(path string, flag int) (*os.File, error) {
+	if err := os.MkdirAll(filepath.Dir(path), 0o755); err != nil {
+		return nil, err
+	}
+	f, err := os.OpenFile(path, flag|os.O_CREATE, 0o600)
+	if err != nil {
+		return nil, err
+	}
+	if err := syscall.Flock(int(f.Fd()), syscall.LOCK_EX); err != nil {
+		_ = f.Close()
+		return nil, err
+	}
+	return f, nil
+}
+
+// AppendDeadLetter adds d to the file at path.
+func AppendDeadLetter(path string, d DeadLetter) error {
+	line, err := json.Marshal(d)
+	if err != nil {
+		return err
+	}
+	dlqMu.Lock()
+	defer dlqMu.Unlock()
+	f, err := openDLQ(path, os.O_WRONLY|os.O_APPEND)
+	if err != nil {
+		return fmt.Errorf("open dead letters: %w", err)
+	}
+	if _, err := f.Write(append(line, '\n')); err != nil {
+		_ = f.Close()
+		return fmt.Errorf("write dead letter: %w", err)
+	}
+	return f.Close()
+}
+
+// ReadDeadLetters returns the entries at path, oldest first; a missing file
+// has none.
+func ReadDeadLetters(path string) ([]DeadLetter, error) {
+	f, err := os.Open(path)
+	if os.IsNotExist(err) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	defer func() { _ = f.Close() }()
+	return decodeDeadLetters(f)
+}
+
+func decodeDeadLetters(r io.Reader) ([]DeadLetter, error) {
+	var out []DeadLetter
+	sc := bufio.NewScanner(r)
+	sc.Buffer(make([]byte, 0, 64<<10), 4<<20)
+	for n := 1; sc.Scan(); n++ {
+		line := bytes.TrimSpace(sc.Bytes())
+		if len(line) == 0 {
+			continue
+		}
+		var d DeadLetter
+		if err := json.Unmarshal(line, &d); err != nil {
+			return nil, fmt.Errorf("dead letters line %d: %w", n, err)
+		}
+		out = append(out, d)
+	}
+	return out, sc.Err()
+}
+
+// RemoveDeadLetters deletes the entries drop selects and returns them.
+// Entries appended meanwhile by the daemon are kept.
+func RemoveDeadLetters(path string, drop func(DeadLetter) bool) ([]DeadLetter, error) {
+	dlqMu.Lock()
+	defer dlqMu.Unlock()
+	f, err := openDLQ(path, os.O_RDWR)
+	if err != nil {
+		return nil, fmt.Errorf("open dead letters: %w", err)
+	}
+	defer func() { _ = f.Close() }()
+	all, err := decodeDeadLetters(f)
+	if err != nil {
+		return nil, err
+	}
+	var keep bytes.Buffer
+	var removed []DeadLetter
+	for _, d := range all {
+		if drop(d) {
+			removed = append(removed, d)
+			continue
+		}
+		line, err := json.Marshal(d)
+		if err != nil {
+			return nil, err
+		}
+		keep.Write(append(line, '\n'))
+	}
+	if len(removed) == 0 {
+		return nil, nil
+	}
+	if err := f.Truncate(0); err != nil {
+		return nil, err
+	}
+	if _, err := f.WriteAt(keep.Bytes(), 0); err != nil {
+		return nil, err
+	}
+	return removed, nil
+}
@@ -1,8 +1,11 @@
 package hook
 
 import (
+	"bytes"
+	"cmp"
 	"context"
 	"fmt"
+	"net/http"
 	"os"
 	"os/exec"
 	"regexp"
@@ -20,18 +23,60 @@ import (
 // Job represents a hook invocation request.
 type Job struct {
 	Text      string
-	Timestamp time.Time
+	Timestamp time.Time // when the job was queued
+
+	// Hook is the hook the job was routed to; HookIndex is its position in
+	// cfg.EffectiveHooks() and keys the hook's cooldown.
+	Hook      *config.HookConfig
+	HookIndex int
+
+	// Context for templates; see Data.
+	RawText    string  // as heard, before wake-word removal; empty = Text
+	Wake       string  // matched wake word or alias
+	Confidence float64 // ASR confidence
+	Language   string  // language ASR decoded; empty = asr.language
+	Device     string  // active input device
+
+	Slots     map[string]string // captured by a regex or intent match
+	JournalID string            // set once the job is in the persistent queue
+	Reply     bool              // carries another hook's "say"; its own is not forwarded again
+	// A chain moves through its steps as one job, each step queued for its
+	// own hook. ChainStep is the step Hook runs: 0 for the entry, n for the
+	// entry's Chain[n-1], with the previous step's output as Text. Past
+	// step 0, Entry and EntryIndex name the entry; see ChainEntry.
+	ChainStep  int
+	Entry      *config.HookConfig
+	EntryIndex int
+
+	Captured    time.Time // end of speech at the mic; zero if unknown
+	Transcribed time.Time // when ASR produced the text; zero if unknown
+	Deadline    time.Time // Captured + max_latency_ms; zero = no limit
+	StalePolicy string    // what to do past Deadline: drop or mark
+	Stale       bool      // ran past Deadline under stale_policy = "mark"
 }
 
-// Runner executes hooks with cooldown and prefix handling.
+// Expired reports whether now is past the job's latency deadline.
+func (j Job) Expired(now time.Time) bool {
+	return !j.Deadline.IsZero() && now.After(j.Deadline)
+}
+
+// Latency is the time from end of speech to now, or 0 if unknown.
+func (j Job) Latency(now time.Time) time.Duration {
+	if j.Captured.IsZero() {
+		return 0
+	}
+	return now.Sub(j.Captured)
+}
+
+// Runner executes hooks with prefix handling and tracks each hook's
+// cooldown separately. It is safe for concurrent use.
 type Runner struct {
 	cfg      *config.Config
 	logger   *logging.Logger
-	lastRun  time.Time
 	mu       sync.Mutex
+	lastRun  map[int]time.Time    // by hook index
+	clients  map[int]*http.Client // webhook clients, by hook index
 	hostname string
-
-	activeHook *config.HookConfig
 }
 
 // NewRunner constructs a hook runner with hostname cached.
@@ -40,41 +85,39 @@ func NewRunner(cfg *config.Config, logger *logging.Logger) *Runner {
 	return &Runner{
 		cfg:      cfg,
 		logger:   logger,
+		lastRun:  map[int]time.Time{},
+		clients:  map[int]*http.Client{},
 		hostname: host,
 	}
 }
 
-// ShouldRun returns whether cooldown allows a new hook.
-func (r *Runner) ShouldRun() bool {
-	hk, last := r.state()
+// ShouldRun returns whether the cooldown of hook idx (hk) allows a new run.
+// Other hooks' runs do not count.
+func (r *Runner) ShouldRun(idx int, hk *config.HookConfig) bool {
 	if hk == nil {
 		return false
 	}
 	if hk.CooldownSec <= 0 {
 		return true
 	}
+	r.mu.Lock()
+	last := r.lastRun[idx]
+	r.mu.Unlock()
 	return time.Since(last).Seconds() >= hk.CooldownSec
 }
 
-// Run executes the configured command with text payload.
-func (r *Runner) Run(ctx context.Context, job Job) error {
-	hk := r.selectedHook()
+// Run executes the job's hook with the text payload: a local command, or
+// an HTTP request for type = "webhook". The result carries the hook's
+// output even when it failed.
+func (r *Runner) Run(ctx context.Context, job Job) (Result, error) {
+	hk := job.Hook
 	if hk == nil {
-		return fmt.Errorf("no hook selected")
+		return Result{}, fmt.Errorf("job has no hook")
 	}
-	cmdStr := hk.Command
-	if cmdStr == "" {
-		return fmt.Errorf("hook command not configured")
-	}
-	args := append([]string{}, hk.Args...)
-
-	prefix := strings.ReplaceAll(hk.Prefix, "${hostname}", r.hostname)
-	text := job.Text
-	if hk.RedactPII {
-		text = redactPII(text)
+	rendered, err := r.Render(job)
+	if err != nil {
+		return Result{}, permanentError{err}
 	}
-	payload := strings.TrimSpace(prefix + text)
-	args = append(args, payload)
 
 	runCtx := ctx
 	var cancel context.CancelFunc
@@ -82,13 +125,114 @@ func (r *Runner) Run(ctx context.Context, job Job) error {
 		runCtx, cancel = context.WithTimeout(ctx, time.Duration(float64(time.Second)*hk.TimeoutSec))
 		defer cancel()
 	}
-	cmd := exec.CommandContext(runCtx, cmdStr, args...)
+	var res Result
+	if hk.Type == config.HookTypeWebhook {
+		res, err = r.runWebhook(runCtx, job, rendered)
+	} else {
+		res, err = r.runCommand(runCtx, job, rendered)
+	}
+	if err != nil {
+		return res, err
+	}
+	if hk.Output == config.OutputJSON {
+		// The hook did its job; an unreadable response only loses the reply.
+		if res.Response, err = parseResponse(res); err != nil {
+			r.logger.Warnf("hook #%d: %v", job.HookIndex, err)
+		}
+	}
+
+	r.mu.Lock()
+	r.lastRun[job.HookIndex] = time.Now()
+	r.mu.Unlock()
+	return res, nil
+}
+
+// Validate reports configuration errors in hk that would make every run
+// fail.
+func Validate(hk *config.HookConfig) error {
+	switch hk.Type {
+	case "", config.HookTypeCommand:
+		if hk.Command == "" {
+			return fmt.Errorf("hook command not configured")
+		}
+		switch hk.Payload {
+		case "", config.PayloadArgv, config.PayloadStdin, config.PayloadStdinJSON, config.PayloadEnvOnly:
+		default:
+			return fmt.Errorf("hook payload must be %s, %s, %s, or %s (got %q)",
+				config.PayloadArgv, config.PayloadStdin, config.PayloadStdinJSON, config.PayloadEnvOnly, hk.Payload)
+		}
+	case config.HookTypeWebhook:
+		if err := validateWebhook(hk); err != nil {
+			return err
+		}
+		if hk.Payload != "" {
+			return fmt.Errorf("payload applies to command hooks; webhooks send body")
+		}
+	default:
+		return fmt.Errorf("hook type must be %q or %q (got %q)", config.HookTypeCommand, config.HookTypeWebhook, hk.Type)
+	}
+	if err := validateMatch(hk); err != nil {
+		return err
+	}
+	switch hk.Output {
+	case "", config.OutputText, config.OutputJSON:
+	default:
+		return fmt.Errorf("hook output must be %s or %s (got %q)", config.OutputText, config.OutputJSON, hk.Output)
+	}
+	if hk.OutputMaxBytes < 0 {
+		return fmt.Errorf("hook output_max_bytes must be >= 0")
+	}
+	if hk.Retries < 0 || hk.RetryBackoffMS < 0 || hk.RetryMaxBackoffMS < 0 {
+		return fmt.Errorf("hook retries, retry_backoff_ms, and retry_max_backoff_ms must be >= 0")
+	}
+	for _, code := range hk.RetryExitCodes {
+		if code < 1 || code > 255 {
+			return fmt.Errorf("hook retry_exit_codes must be exit statuses 1-255 (got %d)", code)
+		}
+	}
+	if err := validateOnFailure(hk.OnFailure); err != nil {
+		return err
+	}
+	for n, step := range hk.Chain {
+		if err := validateOnFailure(step.OnFailure); err != nil {
+			return fmt.Errorf("chain step %d: %w", n+1, err)
+		}
+	}
+	if hk.ReplyHook != nil && hk.Output != config.OutputJSON {
+		return fmt.Errorf("reply_hook needs output = %q", config.OutputJSON)
+	}
+	return validateTemplates(hk)
+}
+
+func (r *Runner) runCommand(ctx context.Context, job Job, rendered Rendered) (Result, error) {
+	hk := job.Hook
+	cmdStr := rendered.Command
+	args := rendered.Args
+
+	cmd := exec.CommandContext(ctx, cmdStr, args...)
+	if rendered.Stdin != nil {
+		cmd.Stdin = bytes.NewReader(rendered.Stdin)
+	}
 	cmd.Env = os.Environ()
-	for k, v := range hk.Env {
+	for k, v := range rendered.Env {
 		cmd.Env = append(cmd.Env, fmt.Sprintf("%s=%s", k, v))
 	}
-	cmd.Env = append(cmd.Env, fmt.Sprintf("BRABBLE_TEXT=%s", text))
-	cmd.Env = append(cmd.Env, fmt.Sprintf("BRABBLE_PREFIX=%s", prefix))
+	cmd.Env = append(cmd.Env, fmt.Sprintf("BRABBLE_TEXT=%s", rendered.Data.Text))
+	cmd.Env = append(cmd.Env, fmt.Sprintf("BRABBLE_PREFIX=%s", rendered.Data.Prefix))
+	if latency := job.Latency(time.Now()); latency > 0 {
+		cmd.Env = append(cmd.Env, fmt.Sprintf("BRABBLE_LATENCY_MS=%d", latency.Milliseconds()))
+	}
+	if job.Stale {
+		cmd.Env = append(cmd.Env, "BRABBLE_STALE=1")
+	}
+	slotNames := make([]string, 0, len(rendered.Data.Slots))
+	for name := range rendered.Data.Slots {
+		slotNames = append(slotNames, name)
+	}
+	sort.Strings(slotNames)
+	for _, name := range slotNames {
+		cmd.Env = append(cmd.Env, fmt.Sprintf("%s=%s", SlotEnv(name), rendered.Data.Slots[name]))
+	}
 
 	envKeys := make([]string, 0, len(hk.Env))
 	for key := range hk.Env {
@@ -98,30 +242,36 @@ func (r *Runner) Run(ctx context.Context, job Job) error {
 	r.logger.Info("hook exec",
 		"cmd", cmdStr,
 		"args", args,
+		"payload", cmp.Or(hk.Payload, config.PayloadArgv),
 		"timeout_sec", hk.TimeoutSec,
 		"env_keys", envKeys,
+		"slots", slotNames,
 		"redact_pii", hk.RedactPII,
+		"stale", job.Stale,
 	)
 
-	out, err := cmd.CombinedOutput()
-	if len(out) > 0 {
-		r.logger.Infof("hook output: %s", strings.TrimSpace(string(out)))
+	stdout, stderr := newCapBuffer(hk), newCapBuffer(hk)
+	cmd.Stdout, cmd.Stderr = stdout, stderr
+	err := cmd.Run()
+	res := Result{
+		Stdout:    stdout.String(),
+		Stderr:    stderr.String(),
+		Truncated: stdout.truncated || stderr.truncated,
+		ExitCode:  -1,
+	}
+	if cmd.ProcessState != nil {
+		res.ExitCode = cmd.ProcessState.ExitCode()
+	}
+	if out := strings.TrimSpace(res.Stdout); out != "" {
+		r.logger.Infof("hook stdout: %s", out)
+	}
+	if out := strings.TrimSpace(res.Stderr); out != "" {
+		r.logger.Infof("hook stderr: %s", out)
 	}
 	if err != nil {
-		return fmt.Errorf("hook failed: %w", err)
+		return res, fmt.Errorf("hook failed: %w", err)
 	}
-
-	r.mu.Lock()
-	r.lastRun = time.Now()
-	r.mu.Unlock()
-	return nil
-}
-
-// SelectHook sets the active hook (used by server dispatcher).
-func (r *Runner) SelectHook(hk *config.HookConfig) {
-	r.mu.Lock()
-	defer r.mu.Unlock()
-	r.activeHook = hk
+	return res, nil
 }
 
 // ParseArgs allows Hook.Args to be configured as a single string.
@@ -132,21 +282,6 @@ func ParseArgs(raw string) ([]string, error) {
 	return shlex.Split(raw)
 }
 
-func (r *Runner) selectedHook() *config.HookConfig {
-	r.mu.Lock()
-	defer r.mu.Unlock()
-	if r.activeHook != nil {
-		return r.activeHook
-	}
-	return nil
-}
-
-func (r *Runner) state() (*config.HookConfig, time.Time) {
-	r.mu.Lock()
-	defer r.mu.Unlock()
-	return r.activeHook, r.lastRun
-}
-
 var (
 	emailRE = regexp.MustCompile(`[\w.+-]+@[\w.-]+\.[A-Za-z]{2,}`)
 	phoneRE = regexp.MustCompile(`\+?\d[\d\s\-\(\)]{6,}\d`)
@@ -3,7 +3,10 @@ package hook
 import (
 	"bytes"
 	"context"
+	"encoding/json"
 	"log/slog"
+	"os"
+	"path/filepath"
 	"testing"
 	"time"
 
@@ -18,19 +21,18 @@ func TestShouldRunCooldown(t *testing.T) {
 		CooldownSec: 0.5,
 	}}
 	r := NewRunner(cfg, logging.NewTestLogger())
-	r.SelectHook(&cfg.Hooks[0])
 
-	if !r.ShouldRun() {
+	if !r.ShouldRun(0, &cfg.Hooks[0]) {
 		t.Fatalf("first call should run")
 	}
-	if err := r.Run(context.Background(), Job{Text: "test", Timestamp: time.Now()}); err != nil {
+	if _, err := r.Run(context.Background(), Job{Hook: &cfg.Hooks[0], Text: "test", Timestamp: time.Now()}); err != nil {
 		t.Fatalf("run: %v", err)
 	}
-	if r.ShouldRun() {
+	if r.ShouldRun(0, &cfg.Hooks[0]) {
 		t.Fatalf("cooldown should block immediate subsequent run")
 	}
 	time.Sleep(time.Duration(cfg.Hook.CooldownSec*float64(time.Second)) + 20*time.Millisecond)
-	if !r.ShouldRun() {
+	if !r.ShouldRun(0, &cfg.Hooks[0]) {
 		t.Fatalf("should run after cooldown")
 	}
 }
@@ -44,10 +46,9 @@ func TestRunUsesPrefixAndEnv(t *testing.T) {
 	}}
 
 	r := NewRunner(cfg, logging.NewTestLogger())
-	r.SelectHook(&cfg.Hooks[0])
 	ctx, cancel := context.WithTimeout(context.Background(), time.Second)
 	defer cancel()
-	if err := r.Run(ctx, Job{Text: "hello", Timestamp: time.Now()}); err != nil {
+	if _, err := r.Run(ctx, Job{Hook: &cfg.Hooks[0], Text: "hello", Timestamp: time.Now()}); err != nil {
 		t.Fatalf("run echo: %v", err)
 	}
 }
@@ -60,9 +61,8 @@ func TestRunDoesNotLogEnvironmentValues(t *testing.T) {
 		Env:     map[string]string{"BRABBLE_TEST_SECRET": "do-not-log-this"},
 	}}
 	r := NewRunner(cfg, &logging.Logger{Logger: slog.New(slog.NewTextHandler(&logs, nil))})
-	r.SelectHook(&cfg.Hooks[0])
 
-	if err := r.Run(context.Background(), Job{Text: "test", Timestamp: time.Now()}); err != nil {
+	if _, err := r.Run(context.Background(), Job{Hook: &cfg.Hooks[0], Text: "test", Timestamp: time.Now()}); err != nil {
 		t.Fatalf("run: %v", err)
 	}
 	if bytes.Contains(logs.Bytes(), []byte("do-not-log-this")) {
@@ -82,3 +82,168 @@ func TestSelectHookConfigFallsBackToSingleHook(t *testing.T) {
 		t.Fatalf("single hook fallback failed: hook=%+v index=%d", hk, index)
 	}
 }
+
+func TestRunExportsLatencyAndStaleMark(t *testing.T) {
+	var logs bytes.Buffer
+	cfg, _ := config.Default()
+	cfg.Hooks = []config.HookConfig{{
+		Command: "/bin/sh",
+		Args:    []string{"-c", `echo "stale=$BRABBLE_STALE latency=${BRABBLE_LATENCY_MS:+set}"`},
+	}}
+	r := NewRunner(cfg, &logging.Logger{Logger: slog.New(slog.NewTextHandler(&logs, nil))})
+
+	job := Job{Hook: &cfg.Hooks[0], Text: "late", Timestamp: time.Now(), Captured: time.Now().Add(-2 * time.Second), Stale: true}
+	if _, err := r.Run(context.Background(), job); err != nil {
+		t.Fatalf("run: %v", err)
+	}
+	if !bytes.Contains(logs.Bytes(), []byte("stale=1 latency=set")) {
+		t.Fatalf("stale env missing from hook output: %s", logs.String())
+	}
+}
+
+func TestJobExpired(t *testing.T) {
+	now := time.Now()
+	if (Job{}).Expired(now) {
+		t.Fatal("job without deadline expired")
+	}
+	if !(Job{Deadline: now.Add(-time.Millisecond)}).Expired(now) {
+		t.Fatal("job past deadline not expired")
+	}
+	if (Job{Deadline: now.Add(time.Second)}).Expired(now) {
+		t.Fatal("job before deadline expired")
+	}
+}
+
+func TestCooldownIsPerHook(t *testing.T) {
+	cfg, _ := config.Default()
+	cfg.Hooks = []config.HookConfig{
+		{Command: "/usr/bin/true", CooldownSec: 60},
+		{Command: "/usr/bin/true", CooldownSec: 60},
+	}
+	r := NewRunner(cfg, logging.NewTestLogger())
+	if _, err := r.Run(context.Background(), Job{Hook: &cfg.Hooks[0], HookIndex: 0, Text: "a"}); err != nil {
+		t.Fatalf("run: %v", err)
+	}
+	if r.ShouldRun(0, &cfg.Hooks[0]) {
+		t.Fatal("hook 0 should be cooling down")
+	}
+	if !r.ShouldRun(1, &cfg.Hooks[1]) {
+		t.Fatal("hook 1 blocked by hook 0's cooldown")
+	}
+}
+
+func TestRenderTemplates(t *testing.T) {
+	cfg, _ := config.Default()
+	cfg.ASR.Language = "de"
+	cfg.Hooks = []config.HookConfig{{
+		Command:   "/bin/echo",
+		Args:      []string{"--lang={{.Language}}", "{{.Device}}|{{.Wake}}|{{.Payload}}"},
+		Prefix:    "${hostname}/{{.Hook}}: ",
+		Env:       map[string]string{"RAW": "{{.RawText}}", "PLAIN": "as is"},
+		RedactPII: true,
+	}}
+	r := NewRunner(cfg, logging.NewTestLogger())
+	job := Job{
+		Hook: &cfg.Hooks[0], HookIndex: 0, Text: "mail bob@example.com", RawText: "clawd mail bob@example.com",
+		Wake: "clawd", Device: "AirPods", Timestamp: time.Now(),
+	}
+	got, err := r.Render(job)
+	if err != nil {
+		t.Fatalf("render: %v", err)
+	}
+	wantPrefix := r.hostname + "/0: "
+	want := []string{"--lang=de", "AirPods|clawd|" + wantPrefix + "mail [redacted-email]"}
+	if got.Data.Prefix != wantPrefix || len(got.Args) != 2 || got.Args[0] != want[0] || got.Args[1] != want[1] {
+		t.Fatalf("args %q prefix %q", got.Args, got.Data.Prefix)
+	}
+	if got.Env["RAW"] != "clawd mail [redacted-email]" || got.Env["PLAIN"] != "as is" {
+		t.Fatalf("env %v", got.Env)
+	}
+
+	// The language whisper detected wins over the configured one.
+	detected := job
+	detected.Language = "fr"
+	if got, _ := r.Render(detected); got.Args[0] != "--lang=fr" {
+		t.Fatalf("detected language arg %q", got.Args[0])
+	}
+
+	// Without templated args the payload is still appended.
+	cfg.Hooks[0].Args = []string{"send"}
+	if got, _ := r.Render(job); len(got.Args) != 2 || got.Args[1] != wantPrefix+"mail [redacted-email]" {
+		t.Fatalf("plain args %q", got.Args)
+	}
+
+	cfg.Hooks[0].Args = []string{"{{.Nope}}"}
+	if _, err := r.Render(job); err == nil {
+		t.Fatal("unknown field rendered")
+	}
+	cfg.Hooks[0].Args = []string{"{{.Text"}
+	if err := Validate(&cfg.Hooks[0]); err == nil {
+		t.Fatal("unparsable template validated")
+	}
+}
+
+func TestRunPayloadModes(t *testing.T) {
+	dir := t.TempDir()
+	// Records argv after the script, stdin, and BRABBLE_TEXT.
+	script := `for a; do printf '%s|' "$a"; done > "$OUT.args"; cat > "$OUT.stdin"; printf '%s' "$BRABBLE_TEXT" > "$OUT.env"`
+	for _, tc := range []struct {
+		mode, args, stdin string
+	}{
+		{config.PayloadArgv, "p: lights off|", ""},
+		{config.PayloadStdin, "", "p: lights off\n"},
+		{config.PayloadStdinJSON, "", ""},
+		{config.PayloadEnvOnly, "", ""},
+	} {
+		out := filepath.Join(dir, tc.mode)
+		cfg, _ := config.Default()
+		cfg.Hooks = []config.HookConfig{{
+			Command: "/bin/sh",
+			Args:    []string{"-c", script, "sh"},
+			Prefix:  "p: ",
+			Payload: tc.mode,
+			Env:     map[string]string{"OUT": out},
+		}}
+		r := NewRunner(cfg, logging.NewTestLogger())
+		job := Job{Hook: &cfg.Hooks[0], Text: "lights off", RawText: "clawd lights off", Timestamp: time.Now(), Captured: time.Now()}
+		if _, err := r.Run(context.Background(), job); err != nil {
+			t.Fatalf("%s: run: %v", tc.mode, err)
+		}
+		read := func(ext string) string {
+			b, err := os.ReadFile(out + ext)
+			if err != nil {
+				t.Fatalf("%s: %v", tc.mode, err)
+			}
+			return string(b)
+		}
+		if got := read(".args"); got != tc.args {
+			t.Fatalf("%s: argv %q, want %q", tc.mode, got, tc.args)
+		}
+		if got := read(".env"); got != "lights off" {
+			t.Fatalf("%s: BRABBLE_TEXT %q", tc.mode, got)
+		}
+		stdin := read(".stdin")
+		if tc.mode != config.PayloadStdinJSON {
+			if stdin != tc.stdin {
+				t.Fatalf("%s: stdin %q, want %q", tc.mode, stdin, tc.stdin)
+			}
+			continue
+		}
+		var doc map[string]any
+		if err := json.Unmarshal([]byte(stdin), &doc); err != nil {
+			t.Fatalf("stdin_json %q: %v", stdin, err)
+		}
+		if doc["text"] != "lights off" || doc["raw_text"] != "clawd lights off" || doc["payload"] != "p: lights off" || doc["captured_at"] == nil {
+			t.Fatalf("stdin_json doc %v", doc)
+		}
+	}
+}
+
+func TestValidatePayloadMode(t *testing.T) {
+	if err := Validate(&config.HookConfig{Command: "/bin/true", Payload: "carrier_pigeon"}); err == nil {
+		t.Fatal("unknown payload mode accepted")
+	}
+	if err := Validate(&config.HookConfig{Type: config.HookTypeWebhook, URL: "http://h/", Payload: config.PayloadStdin}); err == nil {
+		t.Fatal("payload accepted on a webhook")
+	}
+}
@@ -0,0 +1,299 @@
+package hook
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strconv"
+	"sync"
+	"syscall"
+	"time"
+
+	"brabble/internal/config"
+)
+
+// Journal record ops.
+const (
+	journalJob  = "job"
+	journalStep = "step"
+	journalAck  = "ack"
+)
+
+// JournalEntry is one line of the hook journal: a queued job, a chain step
+// it finished, or the ack that retires it.
+type JournalEntry struct {
+	Op          string            `json:"op"`
+	ID          string            `json:"id"`
+	Hook        int               `json:"hook,omitempty"`
+	Target      string            `json:"target,omitempty"`
+	Text        string            `json:"text,omitempty"`
+	RawText     string            `json:"raw_text,omitempty"`
+	Wake        string            `json:"wake,omitempty"`
+	Device      string            `json:"device,omitempty"`
+	Confidence  float64           `json:"confidence,omitempty"`
+	Language    string            `json:"language,omitempty"`
+	Reply       bool              `json:"reply,omitempty"`
+	Slots       map[string]string `json:"slots,omitempty"`
+	Queued      time.Time         `json:"queued_at,omitzero"`
+	Captured    time.Time         `json:"captured_at,omitzero"`
+	Transcribed time.Time         `json:"transcribed_at,omitzero"`
+	Deadline    time.Time         `json:"deadline,omitzero"`
+	StalePolicy string            `json:"stale_policy,omitempty"`
+	ChainStep   int               `json:"chain_step,omitempty"` // see Job.ChainStep
+}
+
+// Job rebuilds a journaled job against the current hooks, at the chain
+// step it had reached, keeping its original timestamps so max_latency_ms
+// still applies. It fails with ErrTargetChanged if the hook at e's index
+// now runs something else.
+func (e JournalEntry) Job(cfg *config.Config) (Job, error) {
+	hk, err := savedHook(cfg, e.Hook, e.Target)
+	if err != nil {
+		return Job{}, err
+	}
+	job := Job{
+		Text:        e.Text,
+		Timestamp:   e.Queued,
+		Hook:        hk,
+		HookIndex:   e.Hook,
+		RawText:     e.RawText,
+		Wake:        e.Wake,
+		Confidence:  e.Confidence,
+		Language:    e.Language,
+		Reply:       e.Reply,
+		Slots:       e.Slots,
+		Device:      e.Device,
+		Captured:    e.Captured,
+		Transcribed: e.Transcribed,
+		Deadline:    e.Deadline,
+		StalePolicy: e.StalePolicy,
+		JournalID:   e.ID,
+	}
+	return job.AtChainStep(cfg.EffectiveHooks(), e.ChainStep)
+}
+
+// DeadLetter records e as failed with err without running it, keeping the
+// target it was journaled for.
+func (e JournalEntry) DeadLetter(err error) DeadLetter {
+	return DeadLetter{
+		ID:         e.ID,
+		FailedAt:   time.Now(),
+		Hook:       e.Hook,
+		Target:     e.Target,
+		ChainStep:  e.ChainStep,
+		Text:       e.Text,
+		RawText:    e.RawText,
+		Wake:       e.Wake,
+		Device:     e.Device,
+		Confidence: e.Confidence,
+		Language:   e.Language,
+		Reply:      e.Reply,
+		Slots:      e.Slots,
+		Queued:     e.Queued,
+		Captured:   e.Captured,
+		Error:      err.Error(),
+	}
+}
+
+// Journal is an append-only on-disk log of queued hook jobs. Each job is
+// written (and synced) before it is queued and acked once it is finished,
+// so jobs without an ack were still pending when the daemon stopped.
+type Journal struct {
+	mu      sync.Mutex
+	f       *os.File
+	pending map[string]struct{}
+	seq     int64
+}
+
+// OpenJournal opens the journal at path and returns the jobs that were
+// never acked, oldest first. The file is compacted to just those jobs. The
+// journal is locked until Close, so two daemons cannot share it.
+func OpenJournal(path string) (*Journal, []JournalEntry, error) {
+	if err := os.MkdirAll(filepath.Dir(path), 0o755); err != nil {
+		return nil, nil, err
+	}
+	f, err := os.OpenFile(path, os.O_RDWR|os.O_CREATE, 0o600)
+	if err != nil {
+		return nil, nil, fmt.Errorf("open journal: %w", err)
+	}
+	if err := syscall.Flock(int(f.Fd()), syscall.LOCK_EX|syscall.LOCK_NB); err != nil {
+		_ = f.Close()
+		return nil, nil, fmt.Errorf("journal %s is in use: %w", path, err)
+	}
+	pending, err := readJournal(f)
+	if err != nil {
+		_ = f.Close()
+		return nil, nil, err
+	}
+	j := &Journal{f: f, pending: map[string]struct{}{}}
+	var buf bytes.Buffer
+	for _, e := range pending {
+		line, err := json.Marshal(e)
+		if err != nil {
+			_ = f.Close()
+			return nil, nil, err
+		}
+		buf.Write(append(line, '\n'))
+		j.pending[e.ID] = struct{}{}
+	}
+	if err := j.rewrite(buf.Bytes()); err != nil {
+		_ = f.Close()
+		return nil, nil, err
+	}
+	return j, pending, nil
+}
+
+// readJournal replays the records in f. A torn last line from a crash
+// mid-write is ignored.
+func readJournal(f *os.File) ([]JournalEntry, error) {
+	var order []string
+	jobs := map[string]JournalEntry{}
+	sc := bufio.NewScanner(f)
+	sc.Buffer(make([]byte, 0, 64<<10), 4<<20)
+	for sc.Scan() {
+		var e JournalEntry
+		if err := json.Unmarshal(sc.Bytes(), &e); err != nil {
+			continue
+		}
+		switch e.Op {
+		case journalJob:
+			if _, dup := jobs[e.ID]; !dup {
+				order = append(order, e.ID)
+			}
+			jobs[e.ID] = e
+		case journalStep:
+			if job, ok := jobs[e.ID]; ok {
+				job.ChainStep, job.Text = e.ChainStep, e.Text
+				jobs[e.ID] = job
+			}
+		case journalAck:
+			delete(jobs, e.ID)
+		}
+	}
+	if err := sc.Err(); err != nil {
+		return nil, fmt.Errorf("read journal: %w", err)
+	}
+	out := make([]JournalEntry, 0, len(jobs))
+	for _, id := range order {
+		if e, ok := jobs[id]; ok {
+			out = append(out, e)
+		}
+	}
+	return out, nil
+}
+
+func (j *Journal) rewrite(data []byte) error {
+	if err := j.f.Truncate(0); err != nil {
+		return err
+	}
+	if _, err := j.f.WriteAt(data, 0); err != nil {
+		return err
+	}
+	if _, err := j.f.Seek(int64(len(data)), 0); err != nil {
+		return err
+	}
+	return j.f.Sync()
+}
+
+func (j *Journal) write(e JournalEntry, sync bool) error {
+	line, err := json.Marshal(e)
+	if err != nil {
+		return err
+	}
+	if _, err := j.f.Write(append(line, '\n')); err != nil {
+		return err
+	}
+	if sync {
+		return j.f.Sync()
+	}
+	return nil
+}
+
+// Append records job durably and sets job.JournalID.
+func (j *Journal) Append(job *Job) error {
+	j.mu.Lock()
+	defer j.mu.Unlock()
+	j.seq++
+	id := strconv.FormatInt(time.Now().UnixNano(), 36) + "-" + strconv.FormatInt(j.seq, 36)
+	err := j.write(JournalEntry{
+		Op:          journalJob,
+		ID:          id,
+		Hook:        job.HookIndex,
+		Target:      Target(job.Hook),
+		Text:        job.Text,
+		RawText:     job.RawText,
+		Wake:        job.Wake,
+		Device:      job.Device,
+		Confidence:  job.Confidence,
+		Language:    job.Language,
+		Reply:       job.Reply,
+		Slots:       job.Slots,
+		Queued:      job.Timestamp,
+		Captured:    job.Captured,
+		Transcribed: job.Transcribed,
+		Deadline:    job.Deadline,
+		StalePolicy: job.StalePolicy,
+	}, true)
+	if err != nil {
+		return fmt.Errorf("journal append: %w", err)
+	}
+	j.pending[id] = struct{}{}
+	job.JournalID = id
+	return nil
+}
+
+// Step records that the chain of job id has run its first done steps (the
+// entry counts as one) and that text is the next step's input, so a restart
+// resumes there instead of rerunning finished steps.
+func (j *Journal) Step(id string, done int, text string) error {
+	if id == "" {
+		return nil
+	}
+	j.mu.Lock()
+	defer j.mu.Unlock()
+	if _, ok := j.pending[id]; !ok {
+		return nil
+	}
+	if err := j.write(JournalEntry{Op: journalStep, ID: id, ChainStep: done, Text: text}, true); err != nil {
+		return fmt.Errorf("journal step: %w", err)
+	}
+	return nil
+}
+
+// Ack retires a job. When nothing is pending the file is truncated, so the
+// journal stays small without a separate compaction pass.
+func (j *Journal) Ack(id string) error {
+	if id == "" {
+		return nil
+	}
+	j.mu.Lock()
+	defer j.mu.Unlock()
+	if _, ok := j.pending[id]; !ok {
+		return nil
+	}
+	delete(j.pending, id)
+	if len(j.pending) == 0 {
+		return j.rewrite(nil)
+	}
+	if err := j.write(JournalEntry{Op: journalAck, ID: id}, false); err != nil {
+		return fmt.Errorf("journal ack: %w", err)
+	}
+	return nil
+}
+
+// Pending reports how many journaled jobs are not yet acked.
+func (j *Journal) Pending() int {
+	j.mu.Lock()
+	defer j.mu.Unlock()
+	return len(j.pending)
+}
+
+// Close releases the journal; unacked jobs stay on disk for the next open.
+func (j *Journal) Close() error {
+	j.mu.Lock()
+	defer j.mu.Unlock()
+	return j.f.Close()
+}
@@ -0,0 +1,67 @@
+package hook
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"brabble/internal/config"
+)
+
+func TestJournalReplaysUnackedJobs(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "journal.jsonl")
+	cfg, _ := config.Default()
+	cfg.Hooks = []config.HookConfig{{Command: "/bin/true"}, {Command: "/bin/echo"}}
+
+	j, pending, err := OpenJournal(path)
+	if err != nil || len(pending) != 0 {
+		t.Fatalf("open: %v %v", pending, err)
+	}
+	if _, _, err := OpenJournal(path); err == nil {
+		t.Fatal("second open of a locked journal succeeded")
+	}
+	captured := time.Now().Add(-time.Second)
+	jobs := []Job{
+		{Text: "one", Hook: &cfg.Hooks[0], Timestamp: time.Now()},
+		{Text: "two", Hook: &cfg.Hooks[1], HookIndex: 1, Timestamp: time.Now(), Captured: captured, Deadline: captured.Add(time.Minute), StalePolicy: config.StaleMark},
+		{Text: "three", Hook: &cfg.Hooks[0], Timestamp: time.Now()},
+	}
+	for i := range jobs {
+		if err := j.Append(&jobs[i]); err != nil || jobs[i].JournalID == "" {
+			t.Fatalf("append: %v", err)
+		}
+	}
+	if err := j.Ack(jobs[0].JournalID); err != nil {
+		t.Fatalf("ack: %v", err)
+	}
+	if err := j.Step(jobs[2].JournalID, 1, "THREE"); err != nil {
+		t.Fatalf("step: %v", err)
+	}
+	// Simulate a crash mid-write.
+	f, _ := os.OpenFile(path, os.O_WRONLY|os.O_APPEND, 0)
+	_, _ = f.WriteString(`{"op":"job","id":"torn","te`)
+	_ = f.Close()
+	if err := j.Close(); err != nil {
+		t.Fatalf("close: %v", err)
+	}
+
+	j, pending, err = OpenJournal(path)
+	if err != nil || len(pending) != 2 || pending[0].Text != "two" || pending[1].Text != "THREE" || pending[1].ChainStep != 1 {
+		t.Fatalf("reopen: %+v %v", pending, err)
+	}
+	job, err := pending[0].Job(cfg)
+	if err != nil || job.Hook != &cfg.Hooks[1] || !job.Captured.Equal(captured) || job.StalePolicy != config.StaleMark || job.JournalID != jobs[1].JournalID {
+		t.Fatalf("rebuilt job %+v %v", job, err)
+	}
+
+	for _, e := range pending {
+		if err := j.Ack(e.ID); err != nil {
+			t.Fatalf("ack: %v", err)
+		}
+	}
+	if info, err := os.Stat(path); err != nil || info.Size() != 0 || j.Pending() != 0 {
+		t.Fatalf("journal not emptied: %v %v", info, err)
+	}
+	_ = j.Close()
+}
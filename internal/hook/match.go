@@ -0,0 +1,143 @@
+package hook
+
+import (
+	"fmt"
+	"regexp"
+	"strings"
+	"sync"
+
+	"brabble/internal/config"
+)
+
+var (
+	// slotRE finds {name} and {name:a|b|c} in intent patterns.
+	slotRE     = regexp.MustCompile(`\{([A-Za-z_][A-Za-z0-9_]*)(?::([^{}]*))?\}`)
+	spaceRE    = regexp.MustCompile(`\s+`)
+	slotNameRE = regexp.MustCompile(`[^A-Za-z0-9]+`)
+
+	// patternCache holds compiled patterns by kind and source, since routing
+	// runs for every segment.
+	patternCache sync.Map // string → *regexp.Regexp
+)
+
+// compilePattern compiles a regex or intent pattern. Both are
+// case-insensitive; intents must match the whole command, ignoring
+// surrounding punctuation and runs of whitespace.
+func compilePattern(kind, pattern string) (*regexp.Regexp, error) {
+	key := kind + "\x00" + pattern
+	if re, ok := patternCache.Load(key); ok {
+		return re.(*regexp.Regexp), nil
+	}
+	src := pattern
+	if kind == config.MatchIntent {
+		var err error
+		if src, err = intentRegexp(pattern); err != nil {
+			return nil, err
+		}
+	}
+	re, err := regexp.Compile("(?i)" + src)
+	if err != nil {
+		return nil, fmt.Errorf("hook %s pattern %q: %w", kind, pattern, err)
+	}
+	patternCache.Store(key, re)
+	return re, nil
+}
+
+// intentRegexp translates an intent pattern: literal words match themselves,
+// {name} captures one or more words, and {name:a|b} captures one of the
+// listed alternatives.
+func intentRegexp(pattern string) (string, error) {
+	if strings.ContainsAny(slotRE.ReplaceAllString(pattern, ""), "{}") {
+		return "", fmt.Errorf("hook intent pattern %q: unbalanced or invalid slot", pattern)
+	}
+	var b strings.Builder
+	// Not \W: it is ASCII-only and would strip accented letters at the edges.
+	b.WriteString(`^[^\p{L}\p{N}]*`)
+	seen := map[string]bool{}
+	literal := func(s string) {
+		for i, part := range spaceRE.Split(s, -1) {
+			if i > 0 {
+				b.WriteString(`\s+`)
+			}
+			b.WriteString(regexp.QuoteMeta(part))
+		}
+	}
+	last := 0
+	for _, m := range slotRE.FindAllStringSubmatchIndex(pattern, -1) {
+		literal(pattern[last:m[0]])
+		last = m[1]
+		name := pattern[m[2]:m[3]]
+		if seen[name] {
+			return "", fmt.Errorf("hook intent pattern %q: slot %q used twice", pattern, name)
+		}
+		seen[name] = true
+		if m[4] < 0 {
+			fmt.Fprintf(&b, `(?P<%s>.+?)`, name)
+			continue
+		}
+		var alts []string
+		for _, alt := range strings.Split(pattern[m[4]:m[5]], "|") {
+			if alt = strings.TrimSpace(alt); alt != "" {
+				alts = append(alts, strings.Join(strings.Fields(regexp.QuoteMeta(alt)), `\s+`))
+			}
+		}
+		if len(alts) == 0 {
+			return "", fmt.Errorf("hook intent pattern %q: slot %q has no choices", pattern, name)
+		}
+		fmt.Fprintf(&b, `(?P<%s>%s)`, name, strings.Join(alts, "|"))
+	}
+	literal(pattern[last:])
+	b.WriteString(`[^\p{L}\p{N}]*$`)
+	return b.String(), nil
+}
+
+// matchPatterns tries hk's patterns against text in order and returns the
+// slots of the first match.
+func matchPatterns(hk *config.HookConfig, text string) (map[string]string, bool) {
+	for _, p := range hk.Patterns {
+		re, err := compilePattern(hk.Match, p)
+		if err != nil {
+			continue // rejected by Validate at startup
+		}
+		m := re.FindStringSubmatchIndex(text)
+		if m == nil {
+			continue
+		}
+		slots := map[string]string{}
+		for i, name := range re.SubexpNames() {
+			if name != "" && m[2*i] >= 0 {
+				slots[name] = strings.TrimSpace(text[m[2*i]:m[2*i+1]])
+			}
+		}
+		return slots, true
+	}
+	return nil, false
+}
+
+func validateMatch(hk *config.HookConfig) error {
+	switch hk.Match {
+	case "", config.MatchWake:
+		if len(hk.Patterns) > 0 {
+			return fmt.Errorf("hook patterns need match = %q or %q", config.MatchRegex, config.MatchIntent)
+		}
+		return nil
+	case config.MatchRegex, config.MatchIntent:
+	default:
+		return fmt.Errorf("hook match must be %s, %s, or %s (got %q)", config.MatchWake, config.MatchRegex, config.MatchIntent, hk.Match)
+	}
+	if len(hk.Patterns) == 0 {
+		return fmt.Errorf("hook match = %q needs patterns", hk.Match)
+	}
+	for _, p := range hk.Patterns {
+		if _, err := compilePattern(hk.Match, p); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// SlotEnv names the environment variable carrying slot name:
+// BRABBLE_SLOT_ plus the name upper-cased, other characters as _.
+func SlotEnv(name string) string {
+	return "BRABBLE_SLOT_" + strings.ToUpper(slotNameRE.ReplaceAllString(name, "_"))
+}
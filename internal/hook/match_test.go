@@ -0,0 +1,112 @@
+package hook
+
+import (
+	"context"
+	"maps"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"brabble/internal/config"
+	"brabble/internal/logging"
+)
+
+func TestIntentPatterns(t *testing.T) {
+	hk := &config.HookConfig{Match: config.MatchIntent, Patterns: []string{"turn {state:on|off} the {device}", "set {device} to {level} percent", "{device} {state:on|off}"}}
+	for _, tc := range []struct {
+		text  string
+		slots map[string]string
+	}{
+		{"Turn OFF the kitchen lights.", map[string]string{"state": "OFF", "device": "kitchen lights"}},
+		{"turn  on   the fan", map[string]string{"state": "on", "device": "fan"}},
+		{"set the lamp to 40 percent", map[string]string{"device": "the lamp", "level": "40"}},
+		{"turn on the café", map[string]string{"state": "on", "device": "café"}},
+		{"¿Übertopf on?", map[string]string{"device": "Übertopf", "state": "on"}},
+		{"turn sideways the fan", nil},
+		{"please turn on the fan", nil},
+	} {
+		slots, ok := matchPatterns(hk, tc.text)
+		if ok != (tc.slots != nil) || !maps.Equal(slots, tc.slots) {
+			t.Fatalf("%q: slots %v ok %v, want %v", tc.text, slots, ok, tc.slots)
+		}
+	}
+}
+
+func TestRouteByPattern(t *testing.T) {
+	cfg, _ := config.Default()
+	cfg.Hooks = []config.HookConfig{
+		{Wake: []string{"clawd"}, Command: "/bin/true"},
+		{Match: config.MatchRegex, Patterns: []string{`^play (?P<song>.+?)( on (?P<room>\w+))?$`}, Command: "/bin/true"},
+		{Match: config.MatchIntent, Patterns: []string{"turn {state:on|off} the {device}"}, Command: "/bin/true"},
+	}
+	for _, tc := range []struct {
+		raw, command string
+		idx          int
+		slots        map[string]string
+	}{
+		{"clawd turn on the fan", "turn on the fan", 0, nil}, // earlier entries win
+		{"hey turn on the fan", "turn on the fan", 2, map[string]string{"state": "on", "device": "fan"}},
+		{"Play Blue in Green", "Play Blue in Green", 1, map[string]string{"song": "Blue in Green"}},
+		{"play jazz on kitchen", "play jazz on kitchen", 1, map[string]string{"song": "jazz", "room": "kitchen"}},
+		{"what time is it", "what time is it", 0, nil}, // fallback
+	} {
+		_, idx, slots := Route(cfg, tc.raw, tc.command)
+		if idx != tc.idx || !maps.Equal(slots, tc.slots) {
+			t.Fatalf("%q: hook %d slots %v, want %d %v", tc.raw, idx, slots, tc.idx, tc.slots)
+		}
+	}
+
+	// The fallback skips patterned entries: their templates expect slots.
+	cfg.Hooks = append(cfg.Hooks[1:], cfg.Hooks[0])
+	if _, idx, _ := Route(cfg, "what time is it", "what time is it"); idx != 2 {
+		t.Fatalf("fallback went to hook %d, want the wake entry #2", idx)
+	}
+	cfg.Hooks = cfg.Hooks[:2]
+	if hk, idx, _ := Route(cfg, "what time is it", "what time is it"); hk != nil || idx != -1 {
+		t.Fatalf("only patterned entries: got hook %d", idx)
+	}
+}
+
+func TestSlotsReachHook(t *testing.T) {
+	out := filepath.Join(t.TempDir(), "out")
+	cfg, _ := config.Default()
+	cfg.Hooks = []config.HookConfig{{
+		Match:    config.MatchIntent,
+		Patterns: []string{"turn {state:on|off} the {device}"},
+		Command:  "/bin/sh",
+		Args:     []string{"-c", `printf '%s/%s/%s' "$BRABBLE_SLOT_STATE" "$BRABBLE_SLOT_DEVICE" "$1" > "` + out + `"`, "sh", "{{.Slots.device}}"},
+	}}
+	hk, idx, slots := Route(cfg, "turn off the porch light", "turn off the porch light")
+	r := NewRunner(cfg, logging.NewTestLogger())
+	if _, err := r.Run(context.Background(), Job{Hook: hk, HookIndex: idx, Slots: slots, Text: "turn off the porch light", Timestamp: time.Now()}); err != nil {
+		t.Fatalf("run: %v", err)
+	}
+	if got, _ := os.ReadFile(out); string(got) != "off/porch light/porch light" {
+		t.Fatalf("hook saw %q", got)
+	}
+	if SlotEnv("room-name") != "BRABBLE_SLOT_ROOM_NAME" {
+		t.Fatalf("SlotEnv %q", SlotEnv("room-name"))
+	}
+}
+
+func TestValidateMatch(t *testing.T) {
+	for _, tc := range []struct {
+		name string
+		hk   config.HookConfig
+		ok   bool
+	}{
+		{"intent", config.HookConfig{Command: "/bin/true", Match: config.MatchIntent, Patterns: []string{"open {app}"}}, true},
+		{"regex", config.HookConfig{Command: "/bin/true", Match: config.MatchRegex, Patterns: []string{`^open (?P<app>\w+)$`}}, true},
+		{"bad regex", config.HookConfig{Command: "/bin/true", Match: config.MatchRegex, Patterns: []string{`(`}}, false},
+		{"no patterns", config.HookConfig{Command: "/bin/true", Match: config.MatchIntent}, false},
+		{"patterns without match", config.HookConfig{Command: "/bin/true", Patterns: []string{"x"}}, false},
+		{"unknown match", config.HookConfig{Command: "/bin/true", Match: "fuzzy", Patterns: []string{"x"}}, false},
+		{"unbalanced slot", config.HookConfig{Command: "/bin/true", Match: config.MatchIntent, Patterns: []string{"open {app"}}, false},
+		{"repeated slot", config.HookConfig{Command: "/bin/true", Match: config.MatchIntent, Patterns: []string{"{a} and {a}"}}, false},
+	} {
+		if err := Validate(&tc.hk); (err == nil) != tc.ok {
+			t.Fatalf("%s: err = %v", tc.name, err)
+		}
+	}
+}
@@ -0,0 +1,73 @@
+package hook
+
+import (
+	"bytes"
+	"cmp"
+	"encoding/json"
+	"fmt"
+	"strings"
+
+	"brabble/internal/config"
+)
+
+// DefaultOutputMaxBytes is how much of each output stream is kept when a
+// hook leaves output_max_bytes unset.
+const DefaultOutputMaxBytes = 16 << 10
+
+// Result is what a hook run produced. For webhooks Stdout is the response
+// body and Status the HTTP status.
+type Result struct {
+	Stdout    string
+	Stderr    string
+	Truncated bool      // a stream exceeded output_max_bytes
+	ExitCode  int       // commands; -1 when killed or never started
+	Status    int       // webhooks
+	Response  *Response // parsed Stdout when output = "json" and the run succeeded
+}
+
+// Response is the JSON a hook may print (or a webhook return) under
+// output = "json".
+type Response struct {
+	Say      string `json:"say,omitempty"`      // reply text, handed to reply_hook
+	Continue bool   `json:"continue,omitempty"` // keep the conversation open: follow-ups skip the wake word
+}
+
+// capBuffer keeps the first max bytes written to it and discards the rest,
+// so a chatty hook cannot grow the daemon without bound.
+type capBuffer struct {
+	buf       bytes.Buffer
+	max       int
+	truncated bool
+}
+
+func newCapBuffer(hk *config.HookConfig) *capBuffer {
+	return &capBuffer{max: cmp.Or(hk.OutputMaxBytes, DefaultOutputMaxBytes)}
+}
+
+func (b *capBuffer) Write(p []byte) (int, error) {
+	if room := b.max - b.buf.Len(); len(p) > room {
+		b.truncated = true
+		b.buf.Write(p[:max(room, 0)])
+	} else {
+		b.buf.Write(p)
+	}
+	return len(p), nil
+}
+
+func (b *capBuffer) String() string { return b.buf.String() }
+
+// parseResponse reads res.Stdout as a Response. Empty output is no response.
+func parseResponse(res Result) (*Response, error) {
+	out := strings.TrimSpace(res.Stdout)
+	if out == "" {
+		return nil, nil
+	}
+	if res.Truncated {
+		return nil, fmt.Errorf("output truncated at output_max_bytes; not parsed")
+	}
+	var resp Response
+	if err := json.Unmarshal([]byte(out), &resp); err != nil {
+		return nil, fmt.Errorf("output is not a JSON response: %w", err)
+	}
+	return &resp, nil
+}
@@ -0,0 +1,80 @@
+package hook
+
+import (
+	"context"
+	"net/http"
+	"strings"
+	"testing"
+	"time"
+
+	"brabble/internal/config"
+	"brabble/internal/logging"
+)
+
+func TestRunCapturesOutput(t *testing.T) {
+	cfg, _ := config.Default()
+	r := NewRunner(cfg, logging.NewTestLogger())
+	run := func(hk config.HookConfig) (Result, error) {
+		t.Helper()
+		return r.Run(context.Background(), Job{Hook: &hk, Text: "hi", Timestamp: time.Now()})
+	}
+	sh := func(script string) config.HookConfig {
+		return config.HookConfig{Command: "/bin/sh", Args: []string{"-c", script, "sh"}}
+	}
+
+	res, err := run(sh(`echo out; echo err >&2; exit 3`))
+	if err == nil || res.Stdout != "out\n" || res.Stderr != "err\n" || res.ExitCode != 3 || res.Response != nil {
+		t.Fatalf("result %+v, err %v", res, err)
+	}
+
+	hk := sh(`printf '0123456789abcdef'`)
+	hk.OutputMaxBytes = 10
+	hk.Output = config.OutputJSON
+	res, err = run(hk)
+	if err != nil || res.Stdout != "0123456789" || !res.Truncated || res.Response != nil {
+		t.Fatalf("truncated result %+v, err %v", res, err)
+	}
+
+	hk = sh(`echo '{"say": "done, it is '"$1"'", "continue": true}'`)
+	hk.Output = config.OutputJSON
+	res, err = run(hk)
+	if err != nil || res.ExitCode != 0 || res.Response == nil || res.Response.Say != "done, it is hi" || !res.Response.Continue {
+		t.Fatalf("json result %+v, err %v", res, err)
+	}
+
+	// Not JSON: the run still succeeds, just without a response.
+	hk = sh(`echo plain words`)
+	hk.Output = config.OutputJSON
+	if res, err = run(hk); err != nil || res.Response != nil || res.Stdout != "plain words\n" {
+		t.Fatalf("plain result %+v, err %v", res, err)
+	}
+}
+
+func TestWebhookResponseIsOutput(t *testing.T) {
+	ts, _ := newWebhookServer(t, http.StatusOK)
+	cfg, _ := config.Default()
+	cfg.Hooks = []config.HookConfig{{Type: config.HookTypeWebhook, URL: ts.URL, Output: config.OutputJSON}}
+	r := NewRunner(cfg, logging.NewTestLogger())
+	res, err := r.Run(context.Background(), Job{Hook: &cfg.Hooks[0], Text: "hi", Timestamp: time.Now()})
+	if err != nil || res.Status != http.StatusOK || strings.TrimSpace(res.Stdout) != `{"ok":true}` || res.Response == nil {
+		t.Fatalf("result %+v, err %v", res, err)
+	}
+}
+
+func TestValidateOutput(t *testing.T) {
+	reply := 1
+	for _, tc := range []struct {
+		name string
+		hk   config.HookConfig
+		ok   bool
+	}{
+		{"json", config.HookConfig{Command: "/bin/true", Output: config.OutputJSON, ReplyHook: &reply}, true},
+		{"unknown", config.HookConfig{Command: "/bin/true", Output: "yaml"}, false},
+		{"reply without json", config.HookConfig{Command: "/bin/true", ReplyHook: &reply}, false},
+		{"negative max", config.HookConfig{Command: "/bin/true", OutputMaxBytes: -1}, false},
+	} {
+		if err := Validate(&tc.hk); (err == nil) != tc.ok {
+			t.Fatalf("%s: err = %v", tc.name, err)
+		}
+	}
+}
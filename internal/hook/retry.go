@@ -0,0 +1,65 @@
+package hook
+
+import (
+	"cmp"
+	"context"
+	"errors"
+	"math/rand/v2"
+	"net/http"
+	"os/exec"
+	"slices"
+	"time"
+
+	"brabble/internal/config"
+)
+
+// StatusError reports a webhook response outside expect_status.
+type StatusError struct {
+	Code   int
+	Status string
+}
+
+func (e *StatusError) Error() string { return "webhook returned " + e.Status }
+
+// permanentError marks failures another attempt cannot fix, such as a bad
+// template.
+type permanentError struct{ err error }
+
+func (e permanentError) Error() string { return e.err.Error() }
+func (e permanentError) Unwrap() error { return e.err }
+
+// Retryable reports whether a failed run of hk is worth another attempt.
+// Commands retry on any non-zero exit unless retry_exit_codes narrows it;
+// webhooks retry on 429 and 5xx. Start, network, and timeout errors always
+// retry; configuration errors and cancellation never do.
+func Retryable(hk *config.HookConfig, err error) bool {
+	var perm permanentError
+	if err == nil || errors.As(err, &perm) || errors.Is(err, context.Canceled) || errors.Is(err, exec.ErrNotFound) {
+		return false
+	}
+	var exit *exec.ExitError
+	if errors.As(err, &exit) {
+		return len(hk.RetryExitCodes) == 0 || slices.Contains(hk.RetryExitCodes, exit.ExitCode())
+	}
+	var status *StatusError
+	if errors.As(err, &status) {
+		return status.Code == http.StatusTooManyRequests || status.Code >= 500
+	}
+	return true
+}
+
+// Backoff returns the delay before retry n (0 = first retry):
+// retry_backoff_ms doubled per retry, capped at retry_max_backoff_ms, with
+// the upper half jittered so workers retrying together spread out.
+func Backoff(hk *config.HookConfig, n int) time.Duration {
+	base := time.Duration(cmp.Or(hk.RetryBackoffMS, config.DefaultRetryBackoffMS)) * time.Millisecond
+	ceiling := time.Duration(cmp.Or(hk.RetryMaxBackoffMS, config.DefaultRetryMaxBackoffMS)) * time.Millisecond
+	d := ceiling
+	if n < 32 && base<<n > 0 && base<<n < ceiling {
+		d = base << n
+	}
+	if d <= 0 {
+		return 0 // Validate rejects negative settings; never hand rand.N a bad bound
+	}
+	return d/2 + rand.N(d/2+1)
+}
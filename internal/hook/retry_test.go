@@ -0,0 +1,125 @@
+package hook
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"os/exec"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"brabble/internal/config"
+)
+
+func exitError(t *testing.T, code int) error {
+	t.Helper()
+	err := exec.Command("/bin/sh", "-c", fmt.Sprintf("exit %d", code)).Run()
+	if err == nil {
+		t.Fatalf("exit %d succeeded", code)
+	}
+	return fmt.Errorf("hook failed: %w", err)
+}
+
+func TestRetryable(t *testing.T) {
+	anyExit := &config.HookConfig{}
+	listed := &config.HookConfig{RetryExitCodes: []int{75}}
+	for _, tc := range []struct {
+		name string
+		hk   *config.HookConfig
+		err  error
+		want bool
+	}{
+		{"exit any", anyExit, exitError(t, 1), true},
+		{"exit listed", listed, exitError(t, 75), true},
+		{"exit unlisted", listed, exitError(t, 1), false},
+		{"503", anyExit, fmt.Errorf("hook failed: %w", &StatusError{Code: 503}), true},
+		{"429", anyExit, &StatusError{Code: 429}, true},
+		{"404", anyExit, &StatusError{Code: 404}, false},
+		{"network", anyExit, errors.New("dial tcp: connection refused"), true},
+		{"template", anyExit, permanentError{errors.New("bad template")}, false},
+		{"canceled", anyExit, fmt.Errorf("hook failed: %w", context.Canceled), false},
+	} {
+		if got := Retryable(tc.hk, tc.err); got != tc.want {
+			t.Fatalf("%s: Retryable = %v, want %v", tc.name, got, tc.want)
+		}
+	}
+}
+
+func TestBackoffGrowsWithJitterAndCap(t *testing.T) {
+	hk := &config.HookConfig{RetryBackoffMS: 100, RetryMaxBackoffMS: 1000}
+	for n, full := range []time.Duration{100, 200, 400, 800, 1000, 1000} {
+		full *= time.Millisecond
+		for range 20 {
+			if d := Backoff(hk, n); d < full/2 || d > full {
+				t.Fatalf("retry %d: %s outside [%s, %s]", n, d, full/2, full)
+			}
+		}
+	}
+	if d := Backoff(hk, 200); d > time.Second {
+		t.Fatalf("huge retry count overflowed cap: %s", d)
+	}
+}
+
+func TestNegativeRetrySettings(t *testing.T) {
+	for _, hk := range []config.HookConfig{
+		{Command: "/bin/true", RetryMaxBackoffMS: -1},
+		{Command: "/bin/true", RetryBackoffMS: -500},
+		{Command: "/bin/true", Retries: -1},
+		{Command: "/bin/true", RetryExitCodes: []int{0}},
+	} {
+		if err := Validate(&hk); err == nil {
+			t.Fatalf("validated %+v", hk)
+		}
+		if d := Backoff(&hk, 0); d < 0 {
+			t.Fatalf("backoff %s for %+v", d, hk)
+		}
+	}
+}
+
+func TestDeadLetterFile(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "state", "dlq.jsonl")
+	if got, err := ReadDeadLetters(path); err != nil || got != nil {
+		t.Fatalf("missing file: %v %v", got, err)
+	}
+	cfg, _ := config.Default()
+	cfg.Hooks = []config.HookConfig{{Command: "/bin/true"}, {Command: "/bin/false"}}
+	job := Job{Hook: &cfg.Hooks[1], HookIndex: 1, Text: "lights off", Wake: "clawd", Timestamp: time.Now()}
+	first := NewDeadLetter(job, 3, errors.New("exit status 1"))
+	second := NewDeadLetter(job, 1, errors.New("timeout"))
+	second.ID = first.ID + "b"
+	for _, d := range []DeadLetter{first, second} {
+		if err := AppendDeadLetter(path, d); err != nil {
+			t.Fatalf("append: %v", err)
+		}
+	}
+	got, err := ReadDeadLetters(path)
+	if err != nil || len(got) != 2 || got[0].Text != "lights off" || got[0].Target != "/bin/false" || got[0].Attempts != 3 {
+		t.Fatalf("read %+v %v", got, err)
+	}
+	replay, err := got[0].Job(cfg, false)
+	if err != nil || replay.Hook != &cfg.Hooks[1] || replay.Wake != "clawd" {
+		t.Fatalf("replay job %+v %v", replay, err)
+	}
+
+	removed, err := RemoveDeadLetters(path, func(d DeadLetter) bool { return d.ID == first.ID })
+	if err != nil || len(removed) != 1 {
+		t.Fatalf("remove %v %v", removed, err)
+	}
+	if got, _ := ReadDeadLetters(path); len(got) != 1 || got[0].ID != second.ID {
+		t.Fatalf("after remove %+v", got)
+	}
+
+	cfg.Hooks[1].Command = "/usr/bin/false"
+	if _, err := got[0].Job(cfg, false); !errors.Is(err, ErrTargetChanged) {
+		t.Fatalf("replay onto an edited hook: %v", err)
+	}
+	if job, err := got[0].Job(cfg, true); err != nil || job.Hook != &cfg.Hooks[1] {
+		t.Fatalf("forced replay %+v %v", job, err)
+	}
+
+	cfg.Hooks = cfg.Hooks[:1]
+	if _, err := got[0].Job(cfg, true); err == nil {
+		t.Fatal("replay of removed hook accepted")
+	}
+}
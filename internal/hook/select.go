@@ -30,21 +30,42 @@ func hookMatches(lowerText string, hk *config.HookConfig) bool {
 	return false
 }
 
-// SelectHookConfig returns the first hook whose wake/alias tokens appear in
-// the provided text. If none match, it falls back to the first configured hook.
-// The returned index is the position in the effective hooks (or 0 on fallback);
-// -1 when no hook is configured.
+// SelectHookConfig returns the first hook matching text, which serves as
+// both the heard text and the command; see Route.
 func SelectHookConfig(cfg *config.Config, text string) (*config.HookConfig, int) {
+	hk, idx, _ := Route(cfg, text, text)
+	return hk, idx
+}
+
+// Route returns the first hook that matches an utterance: wake entries by
+// their wake/alias tokens in raw (the text as heard), regex and intent
+// entries by their patterns against command (the text after wake-word
+// removal), with the pattern's named slots. If none match, it falls back to
+// the first wake entry, without slots; regex and intent entries only run on
+// a match, since their templates expect slots. The returned index is the
+// position in the effective hooks; -1 when nothing is selected.
+func Route(cfg *config.Config, raw, command string) (*config.HookConfig, int, map[string]string) {
 	hooks := cfg.EffectiveHooks()
-	if len(hooks) == 0 {
-		return nil, -1
-	}
-	lower := strings.ToLower(text)
+	lower := strings.ToLower(raw)
+	fallback := -1
 	for i := range hooks {
 		hk := &hooks[i]
-		if hookMatches(lower, hk) {
-			return hk, i
+		switch hk.Match {
+		case config.MatchRegex, config.MatchIntent:
+			if slots, ok := matchPatterns(hk, command); ok {
+				return hk, i, slots
+			}
+		default:
+			if hookMatches(lower, hk) {
+				return hk, i, nil
+			}
+			if fallback < 0 {
+				fallback = i
+			}
 		}
 	}
-	return &hooks[0], 0
+	if fallback < 0 {
+		return nil, -1, nil
+	}
+	return &hooks[fallback], fallback, nil
 }
@@ -0,0 +1,248 @@
+package hook
+
+import (
+	"bytes"
+	"cmp"
+	"encoding/json"
+	"fmt"
+	"strings"
+	"text/template"
+	"time"
+
+	"brabble/internal/config"
+)
+
+// Data is the value hook templates (prefix, args, env values, webhook body)
+// are executed with, e.g. {{.Text}} or {{json .Text}}.
+type Data struct {
+	Text        string    // transcript after wake-word removal (redacted under redact_pii)
+	RawText     string    // transcript as heard, wake word included (redacted likewise)
+	Wake        string    // wake word or alias that matched; empty when none was required
+	Hook        int       // index of the hook in the effective hooks
+	Timestamp   time.Time // when the job was queued
+	Captured    time.Time // end of speech; zero when unknown
+	Transcribed time.Time // when ASR produced the text; zero when unknown
+	Language    string    // language whisper decoded, detected under asr.language = "auto"; asr.language when unknown
+	Confidence  float64   // mean text-token probability from whisper, 0 when unknown
+	Hostname    string
+	Device      string            // active input device; empty when unknown
+	LatencyMS   int64             // end of speech to now; 0 when unknown
+	Stale       bool              // past max_latency_ms under stale_policy = "mark"
+	Slots       map[string]string // regex/intent captures, e.g. {{.Slots.device}}; redacted like Text
+
+	// Set after the prefix is rendered, so unavailable inside it.
+	Prefix  string // rendered prefix
+	Payload string // prefix + text, trimmed
+}
+
+// Rendered is a hook invocation with every template applied.
+type Rendered struct {
+	Data    Data
+	Command string            // command hooks
+	Args    []string          // command hooks, payload included in argv mode
+	Stdin   []byte            // command hooks in stdin modes
+	Env     map[string]string // the hook's env table, rendered
+	Method  string            // webhooks
+	URL     string            // webhooks, before ${VAR} expansion
+	Body    []byte            // webhooks
+}
+
+var templateFuncs = template.FuncMap{
+	// json renders v as a JSON value, so {{json .Text}} is a quoted string.
+	"json": func(v any) (string, error) {
+		b, err := json.Marshal(v)
+		return string(b), err
+	},
+}
+
+func isTemplate(s string) bool { return strings.Contains(s, "{{") }
+
+func parseTemplate(name, s string) (*template.Template, error) {
+	tmpl, err := template.New(name).Funcs(templateFuncs).Parse(s)
+	if err != nil {
+		return nil, fmt.Errorf("hook %s template: %w", name, err)
+	}
+	return tmpl, nil
+}
+
+func renderString(name, s string, data Data) (string, error) {
+	if !isTemplate(s) {
+		return s, nil
+	}
+	tmpl, err := parseTemplate(name, s)
+	if err != nil {
+		return "", err
+	}
+	var buf bytes.Buffer
+	if err := tmpl.Execute(&buf, data); err != nil {
+		return "", fmt.Errorf("hook %s template: %w", name, err)
+	}
+	return buf.String(), nil
+}
+
+// validateTemplates parses every templated field of hk.
+func validateTemplates(hk *config.HookConfig) error {
+	fields := map[string]string{"prefix": hk.Prefix, "body": hk.Body}
+	for i, a := range hk.Args {
+		fields[fmt.Sprintf("args[%d]", i)] = a
+	}
+	for k, v := range hk.Env {
+		fields["env."+k] = v
+	}
+	for name, s := range fields {
+		if !isTemplate(s) {
+			continue
+		}
+		if _, err := parseTemplate(name, s); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// payloadDocument is the JSON form of Data sent by stdin_json hooks and
+// webhooks without a body template.
+type payloadDocument struct {
+	Text          string            `json:"text"`
+	RawText       string            `json:"raw_text"`
+	Wake          string            `json:"wake,omitempty"`
+	Prefix        string            `json:"prefix"`
+	Payload       string            `json:"payload"`
+	Hook          int               `json:"hook"`
+	Hostname      string            `json:"hostname"`
+	Device        string            `json:"device,omitempty"`
+	Language      string            `json:"language,omitempty"`
+	Confidence    float64           `json:"confidence"`
+	Timestamp     string            `json:"timestamp"`
+	CapturedAt    string            `json:"captured_at,omitempty"`
+	TranscribedAt string            `json:"transcribed_at,omitempty"`
+	LatencyMS     int64             `json:"latency_ms,omitempty"`
+	Stale         bool              `json:"stale"`
+	Slots         map[string]string `json:"slots,omitempty"`
+}
+
+func document(data Data) ([]byte, error) {
+	stamp := func(t time.Time) string {
+		if t.IsZero() {
+			return ""
+		}
+		return t.Format(time.RFC3339Nano)
+	}
+	return json.Marshal(payloadDocument{
+		Text:          data.Text,
+		RawText:       data.RawText,
+		Wake:          data.Wake,
+		Prefix:        data.Prefix,
+		Payload:       data.Payload,
+		Hook:          data.Hook,
+		Hostname:      data.Hostname,
+		Device:        data.Device,
+		Language:      data.Language,
+		Confidence:    data.Confidence,
+		Timestamp:     stamp(data.Timestamp),
+		CapturedAt:    stamp(data.Captured),
+		TranscribedAt: stamp(data.Transcribed),
+		LatencyMS:     data.LatencyMS,
+		Stale:         data.Stale,
+		Slots:         data.Slots,
+	})
+}
+
+// data builds the template data for job.
+func (r *Runner) data(job Job) Data {
+	text, raw := job.Text, job.RawText
+	if raw == "" {
+		raw = text
+	}
+	slots := job.Slots
+	if job.Hook.RedactPII {
+		text, raw = redactPII(text), redactPII(raw)
+		slots = make(map[string]string, len(job.Slots))
+		for k, v := range job.Slots {
+			slots[k] = redactPII(v)
+		}
+	}
+	return Data{
+		Text:        text,
+		RawText:     raw,
+		Wake:        job.Wake,
+		Hook:        job.HookIndex,
+		Timestamp:   job.Timestamp,
+		Captured:    job.Captured,
+		Transcribed: job.Transcribed,
+		Language:    cmp.Or(job.Language, r.cfg.ASR.Language),
+		Confidence:  job.Confidence,
+		Hostname:    r.hostname,
+		Device:      job.Device,
+		LatencyMS:   job.Latency(time.Now()).Milliseconds(),
+		Stale:       job.Stale,
+		Slots:       slots,
+	}
+}
+
+// Render applies the job's hook templates without running anything; Run
+// executes exactly what it returns.
+//
+// In argv mode, command hooks get the payload appended as the last argument
+// unless some argument is a template, in which case args are used as
+// rendered and {{.Payload}} goes wherever it is wanted. The stdin modes fill
+// Stdin instead.
+func (r *Runner) Render(job Job) (Rendered, error) {
+	hk := job.Hook
+	if hk == nil {
+		return Rendered{}, fmt.Errorf("job has no hook")
+	}
+	if err := Validate(hk); err != nil {
+		return Rendered{}, err
+	}
+	data := r.data(job)
+	prefix, err := renderString("prefix", strings.ReplaceAll(hk.Prefix, "${hostname}", r.hostname), data)
+	if err != nil {
+		return Rendered{}, err
+	}
+	data.Prefix = prefix
+	data.Payload = strings.TrimSpace(prefix + data.Text)
+
+	out := Rendered{Data: data, Env: make(map[string]string, len(hk.Env))}
+	for k, v := range hk.Env {
+		if out.Env[k], err = renderString("env."+k, v, data); err != nil {
+			return Rendered{}, err
+		}
+	}
+	if hk.Type == config.HookTypeWebhook {
+		out.Method = strings.ToUpper(hk.Method)
+		if out.Method == "" {
+			out.Method = "POST"
+		}
+		out.URL = hk.URL
+		out.Body, err = renderBody(hk, data)
+		return out, err
+	}
+
+	out.Command = hk.Command
+	templated := false
+	for i, a := range hk.Args {
+		templated = templated || isTemplate(a)
+		s, err := renderString(fmt.Sprintf("args[%d]", i), a, data)
+		if err != nil {
+			return Rendered{}, err
+		}
+		out.Args = append(out.Args, s)
+	}
+	switch hk.Payload {
+	case config.PayloadStdin:
+		out.Stdin = []byte(data.Payload + "\n")
+	case config.PayloadStdinJSON:
+		if out.Stdin, err = document(data); err != nil {
+			return Rendered{}, err
+		}
+		out.Stdin = append(out.Stdin, '\n')
+	case config.PayloadEnvOnly:
+		// BRABBLE_TEXT only.
+	default:
+		if !templated {
+			out.Args = append(out.Args, data.Payload)
+		}
+	}
+	return out, nil
+}
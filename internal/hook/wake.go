@@ -0,0 +1,75 @@
+package hook
+
+import (
+	"strings"
+
+	"brabble/internal/config"
+)
+
+// SplitWake applies cfg's wake settings to text: it returns the matched wake
+// word, if wake is enabled and one is present, and the command text, which
+// is text without it. Intent patterns match against the command.
+func SplitWake(cfg *config.Config, text string) (wake, command string) {
+	if !cfg.Wake.Enabled {
+		return "", text
+	}
+	wake, ok := MatchWake(text, cfg.Wake.Word, cfg.Wake.Aliases)
+	if !ok {
+		return "", text
+	}
+	return wake, StripWake(text, cfg.Wake.Word, cfg.Wake.Aliases)
+}
+
+// MatchWake returns the wake word or alias (lower-cased) found in text.
+func MatchWake(text, word string, aliases []string) (string, bool) {
+	lower := strings.ToLower(text)
+	for _, v := range wakeVariants(word, aliases) {
+		if strings.Contains(lower, v) {
+			return v, true
+		}
+	}
+	return "", false
+}
+
+// StripWake removes the first word of text that is the wake word or an alias,
+// ignoring case and surrounding punctuation. The daemon, test-hook, and hook
+// render all use it, so they agree on the command text.
+func StripWake(text, word string, aliases []string) string {
+	variants := wakeVariants(word, aliases)
+	fields := strings.Fields(text)
+	out := make([]string, 0, len(fields))
+	skipped := false
+	for _, f := range fields {
+		if !skipped && matchesAny(stripPunct(f), variants) {
+			skipped = true
+			continue
+		}
+		out = append(out, f)
+	}
+	return strings.Join(out, " ")
+}
+
+func stripPunct(s string) string {
+	return strings.Trim(s, " ,.!?;:\"'")
+}
+
+func wakeVariants(word string, aliases []string) []string {
+	v := []string{strings.ToLower(word)}
+	for _, a := range aliases {
+		a = strings.ToLower(strings.TrimSpace(a))
+		if a == "" {
+			continue
+		}
+		v = append(v, a)
+	}
+	return v
+}
+
+func matchesAny(token string, variants []string) bool {
+	for _, v := range variants {
+		if strings.EqualFold(token, v) {
+			return true
+		}
+	}
+	return false
+}
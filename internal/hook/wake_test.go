@@ -1,8 +1,8 @@
-package run
+package hook
 
 import "testing"
 
-func TestRemoveWakeWord(t *testing.T) {
+func TestStripWake(t *testing.T) {
 	cases := []struct {
 		text    string
 		word    string
@@ -17,9 +17,18 @@ func TestRemoveWakeWord(t *testing.T) {
 		{"Claude engage", "clawd", []string{"claude"}, "engage"},
 	}
 	for _, c := range cases {
-		got := removeWakeWord(c.text, c.word, c.aliases)
+		got := StripWake(c.text, c.word, c.aliases)
 		if got != c.expect {
-			t.Fatalf("removeWakeWord(%q)=%q want %q", c.text, got, c.expect)
+			t.Fatalf("StripWake(%q)=%q want %q", c.text, got, c.expect)
 		}
 	}
 }
+
+func TestMatchWakeAliases(t *testing.T) {
+	if v, ok := MatchWake("hi Claude", "clawd", []string{"claude", "cloud"}); !ok || v != "claude" {
+		t.Fatalf("expected alias match, got %q %v", v, ok)
+	}
+	if _, ok := MatchWake("hi there", "clawd", []string{"claude"}); ok {
+		t.Fatalf("expected no match")
+	}
+}
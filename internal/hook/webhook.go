@@ -0,0 +1,179 @@
+package hook
+
+import (
+	"bytes"
+	"context"
+	"crypto/tls"
+	"crypto/x509"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"os"
+	"slices"
+	"strings"
+
+	"brabble/internal/config"
+)
+
+func validateWebhook(hk *config.HookConfig) error {
+	if hk.URL == "" {
+		return fmt.Errorf("webhook hook needs url")
+	}
+	u, err := url.Parse(expandEnv(hk.Env, hk.URL))
+	if err != nil || (u.Scheme != "http" && u.Scheme != "https") || u.Host == "" {
+		return fmt.Errorf("webhook url must be an http(s) URL (got %q)", hk.URL)
+	}
+	if (hk.TLS.CertFile == "") != (hk.TLS.KeyFile == "") {
+		return fmt.Errorf("webhook tls.cert_file and tls.key_file must be set together")
+	}
+	return nil
+}
+
+// renderBody produces the request body: the body template when set, else
+// the same JSON document stdin_json hooks get.
+func renderBody(hk *config.HookConfig, data Data) ([]byte, error) {
+	if hk.Body == "" {
+		return document(data)
+	}
+	body, err := renderString("body", hk.Body, data)
+	if err != nil {
+		return nil, err
+	}
+	if !json.Valid([]byte(body)) {
+		return nil, fmt.Errorf("webhook body template did not produce valid JSON: %s", body)
+	}
+	return []byte(body), nil
+}
+
+// expandEnv expands ${VAR} from the hook's env table, then the process
+// environment, so secrets stay out of the config file.
+func expandEnv(env map[string]string, s string) string {
+	return os.Expand(s, func(key string) string {
+		if v, ok := env[key]; ok {
+			return v
+		}
+		return os.Getenv(key)
+	})
+}
+
+func (r *Runner) runWebhook(ctx context.Context, job Job, rendered Rendered) (Result, error) {
+	hk := job.Hook
+	method := rendered.Method
+	target := expandEnv(rendered.Env, rendered.URL)
+	req, err := http.NewRequestWithContext(ctx, method, target, bytes.NewReader(rendered.Body))
+	if err != nil {
+		return Result{}, fmt.Errorf("webhook request: %w", redactURL(err, hk.URL))
+	}
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set("User-Agent", "brabble")
+	headerKeys := make([]string, 0, len(hk.Headers))
+	for k, v := range hk.Headers {
+		req.Header.Set(k, expandEnv(rendered.Env, v))
+		headerKeys = append(headerKeys, k)
+	}
+	slices.Sort(headerKeys)
+	if job.Stale {
+		req.Header.Set("X-Brabble-Stale", "1")
+	}
+	client, err := r.webhookClient(job.HookIndex, hk)
+	if err != nil {
+		return Result{}, err
+	}
+	// Log and report the configured URL: the expanded one may carry secrets.
+	r.logger.Info("hook webhook",
+		"method", method,
+		"url", hk.URL,
+		"header_keys", headerKeys,
+		"timeout_sec", hk.TimeoutSec,
+		"redact_pii", hk.RedactPII,
+		"stale", job.Stale,
+	)
+
+	resp, err := client.Do(req)
+	if err != nil {
+		return Result{ExitCode: -1}, fmt.Errorf("hook failed: %w", redactURL(err, hk.URL))
+	}
+	defer func() { _ = resp.Body.Close() }()
+	body := newCapBuffer(hk)
+	_, _ = io.Copy(body, io.LimitReader(resp.Body, int64(body.max)+1))
+	res := Result{Stdout: body.String(), Truncated: body.truncated, Status: resp.StatusCode}
+	if out := strings.TrimSpace(res.Stdout); out != "" {
+		r.logger.Infof("hook response: %d %s", resp.StatusCode, out)
+	}
+	if !statusAccepted(hk.ExpectStatus, resp.StatusCode) {
+		return res, fmt.Errorf("hook failed: %w", &StatusError{Code: resp.StatusCode, Status: resp.Status})
+	}
+	return res, nil
+}
+
+// redactURL puts the configured URL back into a *url.Error, whose text
+// would otherwise carry the expanded one into logs, events and the
+// dead-letter file.
+func redactURL(err error, configured string) error {
+	var ue *url.Error
+	if errors.As(err, &ue) {
+		ue.URL = configured
+	}
+	return err
+}
+
+func statusAccepted(expect []int, code int) bool {
+	if len(expect) == 0 {
+		return code >= 200 && code < 300
+	}
+	return slices.Contains(expect, code)
+}
+
+// webhookClient returns the hook's client, built once with its TLS options.
+// Redirects are not followed, so a 3xx must be listed in expect_status.
+func (r *Runner) webhookClient(idx int, hk *config.HookConfig) (*http.Client, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	if c, ok := r.clients[idx]; ok {
+		return c, nil
+	}
+	tlsCfg, err := webhookTLS(hk.TLS)
+	if err != nil {
+		return nil, err
+	}
+	transport := http.DefaultTransport.(*http.Transport).Clone()
+	transport.TLSClientConfig = tlsCfg
+	c := &http.Client{
+		Transport: transport,
+		CheckRedirect: func(*http.Request, []*http.Request) error {
+			return http.ErrUseLastResponse
+		},
+	}
+	r.clients[idx] = c
+	return c, nil
+}
+
+func webhookTLS(opts config.HookTLS) (*tls.Config, error) {
+	// insecure_skip_verify is an explicit opt-in for self-signed local servers.
+	cfg := &tls.Config{InsecureSkipVerify: opts.InsecureSkipVerify}
+	if opts.CAFile != "" {
+		pem, err := os.ReadFile(os.ExpandEnv(opts.CAFile))
+		if err != nil {
+			return nil, fmt.Errorf("webhook tls.ca_file: %w", err)
+		}
+		pool, err := x509.SystemCertPool()
+		if err != nil {
+			pool = x509.NewCertPool()
+		}
+		if !pool.AppendCertsFromPEM(pem) {
+			return nil, fmt.Errorf("webhook tls.ca_file %s: no PEM certificates", opts.CAFile)
+		}
+		cfg.RootCAs = pool
+	}
+	if opts.CertFile != "" {
+		cert, err := tls.LoadX509KeyPair(os.ExpandEnv(opts.CertFile), os.ExpandEnv(opts.KeyFile))
+		if err != nil {
+			return nil, fmt.Errorf("webhook tls client cert: %w", err)
+		}
+		cfg.Certificates = []tls.Certificate{cert}
+	}
+	return cfg, nil
+}
@@ -0,0 +1,162 @@
+package hook
+
+import (
+	"context"
+	"encoding/json"
+	"encoding/pem"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"brabble/internal/config"
+	"brabble/internal/logging"
+)
+
+type capturedRequest struct {
+	method, auth, contentType string
+	body                      []byte
+}
+
+func newWebhookServer(t *testing.T, status int) (*httptest.Server, chan capturedRequest) {
+	t.Helper()
+	got := make(chan capturedRequest, 4)
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		body, _ := io.ReadAll(r.Body)
+		got <- capturedRequest{r.Method, r.Header.Get("Authorization"), r.Header.Get("Content-Type"), body}
+		w.WriteHeader(status)
+		_, _ = io.WriteString(w, `{"ok":true}`)
+	}))
+	t.Cleanup(ts.Close)
+	return ts, got
+}
+
+func TestWebhookPostsTemplatedBody(t *testing.T) {
+	ts, got := newWebhookServer(t, http.StatusOK)
+	t.Setenv("BRABBLE_TEST_TOKEN", "from-env")
+	cfg, _ := config.Default()
+	cfg.Hooks = []config.HookConfig{{
+		Type:    config.HookTypeWebhook,
+		URL:     ts.URL + "/voice",
+		Method:  "put",
+		Headers: map[string]string{"Authorization": "Bearer ${BRABBLE_TEST_TOKEN}"},
+		Body:    `{"command": {{json .Text}}, "source": {{json .Prefix}}, "stale": {{.Stale}}}`,
+		Prefix:  "kitchen: ",
+	}}
+	r := NewRunner(cfg, logging.NewTestLogger())
+	if _, err := r.Run(context.Background(), Job{Hook: &cfg.Hooks[0], Text: `lights "off"`, Timestamp: time.Now()}); err != nil {
+		t.Fatalf("run: %v", err)
+	}
+	req := <-got
+	if req.method != http.MethodPut || req.auth != "Bearer from-env" || req.contentType != "application/json" {
+		t.Fatalf("request %+v", req)
+	}
+	var body struct {
+		Command, Source string
+		Stale           bool
+	}
+	if err := json.Unmarshal(req.body, &body); err != nil || body.Command != `lights "off"` || body.Source != "kitchen: " {
+		t.Fatalf("body %s: %v", req.body, err)
+	}
+
+	// Header values prefer the hook's env table over the environment.
+	cfg.Hooks[0].Env = map[string]string{"BRABBLE_TEST_TOKEN": "from-hook"}
+	if _, err := r.Run(context.Background(), Job{Hook: &cfg.Hooks[0], Text: "x", Timestamp: time.Now()}); err != nil {
+		t.Fatalf("run: %v", err)
+	}
+	if req := <-got; req.auth != "Bearer from-hook" {
+		t.Fatalf("auth %q", req.auth)
+	}
+}
+
+func TestWebhookDefaultBodyAndStatus(t *testing.T) {
+	ts, got := newWebhookServer(t, http.StatusAccepted)
+	cfg, _ := config.Default()
+	cfg.Hooks = []config.HookConfig{{Type: config.HookTypeWebhook, URL: ts.URL}}
+	r := NewRunner(cfg, logging.NewTestLogger())
+	job := Job{Hook: &cfg.Hooks[0], Text: "hello", Timestamp: time.Now()}
+	if _, err := r.Run(context.Background(), job); err != nil {
+		t.Fatalf("run: %v", err)
+	}
+	req := <-got
+	var body map[string]any
+	if req.method != http.MethodPost || json.Unmarshal(req.body, &body) != nil || body["text"] != "hello" {
+		t.Fatalf("request %+v", req)
+	}
+
+	cfg.Hooks[0].ExpectStatus = []int{http.StatusOK}
+	_, err := r.Run(context.Background(), job)
+	if err == nil || !strings.Contains(err.Error(), "202") {
+		t.Fatalf("unexpected status accepted: %v", err)
+	}
+}
+
+func TestWebhookServerErrorFails(t *testing.T) {
+	ts, _ := newWebhookServer(t, http.StatusInternalServerError)
+	cfg, _ := config.Default()
+	cfg.Hooks = []config.HookConfig{{Type: config.HookTypeWebhook, URL: ts.URL}}
+	r := NewRunner(cfg, logging.NewTestLogger())
+	if _, err := r.Run(context.Background(), Job{Hook: &cfg.Hooks[0], Text: "hello", Timestamp: time.Now()}); err == nil {
+		t.Fatal("500 treated as success")
+	}
+}
+
+func TestWebhookTLS(t *testing.T) {
+	ts := httptest.NewTLSServer(http.HandlerFunc(func(w http.ResponseWriter, _ *http.Request) {
+		w.WriteHeader(http.StatusNoContent)
+	}))
+	defer ts.Close()
+	cfg, _ := config.Default()
+	r := NewRunner(cfg, logging.NewTestLogger())
+	run := func(idx int, tlsOpts config.HookTLS) error {
+		hk := &config.HookConfig{Type: config.HookTypeWebhook, URL: ts.URL, TLS: tlsOpts}
+		_, err := r.Run(context.Background(), Job{Hook: hk, HookIndex: idx, Text: "hi", Timestamp: time.Now()})
+		return err
+	}
+
+	if err := run(0, config.HookTLS{}); err == nil {
+		t.Fatal("self-signed certificate accepted without ca_file")
+	}
+	caFile := filepath.Join(t.TempDir(), "ca.pem")
+	caPEM := pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: ts.Certificate().Raw})
+	if err := os.WriteFile(caFile, caPEM, 0o600); err != nil {
+		t.Fatalf("write ca: %v", err)
+	}
+	if err := run(1, config.HookTLS{CAFile: caFile}); err != nil {
+		t.Fatalf("ca_file: %v", err)
+	}
+	if err := run(2, config.HookTLS{InsecureSkipVerify: true}); err != nil {
+		t.Fatalf("insecure_skip_verify: %v", err)
+	}
+}
+
+func TestValidateWebhook(t *testing.T) {
+	for _, tc := range []struct {
+		name string
+		hk   config.HookConfig
+		ok   bool
+	}{
+		{"ok", config.HookConfig{Type: config.HookTypeWebhook, URL: "http://127.0.0.1:8123/api"}, true},
+		{"no url", config.HookConfig{Type: config.HookTypeWebhook}, false},
+		{"not http", config.HookConfig{Type: config.HookTypeWebhook, URL: "ftp://host/x"}, false},
+		{"bad template", config.HookConfig{Type: config.HookTypeWebhook, URL: "http://h/", Body: `{{.Text`}, false},
+		{"half client cert", config.HookConfig{Type: config.HookTypeWebhook, URL: "https://h/", TLS: config.HookTLS{CertFile: "c.pem"}}, false},
+		{"unknown type", config.HookConfig{Type: "carrier_pigeon", Command: "/bin/true"}, false},
+		{"command", config.HookConfig{Command: "/bin/true"}, true},
+	} {
+		if err := Validate(&tc.hk); (err == nil) != tc.ok {
+			t.Fatalf("%s: err = %v", tc.name, err)
+		}
+	}
+}
+
+func TestWebhookBodyMustBeJSON(t *testing.T) {
+	hk := &config.HookConfig{Type: config.HookTypeWebhook, URL: "http://h/", Body: `{"text": {{.Text}}}`}
+	if _, err := renderBody(hk, Data{Text: "unquoted words"}); err == nil {
+		t.Fatal("invalid JSON body accepted")
+	}
+}
@@ -0,0 +1,187 @@
+// Package resample converts mono float32 audio between sample rates using a
+// polyphase windowed-sinc (Kaiser) filter. It is shared by live capture, which
+// streams device audio at its native rate, and file transcription.
+package resample
+
+import "math"
+
+const (
+	// zeroCrossings is the number of sinc lobes kept on each side of the
+	// filter center; more lobes give a steeper transition band.
+	zeroCrossings = 32
+	// rolloff places the cutoff just below the lower Nyquist frequency so the
+	// transition band does not fold back into the passband.
+	rolloff = 0.92
+	// kaiserBeta trades main-lobe width for stopband attenuation (~90 dB).
+	kaiserBeta = 9.0
+	// maxTableCoeffs bounds the precomputed polyphase table; ratios with very
+	// many phases fall back to computing coefficients per output sample.
+	maxTableCoeffs = 1 << 20
+)
+
+// Resampler converts a stream of samples from one rate to another. It keeps
+// filter history between calls, so audio can be fed in arbitrary block sizes.
+// A Resampler is not safe for concurrent use.
+type Resampler struct {
+	up, down int     // rate ratio dst/src reduced to up/down
+	half     int     // filter taps on each side of the center, in input samples
+	cutoff   float64 // cycles per input sample
+	table    [][]float32
+
+	buf  []float32 // pending input; buf[0] is input sample number base
+	base int64
+	next int64 // number of the next output sample
+}
+
+// New returns a Resampler from srcRate to dstRate (both in Hz and > 0).
+func New(srcRate, dstRate int) *Resampler {
+	g := gcd(srcRate, dstRate)
+	r := &Resampler{up: dstRate / g, down: srcRate / g}
+	r.cutoff = 0.5 * rolloff * math.Min(1, float64(r.up)/float64(r.down))
+	r.half = int(math.Ceil(zeroCrossings / (2 * r.cutoff)))
+	if r.up*2*r.half <= maxTableCoeffs {
+		r.table = make([][]float32, r.up)
+		for p := range r.table {
+			r.table[p] = r.coefficients(p)
+		}
+	}
+	r.Reset()
+	return r
+}
+
+// Reset discards filter history so the next Process starts a new stream.
+func (r *Resampler) Reset() {
+	// Pre-roll with silence so the first outputs have full history.
+	r.buf = make([]float32, r.half-1, 4*r.half)
+	r.base = -int64(r.half - 1)
+	r.next = 0
+}
+
+// Process appends the output available for in to dst and returns it. Output
+// lags input by the filter half-width; call Flush at end of stream.
+func (r *Resampler) Process(dst, in []float32) []float32 {
+	r.buf = append(r.buf, in...)
+	return r.drain(dst)
+}
+
+// Flush pads the stream with silence, appends the remaining output to dst,
+// and resets the Resampler.
+func (r *Resampler) Flush(dst []float32) []float32 {
+	dst = r.Process(dst, make([]float32, r.half))
+	r.Reset()
+	return dst
+}
+
+func (r *Resampler) drain(dst []float32) []float32 {
+	up, down, half := int64(r.up), int64(r.down), int64(r.half)
+	end := r.base + int64(len(r.buf)) // one past the last buffered input
+	for {
+		pos := r.next * down
+		i, phase := pos/up, int(pos%up)
+		if i+half >= end {
+			break
+		}
+		taps := r.buf[i-half+1-r.base : i+half+1-r.base]
+		coeffs := r.phaseCoefficients(phase)
+		var acc float32
+		for k, c := range coeffs {
+			acc += taps[k] * c
+		}
+		dst = append(dst, acc)
+		r.next++
+	}
+	// Drop input no longer needed by future outputs.
+	keepFrom := (r.next*down)/up - half + 1
+	if drop := keepFrom - r.base; drop > 0 {
+		if drop > int64(len(r.buf)) {
+			drop = int64(len(r.buf))
+		}
+		r.buf = append(r.buf[:0], r.buf[drop:]...)
+		r.base += drop
+	}
+	// Rebase counters each full period so they never grow without bound.
+	if r.next >= up {
+		periods := r.next / up
+		r.next -= periods * up
+		r.base -= periods * down
+	}
+	return dst
+}
+
+func (r *Resampler) phaseCoefficients(phase int) []float32 {
+	if r.table != nil {
+		return r.table[phase]
+	}
+	return r.coefficients(phase)
+}
+
+// coefficients returns the 2*half taps for an output landing phase/up of the
+// way between two input samples, normalized to unity DC gain.
+func (r *Resampler) coefficients(phase int) []float32 {
+	frac := float64(phase) / float64(r.up)
+	out := make([]float32, 2*r.half)
+	var sum float64
+	vals := make([]float64, len(out))
+	for k := range out {
+		t := float64(k-r.half+1) - frac
+		v := 2 * r.cutoff * sinc(2*r.cutoff*t) * kaiser(t/float64(r.half))
+		vals[k] = v
+		sum += v
+	}
+	for k, v := range vals {
+		out[k] = float32(v / sum)
+	}
+	return out
+}
+
+// Resample converts a complete buffer from srcRate to dstRate. The result has
+// ceil(len(in)*dstRate/srcRate) samples and is time-aligned with the input.
+func Resample(in []float32, srcRate, dstRate int) []float32 {
+	if srcRate == dstRate || len(in) == 0 {
+		out := make([]float32, len(in))
+		copy(out, in)
+		return out
+	}
+	r := New(srcRate, dstRate)
+	want := int((int64(len(in))*int64(r.up) + int64(r.down) - 1) / int64(r.down))
+	out := r.Process(make([]float32, 0, want+1), in)
+	out = r.Flush(out)
+	if len(out) > want {
+		out = out[:want]
+	}
+	return out
+}
+
+func sinc(x float64) float64 {
+	if x == 0 {
+		return 1
+	}
+	return math.Sin(math.Pi*x) / (math.Pi * x)
+}
+
+func kaiser(x float64) float64 {
+	if x <= -1 || x >= 1 {
+		return 0
+	}
+	return besselI0(kaiserBeta*math.Sqrt(1-x*x)) / besselI0(kaiserBeta)
+}
+
+// besselI0 evaluates the zeroth-order modified Bessel function by series.
+func besselI0(x float64) float64 {
+	sum, term := 1.0, 1.0
+	for k := 1; k < 50; k++ {
+		term *= (x / (2 * float64(k))) * (x / (2 * float64(k)))
+		sum += term
+		if term < sum*1e-12 {
+			break
+		}
+	}
+	return sum
+}
+
+func gcd(a, b int) int {
+	for b != 0 {
+		a, b = b, a%b
+	}
+	return a
+}
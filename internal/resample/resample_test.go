@@ -0,0 +1,121 @@
+package resample
+
+import (
+	"math"
+	"testing"
+)
+
+// linear is the naive interpolator brabble used before this package; it is
+// kept here as the baseline the sinc resampler is measured against.
+func linear(in []float32, srcSR, dstSR int) []float32 {
+	ratio := float64(dstSR) / float64(srcSR)
+	outLen := int(float64(len(in))*ratio + 0.9999)
+	out := make([]float32, outLen)
+	for i := 0; i < outLen; i++ {
+		pos := float64(i) / ratio
+		idx := int(pos)
+		if idx >= len(in)-1 {
+			out[i] = in[len(in)-1]
+			continue
+		}
+		frac := float32(pos - float64(idx))
+		out[i] = in[idx]*(1-frac) + in[idx+1]*frac
+	}
+	return out
+}
+
+func tone(freq float64, rate, n int) []float32 {
+	out := make([]float32, n)
+	for i := range out {
+		out[i] = float32(math.Sin(2 * math.Pi * freq * float64(i) / float64(rate)))
+	}
+	return out
+}
+
+// amplitude measures the strength of freq in x (Goertzel), skipping the edges
+// where filters ramp in and out.
+func amplitude(x []float32, freq float64, rate int) float64 {
+	x = x[len(x)/10 : len(x)-len(x)/10]
+	k := 2 * math.Cos(2*math.Pi*freq/float64(rate))
+	var s1, s2 float64
+	for _, v := range x {
+		s0 := float64(v) + k*s1 - s2
+		s2, s1 = s1, s0
+	}
+	return 2 * math.Sqrt(s1*s1+s2*s2-k*s1*s2) / float64(len(x))
+}
+
+func db(a float64) float64 {
+	return 20 * math.Log10(math.Max(a, 1e-12))
+}
+
+func TestResampleLength(t *testing.T) {
+	in := []float32{0, 1, 2, 3}
+	if out := Resample(in, 16000, 8000); len(out) != 2 {
+		t.Fatalf("downsample length got %d", len(out))
+	}
+	if out := Resample(in, 8000, 16000); len(out) != 8 {
+		t.Fatalf("upsample length got %d", len(out))
+	}
+	if out := Resample(make([]float32, 44100), 44100, 16000); len(out) != 16000 {
+		t.Fatalf("44.1k->16k length got %d", len(out))
+	}
+}
+
+func TestResamplePreservesPassband(t *testing.T) {
+	for _, src := range []int{8000, 22050, 44100, 48000} {
+		out := Resample(tone(1000, src, src), src, 16000)
+		if amp := amplitude(out, 1000, 16000); math.Abs(amp-1) > 0.01 {
+			t.Fatalf("%d Hz: 1 kHz amplitude %.4f, want 1", src, amp)
+		}
+	}
+}
+
+func TestResampleSuppressesAliasingBetterThanLinear(t *testing.T) {
+	cases := []struct {
+		src       int
+		freq      float64 // above the 8 kHz output Nyquist
+		aliasFreq float64
+	}{
+		{48000, 12000, 4000},
+		{44100, 10000, 6000},
+		{32000, 9500, 6500},
+	}
+	for _, c := range cases {
+		in := tone(c.freq, c.src, c.src)
+		sincAlias := db(amplitude(Resample(in, c.src, 16000), c.aliasFreq, 16000))
+		linearAlias := db(amplitude(linear(in, c.src, 16000), c.aliasFreq, 16000))
+		if sincAlias > -60 {
+			t.Fatalf("%d Hz: sinc alias at %.0f Hz is %.1f dB, want < -60 dB", c.src, c.aliasFreq, sincAlias)
+		}
+		if sincAlias > linearAlias-40 {
+			t.Fatalf("%d Hz: sinc alias %.1f dB not clearly below linear %.1f dB", c.src, sincAlias, linearAlias)
+		}
+	}
+}
+
+func TestStreamingMatchesOneShot(t *testing.T) {
+	in := tone(440, 44100, 4410)
+	want := Resample(in, 44100, 16000)
+
+	r := New(44100, 16000)
+	var got []float32
+	for start := 0; start < len(in); start += 441 {
+		got = r.Process(got, in[start:min(start+441, len(in))])
+	}
+	got = r.Flush(got)[:len(want)]
+	for i := range want {
+		if math.Abs(float64(got[i]-want[i])) > 1e-6 {
+			t.Fatalf("sample %d: streaming %.6f one-shot %.6f", i, got[i], want[i])
+		}
+	}
+}
+
+func TestResampleSameRateCopies(t *testing.T) {
+	in := []float32{1, 2, 3}
+	out := Resample(in, 16000, 16000)
+	out[0] = 9
+	if in[0] != 1 {
+		t.Fatal("same-rate resample aliased its input")
+	}
+}
@@ -0,0 +1,153 @@
+// Package responder gives audible feedback: earcons on wake and on hook
+// results, and spoken replies through a local TTS command.
+package responder
+
+import (
+	"context"
+	"os"
+	"os/exec"
+	"strings"
+	"time"
+
+	"brabble/internal/config"
+	"brabble/internal/logging"
+)
+
+// Earcons.
+const (
+	Wake    = "wake"    // wake word heard (or push-to-talk released)
+	Success = "success" // hook job succeeded
+	Failure = "failure" // hook job failed for good
+)
+
+// queueSize bounds pending feedback; beyond it new items are dropped, since
+// late feedback is worse than none.
+const queueSize = 8
+
+// item is one thing to play: an earcon file or text to speak.
+type item struct {
+	file string
+	text string
+}
+
+// Responder plays earcons and speaks text, one at a time and in order.
+// While anything plays, and for duck_tail_ms after, the mic is ducked so the
+// daemon does not transcribe itself.
+type Responder struct {
+	cfg    *config.Config
+	logger *logging.Logger
+	duck   func(ducked bool)
+	queue  chan item
+}
+
+// New builds a responder; duck is called around playback when
+// responder.duck is on. Nothing plays until Run.
+func New(cfg *config.Config, logger *logging.Logger, duck func(ducked bool)) *Responder {
+	return &Responder{cfg: cfg, logger: logger, duck: duck, queue: make(chan item, queueSize)}
+}
+
+// Earcon queues the sound configured for kind, if any.
+func (r *Responder) Earcon(kind string) {
+	var file string
+	switch kind {
+	case Wake:
+		file = r.cfg.Responder.WakeSound
+	case Success:
+		file = r.cfg.Responder.SuccessSound
+	case Failure:
+		file = r.cfg.Responder.FailureSound
+	}
+	if file == "" || r.cfg.Responder.Player == "" {
+		return
+	}
+	r.enqueue(item{file: os.ExpandEnv(file)})
+}
+
+// Say queues text for the TTS command when responder.speak is on.
+func (r *Responder) Say(text string) {
+	text = strings.TrimSpace(text)
+	if text == "" || !r.cfg.Responder.Speak || r.cfg.Responder.TTSCommand == "" {
+		return
+	}
+	r.enqueue(item{text: text})
+}
+
+func (r *Responder) enqueue(it item) {
+	select {
+	case r.queue <- it:
+	default:
+		r.logger.Warnf("responder busy; dropped %s", it)
+	}
+}
+
+func (it item) String() string {
+	if it.file != "" {
+		return "earcon " + it.file
+	}
+	return "speech"
+}
+
+// Run plays queued items until ctx ends.
+func (r *Responder) Run(ctx context.Context) {
+	tailDur := time.Duration(r.cfg.Responder.DuckTailMS) * time.Millisecond
+	ducked := false
+	setDuck := func(on bool) {
+		if ducked != on && r.duck != nil {
+			r.duck(on)
+		}
+		ducked = on
+	}
+	defer setDuck(false)
+	var tail <-chan time.Time
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-tail:
+			tail = nil
+			setDuck(false)
+		case it := <-r.queue:
+			if r.cfg.Responder.Duck {
+				setDuck(true)
+			}
+			r.play(ctx, it)
+			if ducked {
+				// Let the room go quiet before listening again.
+				tail = time.After(tailDur)
+			}
+		}
+	}
+}
+
+func (r *Responder) play(ctx context.Context, it item) {
+	if r.cfg.Responder.TimeoutSec > 0 {
+		var cancel context.CancelFunc
+		ctx, cancel = context.WithTimeout(ctx, time.Duration(r.cfg.Responder.TimeoutSec*float64(time.Second)))
+		defer cancel()
+	}
+	cmd := r.command(ctx, it)
+	r.logger.Debug("responder play", "cmd", cmd.Path, "item", it.String())
+	if out, err := cmd.CombinedOutput(); err != nil {
+		r.logger.Warnf("responder %s: %v %s", it, err, strings.TrimSpace(string(out)))
+	}
+}
+
+// command builds the player invocation for an earcon, or the TTS
+// invocation for text: the file or text goes last, or on stdin under
+// tts_stdin. Text can come from a remote webhook, so it follows "--" and
+// cannot be read as an option.
+func (r *Responder) command(ctx context.Context, it item) *exec.Cmd {
+	rc := r.cfg.Responder
+	if it.file != "" {
+		return exec.CommandContext(ctx, rc.Player, append(append([]string(nil), rc.PlayerArgs...), it.file)...)
+	}
+	args := append([]string(nil), rc.TTSArgs...)
+	if !rc.TTSStdin {
+		args = append(args, "--", it.text)
+	}
+	cmd := exec.CommandContext(ctx, rc.TTSCommand, args...)
+	if rc.TTSStdin {
+		cmd.Stdin = strings.NewReader(it.text + "\n")
+	}
+	return cmd
+}
@@ -0,0 +1,74 @@
+package responder
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"brabble/internal/config"
+	"brabble/internal/logging"
+)
+
+func TestResponderPlaysInOrderWhileDucked(t *testing.T) {
+	dir := t.TempDir()
+	log := filepath.Join(dir, "played")
+	cfg, _ := config.Default()
+	cfg.Responder.Enabled = true
+	cfg.Responder.Player = "/bin/sh"
+	cfg.Responder.PlayerArgs = []string{"-c", `echo "play $1" >> "` + log + `"`, "sh"}
+	cfg.Responder.WakeSound = "wake.wav"
+	cfg.Responder.SuccessSound = "ok.wav"
+	cfg.Responder.TTSCommand = "/bin/sh"
+	cfg.Responder.TTSArgs = []string{"-c", `echo "say $(cat)" >> "` + log + `"`}
+	cfg.Responder.TTSStdin = true
+	cfg.Responder.DuckTailMS = 20
+
+	ducks := make(chan bool, 8)
+	r := New(cfg, logging.NewTestLogger(), func(on bool) { ducks <- on })
+	r.Earcon(Wake)
+	r.Earcon(Failure) // no failure_sound: nothing to play
+	r.Earcon(Success)
+	r.Say("  lights are off ")
+	r.Say("")
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	go r.Run(ctx)
+	for _, want := range []bool{true, false} {
+		select {
+		case got := <-ducks:
+			if got != want {
+				t.Fatalf("duck(%v), want duck(%v)", got, want)
+			}
+		case <-time.After(5 * time.Second):
+			t.Fatalf("no duck(%v)", want)
+		}
+	}
+	played, err := os.ReadFile(log)
+	if err != nil || string(played) != "play wake.wav\nplay ok.wav\nsay lights are off\n" {
+		t.Fatalf("played %q, %v", played, err)
+	}
+}
+
+func TestResponderTTSArgv(t *testing.T) {
+	cfg, _ := config.Default()
+	cfg.Responder.TTSCommand = "say"
+	cfg.Responder.TTSArgs = []string{"-v", "Samantha"}
+	r := New(cfg, logging.NewTestLogger(), nil)
+	cmd := r.command(context.Background(), item{text: "hello"})
+	if got := cmd.Args; len(got) != 5 || got[3] != "--" || got[4] != "hello" || cmd.Stdin != nil {
+		t.Fatalf("args %q", got)
+	}
+	// A reply that looks like options stays the text operand.
+	cmd = r.command(context.Background(), item{text: "-w /tmp/x"})
+	if got := cmd.Args; len(got) != 5 || got[3] != "--" || got[4] != "-w /tmp/x" {
+		t.Fatalf("option-like text: args %q", got)
+	}
+	cfg.Responder.Speak = false
+	r.Say("hello")
+	if len(r.queue) != 0 {
+		t.Fatal("spoke with speak = false")
+	}
+}
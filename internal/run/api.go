@@ -0,0 +1,203 @@
+package run
+
+import (
+	"context"
+	"crypto/subtle"
+	"encoding/json"
+	"errors"
+	"io"
+	"net"
+	"net/http"
+	"slices"
+	"strings"
+	"time"
+
+	"brabble/internal/config"
+	"brabble/internal/control"
+)
+
+// apiMaxBody bounds request bodies; args are small JSON objects.
+const apiMaxBody = 1 << 20
+
+// apiRoutes maps HTTP endpoints onto control ops. Ops listed in
+// api.allowed_ops are also reachable through POST /v1/ops/{op}; the rest
+// (use_model) stay on the local socket.
+var apiRoutes = []struct{ method, path, op string }{
+	{http.MethodGet, "/v1/status", "status"},
+	{http.MethodGet, "/v1/health", "health"},
+	{http.MethodGet, "/v1/capabilities", "capabilities"},
+	{http.MethodGet, "/v1/transcripts", "transcripts"},
+	{http.MethodPost, "/v1/pause", "pause"},
+	{http.MethodPost, "/v1/resume", "resume"},
+	{http.MethodPost, "/v1/test-hook", "test_hook"},
+}
+
+func validateAPI(cfg *config.Config) error {
+	if cfg.API.Enabled && strings.TrimSpace(cfg.API.Token) == "" {
+		return errors.New("api.token (or BRABBLE_API_TOKEN) is required when api.enabled is set")
+	}
+	return nil
+}
+
+// apiHandler serves the control ops over HTTP and the event stream over
+// WebSocket, behind bearer-token auth.
+func (s *Server) apiHandler() http.Handler {
+	mux := http.NewServeMux()
+	for _, rt := range apiRoutes {
+		op := rt.op
+		mux.HandleFunc(rt.method+" "+rt.path, func(w http.ResponseWriter, r *http.Request) {
+			s.serveAPIOp(w, r, op)
+		})
+	}
+	mux.HandleFunc("POST /v1/ops/{op}", func(w http.ResponseWriter, r *http.Request) {
+		op := r.PathValue("op")
+		if o, ok := s.controlOps()[op]; ok && o.open == nil && !slices.Contains(s.cfg.API.AllowedOps, op) {
+			writeAPIError(w, http.StatusForbidden, &control.Error{Code: control.ErrForbidden, Message: op + " is not in api.allowed_ops"})
+			return
+		}
+		s.serveAPIOp(w, r, op)
+	})
+	mux.HandleFunc("GET /v1/events", s.serveAPIEvents)
+	return s.apiCORS(s.apiAuth(mux))
+}
+
+// apiAuth requires "Authorization: Bearer <token>". Browsers cannot set
+// headers on a WebSocket, so /v1/events also accepts ?token=.
+func (s *Server) apiAuth(next http.Handler) http.Handler {
+	want := []byte(s.cfg.API.Token)
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		got, ok := strings.CutPrefix(r.Header.Get("Authorization"), "Bearer ")
+		if !ok && r.URL.Path == "/v1/events" {
+			got, ok = r.URL.Query().Get("token"), true
+		}
+		if !ok || len(want) == 0 || subtle.ConstantTimeCompare([]byte(got), want) != 1 {
+			w.Header().Set("WWW-Authenticate", `Bearer realm="brabble"`)
+			writeAPIError(w, http.StatusUnauthorized, &control.Error{Code: control.ErrUnauthorized, Message: "missing or invalid bearer token"})
+			return
+		}
+		next.ServeHTTP(w, r)
+	})
+}
+
+// apiCORS lets the configured origins call the API from a browser.
+func (s *Server) apiCORS(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		origin := r.Header.Get("Origin")
+		allowed := origin != "" && (slices.Contains(s.cfg.API.AllowedOrigins, "*") || slices.Contains(s.cfg.API.AllowedOrigins, origin))
+		if allowed {
+			w.Header().Set("Access-Control-Allow-Origin", origin)
+			w.Header().Set("Vary", "Origin")
+		}
+		if r.Method == http.MethodOptions && r.Header.Get("Access-Control-Request-Method") != "" {
+			if allowed {
+				w.Header().Set("Access-Control-Allow-Methods", "GET, POST")
+				w.Header().Set("Access-Control-Allow-Headers", "Authorization, Content-Type")
+			}
+			w.WriteHeader(http.StatusNoContent)
+			return
+		}
+		next.ServeHTTP(w, r)
+	})
+}
+
+// serveAPIOp runs op with the request body as args and replies with the
+// same envelope as the control socket.
+func (s *Server) serveAPIOp(w http.ResponseWriter, r *http.Request, op string) {
+	body, err := io.ReadAll(http.MaxBytesReader(w, r.Body, apiMaxBody))
+	if err != nil {
+		writeAPIError(w, http.StatusRequestEntityTooLarge, badRequest("read body: "+err.Error()))
+		return
+	}
+	if o, ok := s.controlOps()[op]; ok && o.open != nil {
+		writeAPIError(w, http.StatusBadRequest, badRequest(op+" streams; use GET /v1/events"))
+		return
+	}
+	req := control.Request{V: control.ProtocolVersion, Op: op, Args: json.RawMessage(strings.TrimSpace(string(body)))}
+	reply, _ := s.dispatch(req)
+	resp := reply.(control.Response)
+	code := http.StatusOK
+	if !resp.OK {
+		code = apiStatusCode(resp.Error.Code)
+	}
+	writeAPIJSON(w, code, resp)
+}
+
+// serveAPIEvents upgrades to a WebSocket and streams subscribe events, one
+// JSON object per text message. ?types=final,wake filters by event type.
+func (s *Server) serveAPIEvents(w http.ResponseWriter, r *http.Request) {
+	var args control.SubscribeArgs
+	if t := r.URL.Query().Get("types"); t != "" {
+		args.Types = strings.Split(t, ",")
+	}
+	raw, _ := json.Marshal(args)
+	_, stream, err := s.subscribeOp(raw)
+	if err != nil {
+		ce := asControlError(err)
+		writeAPIError(w, apiStatusCode(ce.Code), ce)
+		return
+	}
+	defer stream.close()
+	ws, err := upgradeWebSocket(w, r)
+	if err != nil {
+		s.logger.Debugf("api events: %v", err) // upgradeWebSocket has replied or closed the connection
+		return
+	}
+	defer func() { _ = ws.Close() }()
+	ctx, cancel := context.WithCancel(r.Context())
+	defer cancel()
+	// server.Close does not reach hijacked connections; closing ws also
+	// unblocks a write stuck on a stalled client.
+	stopClose := context.AfterFunc(ctx, func() { _ = ws.Close() })
+	defer stopClose()
+	go func() {
+		_ = ws.readLoop()
+		cancel()
+	}()
+	if err := stream.follow(ctx, json.NewEncoder(ws)); err != nil && ctx.Err() == nil {
+		s.logger.Debugf("api events: %v", err)
+	}
+}
+
+func apiStatusCode(code string) int {
+	switch code {
+	case control.ErrBadRequest, control.ErrUnsupportedVersion:
+		return http.StatusBadRequest
+	case control.ErrUnknownOp:
+		return http.StatusNotFound
+	case control.ErrUnauthorized:
+		return http.StatusUnauthorized
+	case control.ErrForbidden:
+		return http.StatusForbidden
+	default:
+		return http.StatusInternalServerError
+	}
+}
+
+func writeAPIError(w http.ResponseWriter, code int, e *control.Error) {
+	writeAPIJSON(w, code, control.Response{V: control.ProtocolVersion, Error: e})
+}
+
+func writeAPIJSON(w http.ResponseWriter, code int, v any) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(code)
+	_ = json.NewEncoder(w).Encode(v)
+}
+
+// apiServe runs the API listener until ctx ends. Requests inherit ctx, so
+// event streams on hijacked connections end with the daemon too.
+func (s *Server) apiServe(ctx context.Context) {
+	server := &http.Server{
+		Addr:              s.cfg.API.Addr,
+		Handler:           s.apiHandler(),
+		ReadHeaderTimeout: 5 * time.Second,
+		BaseContext:       func(net.Listener) context.Context { return ctx },
+	}
+	go func() {
+		<-ctx.Done()
+		_ = server.Close()
+	}()
+	s.logger.Infof("api listening on http://%s/v1/", s.cfg.API.Addr)
+	if err := server.ListenAndServe(); err != nil && !errors.Is(err, http.ErrServerClosed) {
+		s.logger.Warnf("api server: %v", err)
+	}
+}
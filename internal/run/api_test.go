@@ -0,0 +1,157 @@
+package run
+
+import (
+	"bufio"
+	"encoding/binary"
+	"encoding/json"
+	"io"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"brabble/internal/config"
+	"brabble/internal/control"
+)
+
+func newAPITestServer(t *testing.T) (*Server, *httptest.Server) {
+	t.Helper()
+	cfg, _ := config.Default()
+	cfg.API.Enabled = true
+	cfg.API.Token = "s3cret"
+	srv := newTestServer(t, cfg)
+	ts := httptest.NewServer(srv.apiHandler())
+	t.Cleanup(ts.Close)
+	return srv, ts
+}
+
+func apiDo(t *testing.T, ts *httptest.Server, method, path, token, body string) (int, control.Response) {
+	t.Helper()
+	req, _ := http.NewRequest(method, ts.URL+path, strings.NewReader(body))
+	if token != "" {
+		req.Header.Set("Authorization", "Bearer "+token)
+	}
+	res, err := http.DefaultClient.Do(req)
+	if err != nil {
+		t.Fatalf("%s %s: %v", method, path, err)
+	}
+	defer func() { _ = res.Body.Close() }()
+	var resp control.Response
+	if err := json.NewDecoder(res.Body).Decode(&resp); err != nil {
+		t.Fatalf("decode %s: %v", path, err)
+	}
+	return res.StatusCode, resp
+}
+
+func TestAPIRequiresTokenAndSharesOps(t *testing.T) {
+	srv, ts := newAPITestServer(t)
+	srv.setDevice("AirPods")
+
+	for _, token := range []string{"", "wrong"} {
+		if code, resp := apiDo(t, ts, http.MethodGet, "/v1/status", token, ""); code != http.StatusUnauthorized || resp.Error.Code != control.ErrUnauthorized {
+			t.Fatalf("token %q: %d %+v", token, code, resp.Error)
+		}
+	}
+	code, resp := apiDo(t, ts, http.MethodGet, "/v1/status", "s3cret", "")
+	var st control.Status
+	if code != http.StatusOK || !resp.OK || json.Unmarshal(resp.Result, &st) != nil || st.Device != "AirPods" {
+		t.Fatalf("status: %d %+v", code, resp)
+	}
+
+	for _, tc := range []struct {
+		method, path, body string
+		code               int
+	}{
+		{http.MethodPost, "/v1/pause", `{"duration_sec":-5}`, http.StatusBadRequest},
+		{http.MethodPost, "/v1/pause", `not json`, http.StatusBadRequest},
+		{http.MethodPost, "/v1/test-hook", `{}`, http.StatusBadRequest},
+		{http.MethodPost, "/v1/ops/warp_drive", ``, http.StatusNotFound},
+		{http.MethodPost, "/v1/ops/subscribe", ``, http.StatusBadRequest},
+		{http.MethodPost, "/v1/ops/unmute_hooks", ``, http.StatusOK},
+		{http.MethodPost, "/v1/ops/use_model", `{"model":"/tmp/x.bin"}`, http.StatusForbidden},
+		{http.MethodGet, "/v1/events", ``, http.StatusBadRequest}, // not an upgrade
+		{http.MethodGet, "/v1/transcripts", ``, http.StatusOK},
+	} {
+		if code, resp := apiDo(t, ts, tc.method, tc.path, "s3cret", tc.body); code != tc.code {
+			t.Fatalf("%s %s: %d %+v, want %d", tc.method, tc.path, code, resp.Error, tc.code)
+		}
+	}
+}
+
+func TestAPIEventsWebSocket(t *testing.T) {
+	srv, ts := newAPITestServer(t)
+	conn, err := net.Dial("tcp", strings.TrimPrefix(ts.URL, "http://"))
+	if err != nil {
+		t.Fatalf("dial: %v", err)
+	}
+	defer func() { _ = conn.Close() }()
+	handshake := "GET /v1/events?types=device&token=s3cret HTTP/1.1\r\n" +
+		"Host: brabble\r\nUpgrade: websocket\r\nConnection: Upgrade\r\n" +
+		"Sec-WebSocket-Key: dGhlIHNhbXBsZSBub25jZQ==\r\nSec-WebSocket-Version: 13\r\n\r\n"
+	if _, err := io.WriteString(conn, handshake); err != nil {
+		t.Fatalf("handshake: %v", err)
+	}
+	br := bufio.NewReader(conn)
+	res, err := http.ReadResponse(br, nil)
+	if err != nil {
+		t.Fatalf("read upgrade: %v", err)
+	}
+	// Accept value from the RFC 6455 example.
+	if res.StatusCode != http.StatusSwitchingProtocols || res.Header.Get("Sec-WebSocket-Accept") != "s3pPLMBiTxaQ9kYGzzhZRbK+xOo=" {
+		t.Fatalf("upgrade: %d %v", res.StatusCode, res.Header)
+	}
+
+	srv.events.publish(control.Event{Type: control.EventFinal, Text: "filtered out"})
+	srv.setDevice("AirPods")
+	var hdr [2]byte
+	if _, err := io.ReadFull(br, hdr[:]); err != nil {
+		t.Fatalf("read frame: %v", err)
+	}
+	if hdr[0] != 0x80|wsOpText || hdr[1]&0x80 != 0 {
+		t.Fatalf("frame header %x", hdr)
+	}
+	payload := make([]byte, hdr[1])
+	if _, err := io.ReadFull(br, payload); err != nil {
+		t.Fatalf("read payload: %v", err)
+	}
+	var ev control.Event
+	if err := json.Unmarshal(payload, &ev); err != nil || ev.Type != control.EventDevice || ev.Device != "AirPods" {
+		t.Fatalf("event %s: %v", payload, err)
+	}
+
+	// A masked close frame ends the stream and drops the subscription.
+	closeFrame := []byte{0x80 | wsOpClose, 0x80 | 2, 1, 2, 3, 4}
+	closeFrame = append(closeFrame, binary.BigEndian.AppendUint16(nil, 1000)...)
+	closeFrame[6] ^= 1
+	closeFrame[7] ^= 2
+	if _, err := conn.Write(closeFrame); err != nil {
+		t.Fatalf("write close: %v", err)
+	}
+	deadline := time.Now().Add(time.Second)
+	for {
+		srv.events.mu.Lock()
+		n := len(srv.events.subs)
+		srv.events.mu.Unlock()
+		if n == 0 {
+			break
+		}
+		if time.Now().After(deadline) {
+			t.Fatal("subscription not released after close")
+		}
+		time.Sleep(5 * time.Millisecond)
+	}
+}
+
+func TestValidateAPIRequiresToken(t *testing.T) {
+	cfg, _ := config.Default()
+	cfg.API.Enabled = true
+	if err := validateAPI(cfg); err == nil {
+		t.Fatal("enabled api without token accepted")
+	}
+	cfg.API.Token = "x"
+	if err := validateAPI(cfg); err != nil {
+		t.Fatalf("validate: %v", err)
+	}
+}
@@ -0,0 +1,273 @@
+package run
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"sort"
+	"strings"
+	"time"
+
+	"brabble/internal/asr"
+	"brabble/internal/control"
+	"brabble/internal/hook"
+)
+
+// controlOp is one entry in the control op registry. run receives the raw
+// args object (possibly empty) and returns the result payload. Streaming ops
+// set open instead, which returns the acknowledgement plus a stream that
+// then takes over the connection.
+type controlOp struct {
+	summary string
+	run     func(args json.RawMessage) (any, error)
+	open    func(args json.RawMessage) (any, *controlStream, error)
+}
+
+// controlStream writes to a connection after its op is acknowledged. close
+// always runs, even if the acknowledgement could not be written.
+type controlStream struct {
+	follow func(ctx context.Context, enc *json.Encoder) error
+	close  func()
+}
+
+// controlOps returns the op registry, built on first use.
+func (s *Server) controlOps() map[string]controlOp {
+	s.opsOnce.Do(func() {
+		s.ops = map[string]controlOp{
+			"capabilities": {summary: "List protocol version and supported ops", run: noArgs(func() (any, error) {
+				return s.capabilities(), nil
+			})},
+			"status": {summary: "Uptime, mic, model, and recent transcripts", run: noArgs(func() (any, error) {
+				return s.status(), nil
+			})},
+			"transcripts": {summary: "Recent transcripts (status_tail)", run: noArgs(func() (any, error) {
+				return s.copyTranscripts(), nil
+			})},
+			"test_hook": {summary: "Run the matching hook on text now; args: text", run: func(raw json.RawMessage) (any, error) {
+				var args control.TestHookArgs
+				if err := decodeArgs(raw, &args); err != nil {
+					return nil, err
+				}
+				if strings.TrimSpace(args.Text) == "" {
+					return nil, badRequest("text required")
+				}
+				return s.testHook(args.Text)
+			}},
+			"inject": {summary: "Feed text through the live pipeline as a transcript; args: text, partial, raw", run: func(raw json.RawMessage) (any, error) {
+				var args control.InjectArgs
+				if err := decodeArgs(raw, &args); err != nil {
+					return nil, err
+				}
+				if strings.TrimSpace(args.Text) == "" {
+					return nil, badRequest("text required")
+				}
+				return s.inject(args), nil
+			}},
+			"health": {summary: "Liveness check", run: noArgs(func() (any, error) {
+				return control.SimpleResponse{OK: true, Message: "ok"}, nil
+			})},
+			"pause": {summary: "Close the mic; args: duration_sec", run: durationOp(s.pause)},
+			"resume": {summary: "Reopen the mic after pause", run: noArgs(func() (any, error) {
+				return simpleResult(s.resume())
+			})},
+			"mute_hooks": {summary: "Keep transcribing but skip hooks; args: duration_sec", run: durationOp(s.muteHooks)},
+			"unmute_hooks": {summary: "Resume hook dispatch", run: noArgs(func() (any, error) {
+				return simpleResult(s.unmuteHooks())
+			})},
+			"ptt_start":  {summary: "Push-to-talk: open the mic and start an utterance", run: s.pttOp("ptt_start")},
+			"ptt_stop":   {summary: "Push-to-talk: end the utterance and close the mic", run: s.pttOp("ptt_stop")},
+			"ptt_toggle": {summary: "Push-to-talk: start if stopped, stop if started", run: s.pttOp("ptt_toggle")},
+			"subscribe":  {summary: "Stream events until disconnect; args: types", open: s.subscribeOp},
+			"use_model": {summary: "Hot-swap the whisper model; args: model", run: func(raw json.RawMessage) (any, error) {
+				var args control.ModelArgs
+				if err := decodeArgs(raw, &args); err != nil {
+					return nil, err
+				}
+				if args.Model == "" {
+					return nil, badRequest("model path required")
+				}
+				return simpleResult(s.useModel(args.Model))
+			}},
+		}
+	})
+	return s.ops
+}
+
+func (s *Server) capabilities() control.Capabilities {
+	ops := s.controlOps()
+	caps := control.Capabilities{Version: control.ProtocolVersion, Ops: make([]control.OpInfo, 0, len(ops))}
+	for name, op := range ops {
+		caps.Ops = append(caps.Ops, control.OpInfo{Name: name, Summary: op.summary})
+	}
+	sort.Slice(caps.Ops, func(i, j int) bool { return caps.Ops[i].Name < caps.Ops[j].Name })
+	return caps
+}
+
+// testHook runs the hook matching text immediately, bypassing the queue and
+// cooldown, like the test-hook command but with the daemon's config and env.
+// A wake word in text is removed as for live speech, but not required.
+func (s *Server) testHook(text string) (any, error) {
+	wake, command := hook.SplitWake(s.cfg, text)
+	hk, idx, slots := hook.Route(s.cfg, text, command)
+	if hk == nil {
+		return nil, &control.Error{Code: control.ErrFailed, Message: "no matching hook configured"}
+	}
+	r := hook.NewRunner(s.cfg, s.logger)
+	res, err := r.Run(context.Background(), hook.Job{Text: command, RawText: text, Wake: wake, Timestamp: time.Now(), Hook: hk, HookIndex: idx, Slots: slots})
+	if err != nil {
+		return nil, err
+	}
+	msg := "ran " + hook.Target(hk)
+	if out := strings.TrimSpace(res.Stdout); out != "" {
+		msg += ": " + out
+	}
+	return control.SimpleResponse{OK: true, Message: msg}, nil
+}
+
+// inject routes text as if ASR had just transcribed it, through the same
+// wake gating, hook selection, cooldown, queue, metrics, and transcript log
+// as live speech. The reply says how it was routed.
+func (s *Server) inject(args control.InjectArgs) control.SimpleResponse {
+	now := time.Now()
+	s.logger.Infof("inject: %q (partial=%v raw=%v)", args.Text, args.Partial, args.Raw)
+	seg := asr.Segment{Text: args.Text, Start: now, End: now, Transcribed: now, Partial: args.Partial}
+	return control.SimpleResponse{OK: true, Message: s.routeSegment(seg, args.Raw)}
+}
+
+func (s *Server) pttOp(op string) func(json.RawMessage) (any, error) {
+	return noArgs(func() (any, error) { return simpleResult(s.ptt(op)) })
+}
+
+// handleRequest decodes one request line and returns the reply to encode:
+// a Response envelope, or for version 0 requests the bare legacy payload.
+// A non-nil stream follows the reply.
+func (s *Server) handleRequest(line []byte) (any, *controlStream) {
+	var req control.Request
+	if err := json.Unmarshal(line, &req); err != nil {
+		s.logger.Warnf("control unmarshal: %v", err)
+		return control.Response{V: control.ProtocolVersion, Error: badRequest("invalid JSON: " + err.Error())}, nil
+	}
+	if req.V > control.ProtocolVersion {
+		return control.Response{V: control.ProtocolVersion, ID: req.ID, Error: &control.Error{
+			Code:    control.ErrUnsupportedVersion,
+			Message: fmt.Sprintf("protocol version %d not supported (max %d)", req.V, control.ProtocolVersion),
+		}}, nil
+	}
+	return s.dispatch(req)
+}
+
+// dispatch runs a decoded request and shapes the reply for its version.
+func (s *Server) dispatch(req control.Request) (any, *controlStream) {
+	result, stream, err := s.runOp(req)
+	if req.V == 0 {
+		if err != nil {
+			return control.SimpleResponse{Message: err.Error()}, nil
+		}
+		return result, stream
+	}
+	resp := control.Response{V: control.ProtocolVersion, ID: req.ID}
+	if err != nil {
+		resp.Error = asControlError(err)
+		return resp, nil
+	}
+	raw, err := json.Marshal(result)
+	if err != nil {
+		if stream != nil {
+			stream.close()
+		}
+		resp.Error = &control.Error{Code: control.ErrFailed, Message: "encode result: " + err.Error()}
+		return resp, nil
+	}
+	resp.OK, resp.Result = true, raw
+	return resp, stream
+}
+
+func (s *Server) runOp(req control.Request) (any, *controlStream, error) {
+	op, ok := s.controlOps()[req.Op]
+	if !ok {
+		s.logger.Debugf("control: unknown op %q", req.Op)
+		return nil, nil, &control.Error{Code: control.ErrUnknownOp, Message: fmt.Sprintf("unknown op %q", req.Op)}
+	}
+	args := req.Args
+	if req.V == 0 && len(args) == 0 {
+		args = legacyArgs(req)
+	}
+	if op.open != nil {
+		return op.open(args)
+	}
+	result, err := op.run(args)
+	return result, nil, err
+}
+
+// legacyArgs lifts version 0 inline arguments into an args object.
+func legacyArgs(req control.Request) json.RawMessage {
+	fields := map[string]any{}
+	if req.Model != "" {
+		fields["model"] = req.Model
+	}
+	if req.DurationSec != 0 {
+		fields["duration_sec"] = req.DurationSec
+	}
+	if len(fields) == 0 {
+		return nil
+	}
+	raw, _ := json.Marshal(fields)
+	return raw
+}
+
+// decodeArgs strictly decodes raw into v; absent args leave v zero.
+func decodeArgs(raw json.RawMessage, v any) error {
+	if len(raw) == 0 || bytes.Equal(raw, []byte("null")) {
+		return nil
+	}
+	dec := json.NewDecoder(bytes.NewReader(raw))
+	dec.DisallowUnknownFields()
+	if err := dec.Decode(v); err != nil {
+		return badRequest("bad args: " + err.Error())
+	}
+	return nil
+}
+
+func noArgs(fn func() (any, error)) func(json.RawMessage) (any, error) {
+	return func(raw json.RawMessage) (any, error) {
+		if err := decodeArgs(raw, &struct{}{}); err != nil {
+			return nil, err
+		}
+		return fn()
+	}
+}
+
+func durationOp(fn func(time.Duration) control.SimpleResponse) func(json.RawMessage) (any, error) {
+	return func(raw json.RawMessage) (any, error) {
+		var args control.DurationArgs
+		if err := decodeArgs(raw, &args); err != nil {
+			return nil, err
+		}
+		if args.DurationSec < 0 {
+			return nil, badRequest("duration_sec must be >= 0")
+		}
+		return simpleResult(fn(durationArg(args.DurationSec)))
+	}
+}
+
+// simpleResult turns a failed SimpleResponse into an error.
+func simpleResult(resp control.SimpleResponse) (any, error) {
+	if !resp.OK {
+		return nil, &control.Error{Code: control.ErrFailed, Message: resp.Message}
+	}
+	return resp, nil
+}
+
+func badRequest(msg string) *control.Error {
+	return &control.Error{Code: control.ErrBadRequest, Message: msg}
+}
+
+func asControlError(err error) *control.Error {
+	var ce *control.Error
+	if errors.As(err, &ce) {
+		return ce
+	}
+	return &control.Error{Code: control.ErrFailed, Message: err.Error()}
+}
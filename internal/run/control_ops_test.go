@@ -0,0 +1,121 @@
+package run
+
+import (
+	"context"
+	"encoding/json"
+	"io"
+	"net"
+	"strings"
+	"testing"
+
+	"brabble/internal/config"
+	"brabble/internal/control"
+)
+
+func TestControlProtocolEnvelope(t *testing.T) {
+	srv := newTestServer(t, nil)
+	srv.setDevice("AirPods")
+	serverConn, clientConn := net.Pipe()
+	go srv.handleConn(context.Background(), serverConn)
+	defer func() { _ = clientConn.Close() }()
+	dec := json.NewDecoder(clientConn)
+
+	send := func(line string) control.Response {
+		t.Helper()
+		if _, err := io.WriteString(clientConn, line+"\n"); err != nil {
+			t.Fatalf("write %s: %v", line, err)
+		}
+		var resp control.Response
+		if err := dec.Decode(&resp); err != nil {
+			t.Fatalf("decode reply to %s: %v", line, err)
+		}
+		return resp
+	}
+
+	// Several requests share one connection, each answered with its id.
+	resp := send(`{"v":1,"id":"a","op":"status"}`)
+	var st control.Status
+	if !resp.OK || resp.ID != "a" || json.Unmarshal(resp.Result, &st) != nil || st.Device != "AirPods" {
+		t.Fatalf("status reply %+v", resp)
+	}
+	resp = send(`{"v":1,"id":"b","op":"capabilities"}`)
+	var caps control.Capabilities
+	if !resp.OK || resp.ID != "b" || json.Unmarshal(resp.Result, &caps) != nil || caps.Version != control.ProtocolVersion {
+		t.Fatalf("capabilities reply %+v", resp)
+	}
+	names := map[string]bool{}
+	for _, op := range caps.Ops {
+		names[op.Name] = true
+	}
+	if !names["status"] || !names["use_model"] || !names["capabilities"] {
+		t.Fatalf("capabilities ops %+v", caps.Ops)
+	}
+
+	for _, tc := range []struct{ line, code string }{
+		{`{"v":1,"id":"c","op":"warp_drive"}`, control.ErrUnknownOp},
+		{`{"v":1,"id":"c","op":"pause","args":{"duration_sec":-1}}`, control.ErrBadRequest},
+		{`{"v":1,"id":"c","op":"status","args":{"verbose":true}}`, control.ErrBadRequest},
+		{`{"v":1,"id":"c","op":"use_model","args":{}}`, control.ErrBadRequest},
+		{`{"v":99,"id":"c","op":"status"}`, control.ErrUnsupportedVersion},
+		{`{not json}`, control.ErrBadRequest},
+		{`{"v":1,"id":"c","op":"resume"}`, ""},
+	} {
+		resp := send(tc.line)
+		if tc.code == "" {
+			if !resp.OK {
+				t.Fatalf("%s: %+v", tc.line, resp.Error)
+			}
+			continue
+		}
+		if resp.OK || resp.Error == nil || resp.Error.Code != tc.code {
+			t.Fatalf("%s: got %+v %+v, want code %s", tc.line, resp, resp.Error, tc.code)
+		}
+	}
+}
+
+func TestLegacyUnknownOpGetsReply(t *testing.T) {
+	srv := newTestServer(t, nil)
+	serverConn, clientConn := net.Pipe()
+	go srv.handleConn(context.Background(), serverConn)
+	defer func() { _ = clientConn.Close() }()
+
+	if _, err := io.WriteString(clientConn, `{"op":"warp_drive"}`+"\n"); err != nil {
+		t.Fatalf("write request: %v", err)
+	}
+	var resp control.SimpleResponse
+	if err := json.NewDecoder(clientConn).Decode(&resp); err != nil {
+		t.Fatalf("decode: %v", err)
+	}
+	if resp.OK || !strings.Contains(resp.Message, "unknown op") {
+		t.Fatalf("reply %+v", resp)
+	}
+}
+
+func TestInjectUsesLivePipeline(t *testing.T) {
+	cfg, _ := config.Default()
+	cfg.Transcripts.Enabled = false
+	cfg.Hooks = []config.HookConfig{{Command: "/bin/true", MinChars: 5}}
+	srv := newTestServer(t, cfg)
+
+	for _, tc := range []struct {
+		args control.InjectArgs
+		want string
+	}{
+		{control.InjectArgs{Text: "lights off please"}, "no wake word"},
+		{control.InjectArgs{Text: "clawd lights off please", Partial: true}, "partial"},
+		{control.InjectArgs{Text: "clawd hi"}, "min_chars"},
+		{control.InjectArgs{Text: "clawd lights off please"}, `queued "lights off please"`},
+		{control.InjectArgs{Text: "lights off please", Raw: true}, `queued "lights off please"`},
+	} {
+		resp := srv.inject(tc.args)
+		if !resp.OK || !strings.Contains(resp.Message, tc.want) {
+			t.Fatalf("inject %+v: %q, want %q", tc.args, resp.Message, tc.want)
+		}
+	}
+	if queuedJobs(srv) != 2 {
+		t.Fatalf("queued %d jobs, want 2", queuedJobs(srv))
+	}
+	if got := srv.metrics.heard.Load(); got != 5 {
+		t.Fatalf("heard metric %d, want 5", got)
+	}
+}
@@ -0,0 +1,53 @@
+package run
+
+import (
+	"sync"
+	"time"
+)
+
+// conversation is the window a hook opens with {"continue": true}: until it
+// closes, segments skip the wake word and go straight to that hook.
+type conversation struct {
+	mu    sync.Mutex
+	hook  int
+	until time.Time
+}
+
+// open (re)starts the window for hook idx for d.
+func (c *conversation) open(idx int, d time.Duration) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.hook = idx
+	c.until = time.Now().Add(d)
+}
+
+// active returns the hook holding the window at now, if any.
+func (c *conversation) active(now time.Time) (int, bool) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if c.until.IsZero() || !now.Before(c.until) {
+		return 0, false
+	}
+	return c.hook, true
+}
+
+// closeFor ends the window if hook idx holds it, as when the hook answers
+// without asking to continue.
+func (c *conversation) closeFor(idx int) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if c.hook == idx {
+		c.until = time.Time{}
+	}
+}
+
+// state returns when the open window closes, or nil.
+func (c *conversation) state() *time.Time {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if c.until.IsZero() || !time.Now().Before(c.until) {
+		return nil
+	}
+	until := c.until
+	return &until
+}
@@ -0,0 +1,62 @@
+package run
+
+import (
+	"context"
+	"testing"
+
+	"brabble/internal/asr"
+	"brabble/internal/config"
+	"brabble/internal/hook"
+)
+
+func TestHookResponseOpensConversationAndReplies(t *testing.T) {
+	cfg, _ := config.Default()
+	cfg.Transcripts.Enabled = false
+	cfg.Wake.ConversationSec = 60
+	reply := 1
+	cfg.Hooks = []config.HookConfig{
+		{
+			Wake:      []string{"clawd"},
+			Command:   "/bin/sh",
+			Args:      []string{"-c", `echo '{"say": "which room?", "continue": true}'`},
+			Output:    config.OutputJSON,
+			ReplyHook: &reply,
+		},
+		{Wake: []string{"speaker"}, Command: "/bin/cat", Payload: config.PayloadStdin},
+	}
+	srv := newTestServer(t, cfg)
+	if err := validateHooks(cfg); err != nil {
+		t.Fatalf("validate: %v", err)
+	}
+
+	if got := srv.routeSegment(asr.Segment{Text: "kitchen"}, false); got != "no wake word" {
+		t.Fatalf("before the conversation: %s", got)
+	}
+	srv.routeSegment(asr.Segment{Text: "clawd turn on the lights"}, false)
+	srv.runHookJob(context.Background(), <-srv.hookPools()[0].queue)
+
+	st := srv.status()
+	if st.ConversationUntil == nil || len(st.HookResults) != 1 || st.HookResults[0].Say != "which room?" || !st.HookResults[0].Continue {
+		t.Fatalf("status %+v", st)
+	}
+	replyJob := <-srv.hookPools()[1].queue
+	if replyJob.Text != "which room?" || !replyJob.Reply {
+		t.Fatalf("reply job %+v", replyJob)
+	}
+	srv.runHookJob(context.Background(), replyJob)
+	if r := srv.status().HookResults[1]; r.Hook != 1 || r.Stdout != "which room?\n" {
+		t.Fatalf("reply result %+v", r)
+	}
+
+	// The follow-up needs no wake word and goes back to hook #0.
+	srv.routeSegment(asr.Segment{Text: "the kitchen please"}, false)
+	if job := <-srv.hookPools()[0].queue; job.Text != "the kitchen please" || job.HookIndex != 0 {
+		t.Fatalf("follow-up %+v", job)
+	}
+
+	// A response without "continue" closes the window.
+	srv.handleResponse(0, hook.Job{Hook: &cfg.Hooks[0]}, &hook.Response{})
+	if got := srv.routeSegment(asr.Segment{Text: "kitchen"}, false); got != "no wake word" {
+		t.Fatalf("after the conversation: %s", got)
+	}
+}
@@ -0,0 +1,97 @@
+package run
+
+import (
+	"context"
+	"encoding/json"
+	"slices"
+	"sync"
+	"time"
+
+	"brabble/internal/control"
+)
+
+// subscriberBuffer is how many events a slow subscriber may lag before
+// events are dropped for it. Publishing never blocks the daemon.
+const subscriberBuffer = 64
+
+// eventBus fans daemon events out to subscribe connections.
+type eventBus struct {
+	mu   sync.Mutex
+	subs map[*subscriber]struct{}
+}
+
+type subscriber struct {
+	ch     chan control.Event
+	types  []string // empty = all
+	missed int64    // guarded by eventBus.mu
+}
+
+func (b *eventBus) subscribe(types []string) (*subscriber, func()) {
+	sub := &subscriber{ch: make(chan control.Event, subscriberBuffer), types: types}
+	b.mu.Lock()
+	if b.subs == nil {
+		b.subs = map[*subscriber]struct{}{}
+	}
+	b.subs[sub] = struct{}{}
+	b.mu.Unlock()
+	return sub, func() {
+		b.mu.Lock()
+		delete(b.subs, sub)
+		b.mu.Unlock()
+	}
+}
+
+// publish delivers ev to every interested subscriber without blocking; a
+// subscriber whose buffer is full misses it and is told on its next event.
+func (b *eventBus) publish(ev control.Event) {
+	if ev.Time.IsZero() {
+		ev.Time = time.Now()
+	}
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	for sub := range b.subs {
+		if len(sub.types) > 0 && !slices.Contains(sub.types, ev.Type) {
+			continue
+		}
+		out := ev
+		out.Missed = sub.missed
+		select {
+		case sub.ch <- out:
+			sub.missed = 0
+		default:
+			sub.missed++
+		}
+	}
+}
+
+// subscribeOp validates the filter and registers the subscriber before the
+// acknowledgement is sent, so no event after the ack is missed. The stream
+// then runs until the client disconnects or the daemon stops.
+func (s *Server) subscribeOp(raw json.RawMessage) (any, *controlStream, error) {
+	var args control.SubscribeArgs
+	if err := decodeArgs(raw, &args); err != nil {
+		return nil, nil, err
+	}
+	for _, t := range args.Types {
+		if !slices.Contains(control.EventTypes, t) {
+			return nil, nil, badRequest("unknown event type " + t)
+		}
+	}
+	sub, unsubscribe := s.events.subscribe(args.Types)
+	st := &controlStream{
+		close: unsubscribe,
+		follow: func(ctx context.Context, enc *json.Encoder) error {
+			for {
+				select {
+				case <-ctx.Done():
+					return nil
+				case ev := <-sub.ch:
+					if err := enc.Encode(ev); err != nil {
+						return err
+					}
+				}
+			}
+		},
+	}
+	return control.SimpleResponse{OK: true, Message: "subscribed"}, st, nil
+}
@@ -0,0 +1,110 @@
+package run
+
+import (
+	"context"
+	"encoding/json"
+	"io"
+	"net"
+	"slices"
+	"testing"
+	"time"
+
+	"brabble/internal/asr"
+	"brabble/internal/config"
+	"brabble/internal/control"
+)
+
+func TestEventBusFiltersAndCountsMissed(t *testing.T) {
+	var bus eventBus
+	all, unsubAll := bus.subscribe(nil)
+	defer unsubAll()
+	finals, unsubFinals := bus.subscribe([]string{control.EventFinal})
+	defer unsubFinals()
+
+	bus.publish(control.Event{Type: control.EventPartial, Text: "p"})
+	bus.publish(control.Event{Type: control.EventFinal, Text: "f"})
+	if len(all.ch) != 2 || len(finals.ch) != 1 {
+		t.Fatalf("all=%d finals=%d", len(all.ch), len(finals.ch))
+	}
+	if ev := <-finals.ch; ev.Text != "f" || ev.Time.IsZero() {
+		t.Fatalf("final event %+v", ev)
+	}
+
+	for i := 0; i < subscriberBuffer+3; i++ { // overflow the unread subscriber
+		bus.publish(control.Event{Type: control.EventFinal})
+	}
+	for len(finals.ch) > 0 {
+		<-finals.ch
+	}
+	bus.publish(control.Event{Type: control.EventFinal, Text: "after"})
+	if ev := <-finals.ch; ev.Missed != 3 || ev.Text != "after" {
+		t.Fatalf("missed=%d text=%q, want 3 after", ev.Missed, ev.Text)
+	}
+}
+
+func TestSubscribeStreamsEventsUntilDisconnect(t *testing.T) {
+	cfg, _ := config.Default()
+	cfg.Wake.Enabled = false
+	cfg.Transcripts.Enabled = false
+	cfg.Hooks = []config.HookConfig{{Type: config.HookTypeWebhook, URL: "http://127.0.0.1:8123/voice"}}
+	srv := newTestServer(t, cfg)
+	serverConn, clientConn := net.Pipe()
+	done := make(chan struct{})
+	go func() {
+		srv.handleConn(context.Background(), serverConn)
+		close(done)
+	}()
+	dec := json.NewDecoder(clientConn)
+
+	req := `{"v":1,"id":"s","op":"subscribe","args":{"types":["final","hook_dispatch","device"]}}`
+	if _, err := io.WriteString(clientConn, req+"\n"); err != nil {
+		t.Fatalf("write: %v", err)
+	}
+	var ack control.Response
+	if err := dec.Decode(&ack); err != nil || !ack.OK || ack.ID != "s" {
+		t.Fatalf("ack %+v %v", ack, err)
+	}
+
+	go func() {
+		srv.handleSegment(context.Background(), asr.Segment{Text: "preview", Partial: true})
+		srv.handleSegment(context.Background(), asr.Segment{Text: "turn the kitchen lights off please"})
+		srv.setDevice("AirPods")
+	}()
+	var got []string
+	for len(got) < 3 {
+		var ev control.Event
+		if err := dec.Decode(&ev); err != nil {
+			t.Fatalf("decode event: %v", err)
+		}
+		got = append(got, ev.Type)
+		if ev.Type == control.EventHookDispatch && ev.Hook != cfg.Hooks[0].URL {
+			t.Fatalf("dispatch event names hook %q, want its URL", ev.Hook)
+		}
+	}
+	want := []string{control.EventFinal, control.EventHookDispatch, control.EventDevice}
+	if !slices.Equal(got, want) {
+		t.Fatalf("events %v, want %v", got, want)
+	}
+
+	_ = clientConn.Close()
+	select {
+	case <-done:
+	case <-time.After(time.Second):
+		t.Fatal("stream did not end on disconnect")
+	}
+	srv.events.mu.Lock()
+	n := len(srv.events.subs)
+	srv.events.mu.Unlock()
+	if n != 0 {
+		t.Fatalf("%d subscribers left after disconnect", n)
+	}
+}
+
+func TestSubscribeRejectsUnknownEventType(t *testing.T) {
+	srv := newTestServer(t, nil)
+	reply, stream := srv.handleRequest([]byte(`{"v":1,"op":"subscribe","args":{"types":["gossip"]}}`))
+	resp := reply.(control.Response)
+	if stream != nil || resp.OK || resp.Error.Code != control.ErrBadRequest {
+		t.Fatalf("reply %+v", resp)
+	}
+}
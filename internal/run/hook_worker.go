@@ -2,22 +2,434 @@ package run
 
 import (
 	"context"
+	"errors"
+	"fmt"
+	"sync/atomic"
 	"time"
+
+	"brabble/internal/config"
+	"brabble/internal/control"
+	"brabble/internal/hook"
+	"brabble/internal/responder"
 )
 
-func (s *Server) hookWorker(ctx context.Context) {
+// defaultHookQueue is a hook's queue size when queue_size is unset.
+const defaultHookQueue = 16
+
+// hookPool is one hook's queue and workers, so a slow or cooling-down hook
+// does not hold up the others.
+type hookPool struct {
+	index   int
+	hook    *config.HookConfig
+	queue   chan hook.Job
+	workers int
+	busy    atomic.Int64
+}
+
+func newHookPools(cfg *config.Config) []*hookPool {
+	hooks := cfg.EffectiveHooks()
+	pools := make([]*hookPool, len(hooks))
+	for i := range hooks {
+		hk := &hooks[i]
+		size := hk.QueueSize
+		if size <= 0 {
+			size = defaultHookQueue
+		}
+		pools[i] = &hookPool{index: i, hook: hk, queue: make(chan hook.Job, size), workers: max(1, hk.Concurrency)}
+	}
+	return pools
+}
+
+// hookPools returns one pool per effective hook, in config order.
+func (s *Server) hookPools() []*hookPool {
+	s.poolsOnce.Do(func() { s.pools = newHookPools(s.cfg) })
+	return s.pools
+}
+
+func (s *Server) hookPool(idx int) *hookPool {
+	pools := s.hookPools()
+	if idx < 0 || idx >= len(pools) {
+		return nil
+	}
+	return pools[idx]
+}
+
+// startHookWorkers runs each hook's configured number of workers.
+func (s *Server) startHookWorkers(ctx context.Context) {
+	for _, p := range s.hookPools() {
+		for i := 0; i < p.workers; i++ {
+			s.goWorker(func() { s.hookWorker(ctx, p) })
+		}
+	}
+}
+
+func (s *Server) hookWorker(ctx context.Context, p *hookPool) {
 	for {
 		select {
 		case <-ctx.Done():
 			return
-		case job := <-s.hookCh:
-			start := time.Now()
-			if err := s.hook.Run(ctx, job); err != nil {
-				s.logger.Errorf("hook: %v", err)
-				continue
-			}
-			s.metrics.lastHook.Store(time.Since(start).Milliseconds())
-			s.metrics.incSent()
+		case job := <-p.queue:
+			p.busy.Add(1)
+			s.runHookJob(ctx, job)
+			p.busy.Add(-1)
+		}
+	}
+}
+
+// errStale marks a failed job whose retries would run past max_latency_ms
+// under stale_policy = "drop".
+var errStale = errors.New("stale; not retried")
+
+// runWithRetries runs job, retrying retryable failures up to the hook's
+// retries with backoff. Each retry is re-checked against the job's deadline
+// as admitLatency would: dropped (errStale) or run marked stale. It returns
+// the last attempt's result and the number of attempts made.
+func (s *Server) runWithRetries(ctx context.Context, job hook.Job) (hook.Result, int, error) {
+	for attempt := 1; ; attempt++ {
+		res, err := s.hook.Run(ctx, job)
+		if err == nil || attempt > job.Hook.Retries || !hook.Retryable(job.Hook, err) {
+			return res, attempt, err
+		}
+		delay := hook.Backoff(job.Hook, attempt-1)
+		s.logger.Warnf("hook #%d attempt %d/%d failed: %v; retrying in %s", job.HookIndex, attempt, job.Hook.Retries+1, err, delay.Round(time.Millisecond))
+		s.metrics.retried.Add(1)
+		select {
+		case <-ctx.Done():
+			return res, attempt, err
+		case <-time.After(delay):
+		}
+		if !s.admitLatency(&job, time.Now()) {
+			return res, attempt, fmt.Errorf("%w: %w", errStale, err)
+		}
+	}
+}
+
+// deadLetter keeps a failed job for `brabble hook dlq replay`.
+func (s *Server) deadLetter(job hook.Job, attempts int, err error) {
+	if s.saveDeadLetter(hook.NewDeadLetter(job, attempts, err)) {
+		s.logger.Warnf("hook #%d gave up after %d attempt(s); saved to %s", job.HookIndex, attempts, s.cfg.Paths.DeadLetterPath)
+	}
+}
+
+// saveDeadLetter appends d to the dead-letter file, if one is configured,
+// and reports whether it was written.
+func (s *Server) saveDeadLetter(d hook.DeadLetter) bool {
+	path := s.cfg.Paths.DeadLetterPath
+	if path == "" {
+		return false
+	}
+	if err := hook.AppendDeadLetter(path, d); err != nil {
+		s.logger.Warnf("dead letter: %v", err)
+		return false
+	}
+	s.metrics.deadLettered.Add(1)
+	return true
+}
+
+// runHookJob runs one step of job: the hook itself, or the step of a chain
+// it has reached. A chain then moves on through the next step's own queue,
+// so each step runs under that hook's concurrency and cooldown; the last
+// step finishes the job.
+func (s *Server) runHookJob(ctx context.Context, job hook.Job) {
+	start := time.Now()
+	// Re-check: the job may have aged out while queued behind others.
+	if !s.admitLatency(&job, start) {
+		s.ackJob(job)
+		return
+	}
+	if job.ChainStep == 0 && !job.Transcribed.IsZero() {
+		s.metrics.dispatchLatency.observe(start.Sub(job.Transcribed))
+	}
+	if job.ChainStep == 0 && !job.Captured.IsZero() {
+		s.metrics.totalLatency.observe(start.Sub(job.Captured))
+	}
+	var (
+		res      hook.Result
+		attempts int
+		err      error
+	)
+	if job.ChainStep > 0 && !s.hook.ShouldRun(job.HookIndex, job.Hook) {
+		err = fmt.Errorf("hook #%d is cooling down", job.HookIndex)
+	} else {
+		res, attempts, err = s.runWithRetries(ctx, job)
+	}
+	s.recordHookResult(job, res, attempts, time.Since(start), err)
+	_, entry := job.ChainEntry()
+	if err != nil {
+		if job.ChainStep > 0 {
+			err = fmt.Errorf("hook #%d chain step %d (hook #%d): %w", entry, job.ChainStep, job.HookIndex, err)
+		}
+		if ctx.Err() != nil && job.JournalID != "" {
+			s.logger.Errorf("hook: %v", err)
+			s.logger.Infof("hook #%d interrupted by shutdown; left in journal for the next start", entry)
+			return
+		}
+		if job.OnFailure() != config.ChainContinue || errors.Is(err, errStale) || ctx.Err() != nil {
+			s.failJob(job, attempts, err)
+			return
+		}
+		s.logger.Warnf("%v; continuing", err)
+		res = hook.Result{}
+	}
+	if !job.LastStep() {
+		s.nextChainStep(job, hook.ChainInput(job.Text, res))
+		return
+	}
+	s.ackJob(job)
+	if !job.Reply {
+		s.earcon(responder.Success)
+	}
+	s.metrics.lastHook.Store(time.Since(start).Milliseconds())
+	s.metrics.incSent()
+	s.handleResponse(entry, job, res.Response)
+}
+
+// failJob ends job after a failure: it is dead-lettered at the step that
+// failed, unless it went stale, and retired from the journal.
+func (s *Server) failJob(job hook.Job, attempts int, err error) {
+	s.logger.Errorf("hook: %v", err)
+	if !errors.Is(err, errStale) {
+		s.deadLetter(job, attempts, err)
+	}
+	s.ackJob(job)
+	if !job.Reply {
+		s.earcon(responder.Failure)
+	}
+}
+
+// nextChainStep queues job's chain at its next step with input as the
+// text. The step's queue is not waited on: a full one fails the job there,
+// so a dead-letter replay can resume it.
+func (s *Server) nextChainStep(job hook.Job, input string) {
+	next, err := job.AtChainStep(s.cfg.EffectiveHooks(), job.ChainStep+1)
+	if err != nil {
+		s.failJob(job, 0, err)
+		return
+	}
+	next.Text = input
+	pool := s.hookPool(next.HookIndex)
+	next.Hook = pool.hook
+	s.journalStep(next)
+	select {
+	case pool.queue <- next:
+		s.events.publish(control.Event{Type: control.EventHookDispatch, Text: next.Text, Hook: hook.Target(pool.hook)})
+	default:
+		_, entry := next.ChainEntry()
+		s.failJob(next, 0, fmt.Errorf("hook #%d chain step %d: hook #%d queue full", entry, next.ChainStep, next.HookIndex))
+	}
+}
+
+// recordHookResult keeps the outcome for status and publishes it as a
+// hook_result event.
+func (s *Server) recordHookResult(job hook.Job, res hook.Result, attempts int, took time.Duration, err error) {
+	r := control.HookResult{
+		Time:       time.Now(),
+		Hook:       job.HookIndex,
+		Target:     hook.Target(job.Hook),
+		Text:       job.Text,
+		DurationMS: took.Milliseconds(),
+		Attempts:   attempts,
+		ExitCode:   res.ExitCode,
+		Status:     res.Status,
+		Stdout:     res.Stdout,
+		Stderr:     res.Stderr,
+		Truncated:  res.Truncated,
+	}
+	if err != nil {
+		r.Error = err.Error()
+	}
+	if res.Response != nil {
+		r.Say, r.Continue = res.Response.Say, res.Response.Continue
+	}
+	s.resultsMu.Lock()
+	s.hookResults = append(s.hookResults, r)
+	if tail := max(s.cfg.UI.StatusTail, 1); len(s.hookResults) > tail {
+		s.hookResults = s.hookResults[len(s.hookResults)-tail:]
+	}
+	s.resultsMu.Unlock()
+	s.events.publish(control.Event{
+		Type:       control.EventHookResult,
+		Text:       job.Text,
+		Hook:       hook.Target(job.Hook),
+		Error:      r.Error,
+		DurationMS: r.DurationMS,
+		Stdout:     r.Stdout,
+		Stderr:     r.Stderr,
+		Say:        r.Say,
+		Continue:   r.Continue,
+	})
+}
+
+func (s *Server) copyHookResults() []control.HookResult {
+	s.resultsMu.Lock()
+	defer s.resultsMu.Unlock()
+	return append([]control.HookResult(nil), s.hookResults...)
+}
+
+// handleResponse acts on the output = "json" response of job, the last
+// step run for hook entry: "continue" opens (or extends) the conversation
+// window with entry, so follow-ups go through its whole chain, anything else
+// closes it, and "say" goes to job's reply_hook, or is spoken by the
+// responder without one.
+func (s *Server) handleResponse(entry int, job hook.Job, resp *hook.Response) {
+	if resp == nil {
+		return
+	}
+	if window := durationArg(s.cfg.Wake.ConversationSec); resp.Continue && window > 0 {
+		s.conv.open(entry, window)
+		s.logger.Infof("hook #%d continues the conversation for %s", entry, window)
+	} else {
+		s.conv.closeFor(entry)
+	}
+	if resp.Say == "" {
+		return
+	}
+	switch {
+	case job.Hook.ReplyHook == nil:
+		s.speak(resp.Say)
+	case !job.Reply:
+		s.sendReply(job, *job.Hook.ReplyHook, resp.Say)
+	}
+}
+
+// earcon plays kind through the responder, if enabled.
+func (s *Server) earcon(kind string) {
+	if s.responder == nil {
+		return
+	}
+	s.responder.Earcon(kind)
+}
+
+// speak hands text to the responder's TTS, if enabled.
+func (s *Server) speak(text string) {
+	if s.responder == nil {
+		return
+	}
+	s.responder.Say(text)
+}
+
+// sendReply queues say for hook idx. Replies skip wake, cooldown, and
+// latency checks: they were not spoken.
+func (s *Server) sendReply(from hook.Job, idx int, say string) {
+	pool := s.hookPool(idx)
+	if pool == nil {
+		s.logger.Warnf("hook #%d reply_hook %d does not exist; reply dropped", from.HookIndex, idx)
+		return
+	}
+	s.enqueueJob(pool, hook.Job{
+		Text:      say,
+		RawText:   say,
+		Timestamp: time.Now(),
+		Hook:      pool.hook,
+		HookIndex: idx,
+		Device:    from.Device,
+		Reply:     true,
+	})
+}
+
+// enqueueJob journals job and queues it for pool without blocking; a full
+// queue drops it.
+func (s *Server) enqueueJob(pool *hookPool, job hook.Job) bool {
+	s.journalJob(&job)
+	select {
+	case pool.queue <- job:
+		s.events.publish(control.Event{Type: control.EventHookDispatch, Text: job.Text, Hook: hook.Target(pool.hook)})
+		return true
+	default:
+		s.ackJob(job)
+		s.metrics.incDropped()
+		s.logger.Warnf("hook #%d queue full, dropping job", pool.index)
+		s.publishError("hook #%d queue full, dropped %q", pool.index, job.Text)
+		return false
+	}
+}
+
+// fanOut queues a copy of job for each of its hook's fan_out targets. Each
+// copy is an independent job under the target's own latency limit, retries,
+// and dead letters.
+func (s *Server) fanOut(job hook.Job, now time.Time) {
+	for _, idx := range job.Hook.FanOut {
+		pool := s.hookPool(idx)
+		if pool == nil {
+			s.logger.Warnf("hook #%d fan_out target %d does not exist", job.HookIndex, idx)
+			continue
 		}
+		c := job
+		c.Hook, c.HookIndex = pool.hook, idx
+		c.StalePolicy, c.Deadline, c.Stale = pool.hook.StalePolicy, time.Time{}, false
+		if pool.hook.MaxLatency > 0 && !c.Captured.IsZero() {
+			c.Deadline = c.Captured.Add(time.Duration(pool.hook.MaxLatency) * time.Millisecond)
+		}
+		if !s.admitLatency(&c, now) {
+			continue
+		}
+		s.enqueueJob(pool, c)
+	}
+}
+
+// journalJob records job in the persistent queue, if enabled. A journal
+// write failure only costs durability, so the job is queued regardless.
+func (s *Server) journalJob(job *hook.Job) {
+	if s.journal == nil {
+		return
+	}
+	if err := s.journal.Append(job); err != nil {
+		s.logger.Warnf("%v; job not persisted", err)
+	}
+}
+
+// journalStep records that job's chain has reached its current step, so a
+// restart resumes there instead of rerunning finished steps.
+func (s *Server) journalStep(job hook.Job) {
+	if s.journal == nil {
+		return
+	}
+	if err := s.journal.Step(job.JournalID, job.ChainStep, job.Text); err != nil {
+		s.logger.Warnf("%v; a restart would rerun finished chain steps", err)
+	}
+}
+
+// ackJob retires job from the persistent queue once it is finished.
+func (s *Server) ackJob(job hook.Job) {
+	if s.journal == nil {
+		return
+	}
+	if err := s.journal.Ack(job.JournalID); err != nil {
+		s.logger.Warnf("%v", err)
+	}
+}
+
+// replayJournal queues jobs a previous run left unfinished. Jobs past
+// max_latency_ms are dropped (or marked) like any other.
+func (s *Server) replayJournal(pending []hook.JournalEntry) {
+	now := time.Now()
+	replayed := 0
+	for _, e := range pending {
+		job, err := e.Job(s.cfg)
+		if errors.Is(err, hook.ErrTargetChanged) {
+			// The config changed under the job; keep it for a deliberate replay.
+			s.logger.Warnf("journal: not replaying %q: %v", e.Text, err)
+			s.saveDeadLetter(e.DeadLetter(err))
+			_ = s.journal.Ack(e.ID)
+			continue
+		}
+		if err != nil {
+			s.logger.Warnf("journal: dropping %q: %v", e.Text, err)
+			_ = s.journal.Ack(e.ID)
+			continue
+		}
+		if !s.admitLatency(&job, now) {
+			s.ackJob(job)
+			continue
+		}
+		select {
+		case s.hookPool(job.HookIndex).queue <- job:
+			replayed++
+		default:
+			s.logger.Warnf("journal: hook #%d queue full; %q stays journaled", job.HookIndex, job.Text)
+		}
+	}
+	if len(pending) > 0 {
+		s.logger.Infof("journal: replayed %d of %d unfinished hook job(s)", replayed, len(pending))
 	}
 }
@@ -0,0 +1,489 @@
+package run
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"brabble/internal/asr"
+	"brabble/internal/config"
+	"brabble/internal/control"
+	"brabble/internal/hook"
+	"brabble/internal/responder"
+)
+
+// drainHookQueues runs queued jobs, chain steps included, until every
+// hook's queue is empty.
+func drainHookQueues(srv *Server) {
+	for ran := true; ran; {
+		ran = false
+		for _, p := range srv.hookPools() {
+			select {
+			case job := <-p.queue:
+				srv.runHookJob(context.Background(), job)
+				ran = true
+			default:
+			}
+		}
+	}
+}
+
+func queuedJobs(srv *Server) int {
+	n := 0
+	for _, p := range srv.hookPools() {
+		n += len(p.queue)
+	}
+	return n
+}
+
+func TestInterleavedRoutingUsesPerHookPools(t *testing.T) {
+	dir := t.TempDir()
+	cfg, _ := config.Default()
+	cfg.Wake.Enabled = false
+	cfg.Transcripts.Enabled = false
+	record := func(name string) config.HookConfig {
+		return config.HookConfig{
+			Wake:        []string{name},
+			Command:     "/bin/sh",
+			Args:        []string{"-c", `printf '%s\n' "$0" >> "` + filepath.Join(dir, name) + `"`},
+			CooldownSec: 60,
+			QueueSize:   3,
+		}
+	}
+	cfg.Hooks = []config.HookConfig{record("alpha"), record("beta")}
+	srv := newTestServer(t, cfg)
+
+	for _, text := range []string{"alpha one", "beta one", "alpha two"} {
+		srv.handleSegment(context.Background(), asr.Segment{Text: text})
+	}
+	pools := srv.hookPools()
+	if len(pools[0].queue) != 2 || len(pools[1].queue) != 1 || cap(pools[0].queue) != 3 {
+		t.Fatalf("queues alpha=%d beta=%d", len(pools[0].queue), len(pools[1].queue))
+	}
+	job := <-pools[0].queue
+	if job.Hook != pools[0].hook || job.HookIndex != 0 {
+		t.Fatalf("job carries hook #%d %p", job.HookIndex, job.Hook)
+	}
+	srv.runHookJob(context.Background(), job)
+
+	// alpha is now cooling down; beta has not run and is unaffected.
+	if msg := srv.routeSegment(asr.Segment{Text: "alpha three"}, false); !strings.HasPrefix(msg, "cooldown; hook #0") {
+		t.Fatalf("alpha after run: %q", msg)
+	}
+	if msg := srv.routeSegment(asr.Segment{Text: "beta two"}, false); !strings.HasPrefix(msg, "queued") {
+		t.Fatalf("beta during alpha cooldown: %q", msg)
+	}
+	srv.runHookJob(context.Background(), <-pools[1].queue)
+
+	for name, want := range map[string]string{"alpha": "alpha one\n", "beta": "beta one\n"} {
+		got, err := os.ReadFile(filepath.Join(dir, name))
+		if err != nil || string(got) != want {
+			t.Fatalf("%s ran %q (%v), want %q", name, got, err, want)
+		}
+	}
+}
+
+func TestHookConcurrencyRunsJobsInParallel(t *testing.T) {
+	dir := t.TempDir()
+	release := filepath.Join(dir, "release")
+	cfg, _ := config.Default()
+	cfg.Wake.Enabled = false
+	cfg.Transcripts.Enabled = false
+	cfg.Hooks = []config.HookConfig{{
+		Command:     "/bin/sh",
+		Args:        []string{"-c", `while [ ! -e "` + release + `" ]; do sleep 0.01; done`},
+		Concurrency: 2,
+	}}
+	cfg.Paths.DeadLetterPath = "" // the jobs are killed at cleanup
+	srv := newTestServer(t, cfg)
+	ctx, cancel := context.WithCancel(context.Background())
+	defer func() {
+		_ = os.WriteFile(release, nil, 0o600)
+		cancel()
+		srv.wg.Wait()
+	}()
+	srv.startHookWorkers(ctx)
+
+	pool := srv.hookPools()[0]
+	if pool.workers != 2 {
+		t.Fatalf("workers = %d", pool.workers)
+	}
+	pool.queue <- hook.Job{Text: "one", Timestamp: time.Now(), Hook: pool.hook}
+	pool.queue <- hook.Job{Text: "two", Timestamp: time.Now(), Hook: pool.hook}
+	deadline := time.Now().Add(2 * time.Second)
+	for pool.busy.Load() != 2 {
+		if time.Now().After(deadline) {
+			t.Fatalf("busy workers = %d, want 2", pool.busy.Load())
+		}
+		time.Sleep(5 * time.Millisecond)
+	}
+}
+
+func TestHookRetriesThenDeadLetters(t *testing.T) {
+	dir := t.TempDir()
+	cfg, _ := config.Default()
+	cfg.Paths.DeadLetterPath = filepath.Join(dir, "dlq.jsonl")
+	count := filepath.Join(dir, "count")
+	// Fails until it has been called three times.
+	flaky := `echo x >> "` + count + `"; [ "$(wc -l < "` + count + `")" -ge 3 ]`
+	cfg.Hooks = []config.HookConfig{
+		{Command: "/bin/sh", Args: []string{"-c", flaky}, Retries: 2, RetryBackoffMS: 1},
+		{Command: "/bin/sh", Args: []string{"-c", "exit 1"}, Retries: 1, RetryBackoffMS: 1},
+	}
+	srv := newTestServer(t, cfg)
+
+	srv.runHookJob(context.Background(), hook.Job{Text: "flaky", Timestamp: time.Now(), Hook: &cfg.Hooks[0]})
+	if srv.metrics.sent.Load() != 1 || srv.metrics.retried.Load() != 2 {
+		t.Fatalf("sent %d retried %d", srv.metrics.sent.Load(), srv.metrics.retried.Load())
+	}
+
+	srv.runHookJob(context.Background(), hook.Job{Text: "doomed", Timestamp: time.Now(), Hook: &cfg.Hooks[1], HookIndex: 1})
+	letters, err := hook.ReadDeadLetters(cfg.Paths.DeadLetterPath)
+	if err != nil || len(letters) != 1 || letters[0].Text != "doomed" || letters[0].Attempts != 2 || letters[0].Hook != 1 {
+		t.Fatalf("dead letters %+v %v", letters, err)
+	}
+	if srv.metrics.deadLettered.Load() != 1 {
+		t.Fatalf("dead-lettered metric %d", srv.metrics.deadLettered.Load())
+	}
+}
+
+func TestWebhookFailuresKeepURLSecretsOut(t *testing.T) {
+	cfg, _ := config.Default()
+	cfg.Paths.DeadLetterPath = filepath.Join(t.TempDir(), "dlq.jsonl")
+	cfg.Hooks = []config.HookConfig{{
+		Type: config.HookTypeWebhook,
+		URL:  "http://127.0.0.1:1/x?key=${SECRET_TOK}", // nothing listens on port 1
+		Env:  map[string]string{"SECRET_TOK": "hunter2"},
+	}}
+	srv := newTestServer(t, cfg)
+	sub, unsub := srv.events.subscribe([]string{control.EventHookResult})
+	defer unsub()
+
+	srv.runHookJob(context.Background(), hook.Job{Text: "lights off", Timestamp: time.Now(), Hook: &cfg.Hooks[0]})
+	results := srv.copyHookResults()
+	if len(results) != 1 || results[0].Error == "" {
+		t.Fatalf("hook results %+v", results)
+	}
+	ev := <-sub.ch
+	letters, err := hook.ReadDeadLetters(cfg.Paths.DeadLetterPath)
+	if err != nil || len(letters) != 1 {
+		t.Fatalf("dead letters %+v %v", letters, err)
+	}
+	for where, text := range map[string]string{"status": results[0].Error, "event": ev.Error, "dead letter": letters[0].Error} {
+		if strings.Contains(text, "hunter2") || !strings.Contains(text, "${SECRET_TOK}") {
+			t.Fatalf("%s error %q", where, text)
+		}
+	}
+}
+
+func TestHookRetriesStopAtDeadline(t *testing.T) {
+	dir := t.TempDir()
+	cfg, _ := config.Default()
+	cfg.Paths.DeadLetterPath = filepath.Join(dir, "dlq.jsonl")
+	count := filepath.Join(dir, "count")
+	failing := `echo "x$BRABBLE_STALE" >> "` + count + `"; exit 1`
+	cfg.Hooks = []config.HookConfig{{Command: "/bin/sh", Args: []string{"-c", failing}, Retries: 5, RetryBackoffMS: 40}}
+	srv := newTestServer(t, cfg)
+	attempts := func() []string {
+		t.Helper()
+		b, _ := os.ReadFile(count)
+		_ = os.Remove(count)
+		return strings.Fields(string(b))
+	}
+
+	job := hook.Job{Text: "late", Timestamp: time.Now(), Hook: &cfg.Hooks[0], Deadline: time.Now().Add(20 * time.Millisecond)}
+	srv.runHookJob(context.Background(), job)
+	if got := attempts(); len(got) != 1 {
+		t.Fatalf("drop policy retried past the deadline: %d attempts", len(got))
+	}
+	if letters, _ := hook.ReadDeadLetters(cfg.Paths.DeadLetterPath); len(letters) != 0 || srv.metrics.stale.Load() != 1 {
+		t.Fatalf("stale job dead-lettered %+v, stale %d", letters, srv.metrics.stale.Load())
+	}
+
+	job.StalePolicy, job.Deadline = config.StaleMark, time.Now().Add(20*time.Millisecond)
+	cfg.Hooks[0].Retries = 1
+	srv.runHookJob(context.Background(), job)
+	if got := attempts(); len(got) != 2 || got[1] != "x1" {
+		t.Fatalf("mark policy attempts %q, want the retry marked stale", got)
+	}
+}
+
+func TestJournalReplaysUnfinishedJobsOnRestart(t *testing.T) {
+	dir := t.TempDir()
+	cfg, _ := config.Default()
+	cfg.Wake.Enabled = false
+	cfg.Transcripts.Enabled = false
+	cfg.Paths.JournalPath = filepath.Join(dir, "journal.jsonl")
+	cfg.Hooks = []config.HookConfig{{Command: "/bin/true", MaxLatency: 60_000}}
+	newServer := func() (*Server, []hook.JournalEntry) {
+		t.Helper()
+		j, pending, err := hook.OpenJournal(cfg.Paths.JournalPath)
+		if err != nil {
+			t.Fatalf("open journal: %v", err)
+		}
+		srv := newTestServer(t, cfg)
+		srv.journal = j
+		return srv, pending
+	}
+
+	srv, _ := newServer()
+	srv.handleSegment(context.Background(), asr.Segment{Text: "finished before the crash", End: time.Now()})
+	srv.handleSegment(context.Background(), asr.Segment{Text: "still queued", End: time.Now()})
+	srv.handleSegment(context.Background(), asr.Segment{Text: "too old by restart", End: time.Now().Add(-59 * time.Second)})
+	srv.runHookJob(context.Background(), <-srv.hookPools()[0].queue)
+	_ = srv.journal.Close() // crash: the other two never ran
+
+	time.Sleep(1100 * time.Millisecond) // the old job passes max_latency_ms
+	srv, pending := newServer()
+	defer func() { _ = srv.journal.Close() }()
+	if len(pending) != 2 {
+		t.Fatalf("pending %+v", pending)
+	}
+	srv.replayJournal(pending)
+	queue := srv.hookPools()[0].queue
+	if len(queue) != 1 {
+		t.Fatalf("replayed %d jobs, want 1", len(queue))
+	}
+	job := <-queue
+	if job.Text != "still queued" || srv.metrics.stale.Load() != 1 {
+		t.Fatalf("replayed %q, stale %d", job.Text, srv.metrics.stale.Load())
+	}
+	srv.runHookJob(context.Background(), job)
+	if n := srv.journal.Pending(); n != 0 {
+		t.Fatalf("%d jobs still pending", n)
+	}
+}
+
+func TestJournalDeadLettersJobsForEditedHooks(t *testing.T) {
+	dir := t.TempDir()
+	cfg, _ := config.Default()
+	cfg.Wake.Enabled = false
+	cfg.Transcripts.Enabled = false
+	cfg.Paths.JournalPath = filepath.Join(dir, "journal.jsonl")
+	cfg.Paths.DeadLetterPath = filepath.Join(dir, "dlq.jsonl")
+	cfg.Hooks = []config.HookConfig{{Command: "/bin/true"}}
+	j, _, err := hook.OpenJournal(cfg.Paths.JournalPath)
+	if err != nil {
+		t.Fatalf("open journal: %v", err)
+	}
+	srv := newTestServer(t, cfg)
+	srv.journal = j
+	srv.handleSegment(context.Background(), asr.Segment{Text: "meant for true", End: time.Now()})
+	_ = j.Close()
+
+	cfg.Hooks[0].Command = "/bin/false" // edited while the daemon was down
+	j, pending, err := hook.OpenJournal(cfg.Paths.JournalPath)
+	if err != nil || len(pending) != 1 {
+		t.Fatalf("reopen: %+v %v", pending, err)
+	}
+	defer func() { _ = j.Close() }()
+	srv = newTestServer(t, cfg)
+	srv.journal = j
+	srv.replayJournal(pending)
+	if n := len(srv.hookPools()[0].queue); n != 0 || j.Pending() != 0 {
+		t.Fatalf("queued %d, pending %d; want the job dead-lettered", n, j.Pending())
+	}
+	letters, err := hook.ReadDeadLetters(cfg.Paths.DeadLetterPath)
+	if err != nil || len(letters) != 1 || letters[0].Target != "/bin/true" || letters[0].Text != "meant for true" {
+		t.Fatalf("dead letters %+v %v", letters, err)
+	}
+}
+
+func TestResponderFollowsWakeAndHookResults(t *testing.T) {
+	log := filepath.Join(t.TempDir(), "played")
+	cfg, _ := config.Default()
+	cfg.Transcripts.Enabled = false
+	cfg.Responder.Enabled = true
+	cfg.Responder.Player = "/bin/sh"
+	cfg.Responder.PlayerArgs = []string{"-c", `echo "$1" >> "` + log + `"`, "sh"}
+	cfg.Responder.WakeSound = "wake"
+	cfg.Responder.SuccessSound = "ok"
+	cfg.Responder.FailureSound = "fail"
+	cfg.Responder.TTSCommand = "/bin/sh"
+	cfg.Responder.TTSArgs = []string{"-c", `echo "say $2" >> "` + log + `"`, "sh"}
+	cfg.Hooks = []config.HookConfig{
+		{Wake: []string{"clawd"}, Command: "/bin/sh", Args: []string{"-c", `echo '{"say": "done"}'`}, Output: config.OutputJSON},
+		{Wake: []string{"broken"}, Command: "/bin/sh", Args: []string{"-c", "exit 1"}},
+	}
+	cfg.Paths.DeadLetterPath = ""
+	srv := newTestServer(t, cfg)
+	srv.responder = responder.New(cfg, srv.logger, srv.duckCapture)
+
+	srv.routeSegment(asr.Segment{Text: "clawd lights off", Partial: true}, false)
+	srv.routeSegment(asr.Segment{Text: "clawd lights off"}, false)
+	srv.runHookJob(context.Background(), <-srv.hookPools()[0].queue)
+	srv.runHookJob(context.Background(), hook.Job{Text: "x", Timestamp: time.Now(), Hook: &cfg.Hooks[1], HookIndex: 1})
+
+	ctx, cancel := context.WithCancel(context.Background())
+	done := make(chan struct{})
+	go func() { srv.responder.Run(ctx); close(done) }()
+	want := "wake\nok\nsay done\nfail\n"
+	deadline := time.Now().Add(5 * time.Second)
+	for {
+		played, _ := os.ReadFile(log)
+		if string(played) == want {
+			break
+		}
+		if time.Now().After(deadline) {
+			t.Fatalf("played %q, want %q", played, want)
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+	cancel()
+	<-done
+}
+
+func TestFanOutAndChain(t *testing.T) {
+	dir := t.TempDir()
+	out := filepath.Join(dir, "out")
+	cfg, _ := config.Default()
+	cfg.Transcripts.Enabled = false
+	cfg.Paths.DeadLetterPath = filepath.Join(dir, "dlq.jsonl")
+	sh := func(script string) config.HookConfig {
+		return config.HookConfig{Command: "/bin/sh", Args: []string{"-c", script, "sh"}}
+	}
+	upper := sh(`echo "$1" | tr a-z A-Z`)
+	upper.Wake = []string{"clawd"}
+	upper.FanOut = []int{4}
+	upper.Chain = []config.ChainStep{{Hook: 1, OnFailure: config.ChainContinue}, {Hook: 2}}
+	flaky := sh("exit 1")
+	last := sh(`printf '%s' "$1" > "` + out + `"; exit 1`)
+	strict := sh(`echo "$1" | tr a-z A-Z`)
+	strict.Wake = []string{"strict"}
+	strict.Chain = []config.ChainStep{{Hook: 2}}
+	cfg.Hooks = []config.HookConfig{upper, flaky, last, strict, sh("true")}
+	if err := validateHooks(cfg); err != nil {
+		t.Fatalf("validate: %v", err)
+	}
+	srv := newTestServer(t, cfg)
+
+	if got := srv.routeSegment(asr.Segment{Text: "clawd lights off"}, false); !strings.Contains(got, "1 fan-out") {
+		t.Fatalf("route: %s", got)
+	}
+	if copied := <-srv.hookPools()[4].queue; copied.Text != "lights off" || copied.HookIndex != 4 {
+		t.Fatalf("fan-out copy %+v", copied)
+	}
+	// upper → flaky (fails, continues with the same input) → last, each
+	// step through its own hook's queue.
+	srv.runHookJob(context.Background(), <-srv.hookPools()[0].queue)
+	if step := srv.hookPools()[1].queue; len(step) != 1 {
+		t.Fatalf("first step queued %d jobs for its hook", len(step))
+	}
+	drainHookQueues(srv)
+	if got, _ := os.ReadFile(out); string(got) != "LIGHTS OFF" {
+		t.Fatalf("last step got %q", got)
+	}
+	results := srv.status().HookResults
+	if len(results) != 3 || results[1].Hook != 1 || results[1].Error == "" || results[2].Text != "LIGHTS OFF" {
+		t.Fatalf("results %+v", results)
+	}
+	// The failing last step (on_failure = stop) fails the job, which is
+	// dead-lettered under the entry at that step, with the step's input.
+	letters, err := hook.ReadDeadLetters(cfg.Paths.DeadLetterPath)
+	if err != nil || len(letters) != 1 || letters[0].Hook != 0 || letters[0].ChainStep != 2 || letters[0].Text != "LIGHTS OFF" {
+		t.Fatalf("dead letters %+v %v", letters, err)
+	}
+	if job, err := letters[0].Job(cfg, false); err != nil || job.HookIndex != 2 || job.ChainStep != 2 || job.EntryIndex != 0 {
+		t.Fatalf("replay job %+v %v", job, err)
+	}
+
+	// A chain's last step asking to continue keeps the conversation with
+	// the entry, so the follow-up runs the whole chain again.
+	cfg.Wake.ConversationSec = 60
+	cfg.Wake.Aliases = append(cfg.Wake.Aliases, "strict")
+	cfg.Hooks[2] = sh(`echo '{"continue": true}'`)
+	cfg.Hooks[2].Output = config.OutputJSON
+	srv.routeSegment(asr.Segment{Text: "strict hello"}, false)
+	drainHookQueues(srv)
+	srv.routeSegment(asr.Segment{Text: "and again"}, false)
+	if n := len(srv.hookPools()[3].queue); n != 1 {
+		t.Fatalf("follow-up queued %d for the chain entry", n)
+	}
+
+	cfg.Hooks[4].Chain = []config.ChainStep{{Hook: 0}}
+	if err := validateHooks(cfg); err == nil {
+		t.Fatal("fan_out to a hook with its own chain accepted")
+	}
+	cfg.Hooks[4].Chain = nil
+	cfg.Hooks[3].Chain = []config.ChainStep{{Hook: 3}}
+	if err := validateHooks(cfg); err == nil {
+		t.Fatal("chain to itself accepted")
+	}
+}
+
+func TestChainStepsKeepTheirHooksCooldown(t *testing.T) {
+	dir := t.TempDir()
+	cfg, _ := config.Default()
+	cfg.Wake.Enabled = false
+	cfg.Transcripts.Enabled = false
+	cfg.Paths.DeadLetterPath = filepath.Join(dir, "dlq.jsonl")
+	entry := config.HookConfig{Command: "/bin/true", Chain: []config.ChainStep{{Hook: 1}}}
+	notify := config.HookConfig{Command: "/bin/true", CooldownSec: 60}
+	cfg.Hooks = []config.HookConfig{entry, notify}
+	srv := newTestServer(t, cfg)
+
+	for range 2 {
+		srv.handleSegment(context.Background(), asr.Segment{Text: "lights off"})
+		drainHookQueues(srv)
+	}
+	// The second run reaches the step while its hook cools down: the job
+	// fails there and can be replayed from that step.
+	letters, err := hook.ReadDeadLetters(cfg.Paths.DeadLetterPath)
+	if err != nil || len(letters) != 1 || letters[0].ChainStep != 1 || !strings.Contains(letters[0].Error, "cooling down") {
+		t.Fatalf("dead letters %+v %v", letters, err)
+	}
+	if srv.metrics.sent.Load() != 1 {
+		t.Fatalf("sent %d, want 1", srv.metrics.sent.Load())
+	}
+}
+
+func TestJournaledChainResumesAfterFinishedSteps(t *testing.T) {
+	dir := t.TempDir()
+	ran := filepath.Join(dir, "ran")
+	cfg, _ := config.Default()
+	cfg.Wake.Enabled = false
+	cfg.Transcripts.Enabled = false
+	cfg.Paths.JournalPath = filepath.Join(dir, "journal.jsonl")
+	cfg.Paths.DeadLetterPath = ""
+	step := func(name string) config.HookConfig {
+		return config.HookConfig{Command: "/bin/sh", Args: []string{"-c", `echo "` + name + ` $1" >> "` + ran + `"; echo "$1 ` + name + `"`, "sh"}}
+	}
+	head := step("head")
+	head.Chain = []config.ChainStep{{Hook: 1}, {Hook: 2}}
+	cfg.Hooks = []config.HookConfig{head, step("middle"), step("tail")}
+	j, _, err := hook.OpenJournal(cfg.Paths.JournalPath)
+	if err != nil {
+		t.Fatalf("open journal: %v", err)
+	}
+	srv := newTestServer(t, cfg)
+	srv.journal = j
+	srv.handleSegment(context.Background(), asr.Segment{Text: "go", End: time.Now()})
+	job := <-srv.hookPools()[0].queue
+	// The head and middle steps ran before a crash.
+	if err := j.Step(job.JournalID, 2, "go head middle"); err != nil {
+		t.Fatalf("step: %v", err)
+	}
+	_ = j.Close()
+
+	j, pending, err := hook.OpenJournal(cfg.Paths.JournalPath)
+	if err != nil || len(pending) != 1 || pending[0].ChainStep != 2 {
+		t.Fatalf("reopen: %+v %v", pending, err)
+	}
+	defer func() { _ = j.Close() }()
+	srv = newTestServer(t, cfg)
+	srv.journal = j
+	srv.replayJournal(pending)
+	if len(srv.hookPools()[0].queue) != 0 {
+		t.Fatal("resumed chain queued for its entry")
+	}
+	srv.runHookJob(context.Background(), <-srv.hookPools()[2].queue)
+	if got, _ := os.ReadFile(ran); string(got) != "tail go head middle\n" {
+		t.Fatalf("resumed chain ran %q, want only the tail step", got)
+	}
+	if j.Pending() != 0 {
+		t.Fatalf("%d jobs still pending", j.Pending())
+	}
+}
@@ -2,9 +2,14 @@ package run
 
 import (
 	"fmt"
+	"io"
 	"net/http"
+	"strconv"
+	"sync"
 	"sync/atomic"
 	"time"
+
+	"brabble/internal/asr"
 )
 
 type metrics struct {
@@ -12,7 +17,17 @@ type metrics struct {
 	sent     atomic.Int64
 	skipped  atomic.Int64
 	dropped  atomic.Int64
+	stale    atomic.Int64 // jobs past max_latency_ms (dropped or marked)
 	lastHook atomic.Int64 // ms
+
+	retried      atomic.Int64 // hook attempts that were retried
+	deadLettered atomic.Int64 // hook jobs written to the dead-letter file
+
+	// Latency from end of speech: to transcript (asr), transcript to hook
+	// start (dispatch), and end to end (total).
+	asrLatency      histogram
+	dispatchLatency histogram
+	totalLatency    histogram
 }
 
 func (m *metrics) reset() {
@@ -20,13 +35,95 @@ func (m *metrics) reset() {
 	m.sent.Store(0)
 	m.skipped.Store(0)
 	m.dropped.Store(0)
+	m.stale.Store(0)
 	m.lastHook.Store(0)
+	m.retried.Store(0)
+	m.deadLettered.Store(0)
+	m.asrLatency.reset()
+	m.dispatchLatency.reset()
+	m.totalLatency.reset()
 }
 
 func (m *metrics) incHeard()   { m.heard.Add(1) }
 func (m *metrics) incSent()    { m.sent.Add(1) }
 func (m *metrics) incSkipped() { m.skipped.Add(1) }
 func (m *metrics) incDropped() { m.dropped.Add(1) }
+func (m *metrics) incStale()   { m.stale.Add(1) }
+
+// latencyBuckets are histogram upper bounds in seconds.
+var latencyBuckets = []float64{0.05, 0.1, 0.25, 0.5, 1, 2, 5, 10, 30}
+
+// histogram is a fixed-bucket Prometheus-style histogram.
+type histogram struct {
+	mu     sync.Mutex
+	counts []int64 // one per latencyBuckets entry plus +Inf; see buckets
+	sum    float64
+	total  int64
+}
+
+func (h *histogram) reset() {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	h.counts = nil
+	h.sum = 0
+	h.total = 0
+}
+
+// buckets returns the per-bucket counts, sizing them from latencyBuckets on
+// first use so the two cannot drift apart. Callers hold h.mu.
+func (h *histogram) buckets() []int64 {
+	if h.counts == nil {
+		h.counts = make([]int64, len(latencyBuckets)+1)
+	}
+	return h.counts
+}
+
+func (h *histogram) observe(d time.Duration) {
+	if d < 0 {
+		return
+	}
+	v := d.Seconds()
+	i := 0
+	for i < len(latencyBuckets) && v > latencyBuckets[i] {
+		i++
+	}
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	h.buckets()[i]++
+	h.sum += v
+	h.total++
+}
+
+// write emits cumulative buckets in Prometheus text format.
+func (h *histogram) write(w io.Writer, name, stage string) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	counts := h.buckets()
+	var cum int64
+	for i, le := range latencyBuckets {
+		cum += counts[i]
+		_, _ = fmt.Fprintf(w, "%s_bucket{stage=%q,le=%q} %d\n", name, stage, strconv.FormatFloat(le, 'g', -1, 64), cum)
+	}
+	_, _ = fmt.Fprintf(w, "%s_bucket{stage=%q,le=\"+Inf\"} %d\n", name, stage, h.total)
+	_, _ = fmt.Fprintf(w, "%s_sum{stage=%q} %g\n", name, stage, h.sum)
+	_, _ = fmt.Fprintf(w, "%s_count{stage=%q} %d\n", name, stage, h.total)
+}
+
+// backlogReporter is implemented by recognizers that expose their
+// capture→ASR queue.
+type backlogReporter interface {
+	Backlog() asr.BacklogStats
+}
+
+// modelStatsReporter is implemented by recognizers that track model loads.
+type modelStatsReporter interface {
+	ModelStats() asr.ModelStats
+}
+
+// workerReporter is implemented by recognizers with transcription workers.
+type workerReporter interface {
+	WorkerStats() []asr.WorkerStats
+}
 
 func (s *Server) metricsServe(ctxDone <-chan struct{}, addr string, logger interface {
 	Infof(string, ...any)
@@ -41,9 +138,58 @@ func (s *Server) metricsServe(ctxDone <-chan struct{}, addr string, logger inter
 		write("brabble_hooks_sent_total %d\n", s.metrics.sent.Load())
 		write("brabble_hooks_skipped_total %d\n", s.metrics.skipped.Load())
 		write("brabble_hooks_dropped_total %d\n", s.metrics.dropped.Load())
-		write("brabble_hook_queue_depth %d\n", len(s.hookCh))
-		write("brabble_hook_queue_capacity %d\n", cap(s.hookCh))
+		depth, capacity := 0, 0
+		for _, p := range s.hookPools() {
+			depth += len(p.queue)
+			capacity += cap(p.queue)
+		}
+		write("brabble_hook_queue_depth %d\n", depth)
+		write("brabble_hook_queue_capacity %d\n", capacity)
+		for _, p := range s.hookPools() {
+			write("brabble_hook_pool_queue_depth{hook=\"%d\"} %d\n", p.index, len(p.queue))
+			write("brabble_hook_pool_busy_workers{hook=\"%d\"} %d\n", p.index, p.busy.Load())
+			write("brabble_hook_pool_workers{hook=\"%d\"} %d\n", p.index, p.workers)
+		}
 		write("brabble_hook_last_ms %d\n", s.metrics.lastHook.Load())
+		write("brabble_hooks_stale_total %d\n", s.metrics.stale.Load())
+		write("brabble_hook_retries_total %d\n", s.metrics.retried.Load())
+		write("brabble_hooks_dead_lettered_total %d\n", s.metrics.deadLettered.Load())
+		if s.journal != nil {
+			write("brabble_hook_journal_pending %d\n", s.journal.Pending())
+		}
+		if b, ok := s.recognizer.Load().(backlogReporter); ok {
+			st := b.Backlog()
+			write("brabble_asr_queue_depth %d\n", st.Depth)
+			write("brabble_asr_queue_capacity %d\n", st.Capacity)
+			write("brabble_asr_dropped_total{kind=\"partial\"} %d\n", st.DroppedPartials)
+			write("brabble_asr_dropped_total{kind=\"final\"} %d\n", st.DroppedFinals)
+		}
+		if wr, ok := s.recognizer.Load().(workerReporter); ok {
+			for i, st := range wr.WorkerStats() {
+				write("brabble_asr_worker_busy_seconds_total{worker=\"%d\"} %g\n", i, st.Busy.Seconds())
+				write("brabble_asr_worker_segments_total{worker=\"%d\"} %d\n", i, st.Segments)
+			}
+		}
+		if mr, ok := s.recognizer.Load().(modelStatsReporter); ok {
+			st := mr.ModelStats()
+			loaded := 0
+			if st.Loaded {
+				loaded = 1
+			}
+			write("# TYPE brabble_asr_model_loaded gauge\n")
+			write("brabble_asr_model_loaded %d\n", loaded)
+			write("# TYPE brabble_asr_model_loads_total counter\n")
+			write("brabble_asr_model_loads_total %d\n", st.Loads)
+			write("# TYPE brabble_asr_model_unloads_total counter\n")
+			write("brabble_asr_model_unloads_total %d\n", st.Unloads)
+			write("# TYPE brabble_asr_model_last_load_seconds gauge\n")
+			write("brabble_asr_model_last_load_seconds %g\n", st.LastLoad.Seconds())
+		}
+		// The histogram's samples must directly follow its TYPE line.
+		write("# TYPE brabble_latency_seconds histogram\n")
+		s.metrics.asrLatency.write(w, "brabble_latency_seconds", "asr")
+		s.metrics.dispatchLatency.write(w, "brabble_latency_seconds", "dispatch")
+		s.metrics.totalLatency.write(w, "brabble_latency_seconds", "total")
 		lastHeard := s.lastHeard.Load()
 		write("brabble_last_heard_unix_nano %d\n", lastHeard)
 	})
@@ -0,0 +1,27 @@
+package run
+
+import (
+	"bytes"
+	"testing"
+	"time"
+)
+
+func TestHistogramWritesCumulativeBuckets(t *testing.T) {
+	var h histogram
+	h.observe(30 * time.Millisecond)
+	h.observe(700 * time.Millisecond)
+	h.observe(time.Minute)
+	var out bytes.Buffer
+	h.write(&out, "brabble_latency_seconds", "total")
+	for _, want := range []string{
+		`brabble_latency_seconds_bucket{stage="total",le="0.05"} 1`,
+		`brabble_latency_seconds_bucket{stage="total",le="1"} 2`,
+		`brabble_latency_seconds_bucket{stage="total",le="30"} 2`,
+		`brabble_latency_seconds_bucket{stage="total",le="+Inf"} 3`,
+		`brabble_latency_seconds_count{stage="total"} 3`,
+	} {
+		if !bytes.Contains(out.Bytes(), []byte(want)) {
+			t.Fatalf("missing %q in:\n%s", want, out.String())
+		}
+	}
+}
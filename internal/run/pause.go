@@ -0,0 +1,149 @@
+package run
+
+import (
+	"fmt"
+	"sync"
+	"time"
+
+	"brabble/internal/control"
+)
+
+// timedFlag is an on/off switch with an optional expiry.
+type timedFlag struct {
+	mu    sync.Mutex
+	on    bool
+	until time.Time // zero = until cleared
+	timer *time.Timer
+	gen   uint64 // invalidates timers from earlier sets
+}
+
+// set turns the flag on for d (0 = indefinitely); onExpire runs if the
+// period ends before the flag is cleared or set again.
+func (f *timedFlag) set(d time.Duration, onExpire func()) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	f.stopTimerLocked()
+	f.on = true
+	f.until = time.Time{}
+	if d <= 0 {
+		return
+	}
+	f.until = time.Now().Add(d)
+	gen := f.gen
+	f.timer = time.AfterFunc(d, func() {
+		f.mu.Lock()
+		if f.gen != gen || !f.on {
+			f.mu.Unlock()
+			return
+		}
+		f.on = false
+		f.until = time.Time{}
+		f.timer = nil
+		f.mu.Unlock()
+		onExpire()
+	})
+}
+
+// clear turns the flag off and reports whether it was on.
+func (f *timedFlag) clear() bool {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	f.stopTimerLocked()
+	was := f.on
+	f.on = false
+	f.until = time.Time{}
+	return was
+}
+
+func (f *timedFlag) stopTimerLocked() {
+	f.gen++
+	if f.timer != nil {
+		f.timer.Stop()
+		f.timer = nil
+	}
+}
+
+// state returns whether the flag is on and, if it expires, when.
+func (f *timedFlag) state() (bool, *time.Time) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	if !f.on || f.until.IsZero() {
+		return f.on, nil
+	}
+	until := f.until
+	return true, &until
+}
+
+// capturePauser is implemented by recognizers that can release the mic
+// without unloading the model.
+type capturePauser interface {
+	SetPaused(paused bool)
+}
+
+// captureDucker is implemented by recognizers that can ignore the mic
+// without closing it.
+type captureDucker interface {
+	SetDucked(ducked bool)
+}
+
+// duckCapture mutes transcription while the responder plays.
+func (s *Server) duckCapture(ducked bool) {
+	if d, ok := s.recognizer.Load().(captureDucker); ok {
+		d.SetDucked(ducked)
+	}
+}
+
+// pause stops listening for d (0 = until resume).
+func (s *Server) pause(d time.Duration) control.SimpleResponse {
+	p, ok := s.recognizer.Load().(capturePauser)
+	if !ok {
+		return control.SimpleResponse{Message: "recognizer not running or cannot pause"}
+	}
+	s.paused.set(d, func() {
+		s.logger.Info("pause expired; resuming capture")
+		p.SetPaused(false)
+	})
+	p.SetPaused(true)
+	return control.SimpleResponse{OK: true, Message: "paused" + forDuration(d)}
+}
+
+func (s *Server) resume() control.SimpleResponse {
+	if !s.paused.clear() {
+		return control.SimpleResponse{OK: true, Message: "not paused"}
+	}
+	if p, ok := s.recognizer.Load().(capturePauser); ok {
+		p.SetPaused(false)
+	}
+	return control.SimpleResponse{OK: true, Message: "resumed"}
+}
+
+// muteHooks keeps transcribing but stops dispatching hooks for d.
+func (s *Server) muteHooks(d time.Duration) control.SimpleResponse {
+	s.muted.set(d, func() { s.logger.Info("hook mute expired") })
+	s.logger.Infof("hooks muted%s", forDuration(d))
+	return control.SimpleResponse{OK: true, Message: "hooks muted" + forDuration(d)}
+}
+
+func (s *Server) unmuteHooks() control.SimpleResponse {
+	if !s.muted.clear() {
+		return control.SimpleResponse{OK: true, Message: "hooks not muted"}
+	}
+	s.logger.Info("hooks unmuted")
+	return control.SimpleResponse{OK: true, Message: "hooks unmuted"}
+}
+
+func (s *Server) hooksMuted() bool {
+	on, _ := s.muted.state()
+	return on
+}
+
+func forDuration(d time.Duration) string {
+	if d <= 0 {
+		return ""
+	}
+	return fmt.Sprintf(" for %s", d)
+}
+
+func durationArg(sec float64) time.Duration {
+	return time.Duration(sec * float64(time.Second))
+}
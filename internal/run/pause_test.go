@@ -0,0 +1,95 @@
+package run
+
+import (
+	"context"
+	"sync"
+	"testing"
+	"time"
+
+	"brabble/internal/asr"
+	"brabble/internal/config"
+)
+
+type pauseRecognizer struct {
+	swapRecognizer
+	mu     sync.Mutex
+	paused bool
+}
+
+func (r *pauseRecognizer) SetPaused(p bool) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.paused = p
+}
+
+func (r *pauseRecognizer) isPaused() bool {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	return r.paused
+}
+
+func TestPauseResumeAndAutoExpiry(t *testing.T) {
+	srv := newTestServer(t, nil)
+	if resp := srv.pause(0); resp.OK {
+		t.Fatal("pause without a recognizer should fail")
+	}
+	rec := &pauseRecognizer{}
+	srv.recognizer.Store(asr.Recognizer(rec))
+
+	if resp := srv.pause(0); !resp.OK || !rec.isPaused() || !srv.status().Paused {
+		t.Fatalf("pause: %+v paused=%v", resp, rec.isPaused())
+	}
+	if resp := srv.resume(); !resp.OK || rec.isPaused() || srv.status().Paused {
+		t.Fatalf("resume: %+v paused=%v", resp, rec.isPaused())
+	}
+
+	srv.pause(30 * time.Millisecond)
+	if st := srv.status(); !st.Paused || st.PausedUntil == nil {
+		t.Fatalf("timed pause status=%+v", st)
+	}
+	deadline := time.Now().Add(time.Second)
+	for rec.isPaused() && time.Now().Before(deadline) {
+		time.Sleep(5 * time.Millisecond)
+	}
+	if rec.isPaused() || srv.status().Paused {
+		t.Fatal("pause did not expire")
+	}
+}
+
+func TestTimedFlagResetCancelsEarlierExpiry(t *testing.T) {
+	var f timedFlag
+	expired := make(chan struct{}, 2)
+	f.set(20*time.Millisecond, func() { expired <- struct{}{} })
+	f.set(0, func() { expired <- struct{}{} }) // re-set indefinitely
+	select {
+	case <-expired:
+		t.Fatal("stale timer expired the flag")
+	case <-time.After(60 * time.Millisecond):
+	}
+	if on, until := f.state(); !on || until != nil {
+		t.Fatalf("state on=%v until=%v", on, until)
+	}
+}
+
+func TestMutedHooksSkipDispatch(t *testing.T) {
+	cfg, _ := config.Default()
+	cfg.Wake.Enabled = false
+	cfg.Hooks = []config.HookConfig{{Command: "/bin/true"}}
+	srv := newTestServer(t, cfg)
+	cfg.Transcripts.Enabled = false
+	seg := asr.Segment{Text: "turn the kitchen lights off please"}
+
+	srv.muteHooks(0)
+	srv.handleSegment(context.Background(), seg)
+	if queuedJobs(srv) != 0 {
+		t.Fatal("muted hooks still dispatched")
+	}
+	if !srv.status().HooksMuted {
+		t.Fatal("status does not report mute")
+	}
+	srv.unmuteHooks()
+	srv.handleSegment(context.Background(), seg)
+	if queuedJobs(srv) != 1 {
+		t.Fatal("unmuted hooks did not dispatch")
+	}
+}
@@ -0,0 +1,39 @@
+package run
+
+import "brabble/internal/control"
+
+// pushToTalker is implemented by recognizers that support wake.mode =
+// "push_to_talk".
+type pushToTalker interface {
+	SetTalking(talking bool) (changed bool, err error)
+	Talking() bool
+}
+
+// ptt handles ptt_start, ptt_stop, and ptt_toggle.
+func (s *Server) ptt(op string) control.SimpleResponse {
+	p, ok := s.recognizer.Load().(pushToTalker)
+	if !ok {
+		return control.SimpleResponse{Message: "recognizer not running or lacks push-to-talk"}
+	}
+	talking := op == "ptt_start"
+	if op == "ptt_toggle" {
+		talking = !p.Talking()
+	}
+	changed, err := p.SetTalking(talking)
+	if err != nil {
+		return control.SimpleResponse{Message: err.Error()}
+	}
+	msg := "talking"
+	if !talking {
+		msg = "released"
+	}
+	if !changed {
+		msg = "already " + msg
+	}
+	return control.SimpleResponse{OK: true, Message: msg}
+}
+
+func (s *Server) talking() bool {
+	p, ok := s.recognizer.Load().(pushToTalker)
+	return ok && p.Talking()
+}
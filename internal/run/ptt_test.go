@@ -0,0 +1,50 @@
+package run
+
+import (
+	"sync"
+	"testing"
+
+	"brabble/internal/asr"
+)
+
+type pttRecognizer struct {
+	swapRecognizer
+	mu      sync.Mutex
+	talking bool
+}
+
+func (r *pttRecognizer) SetTalking(t bool) (bool, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	changed := r.talking != t
+	r.talking = t
+	return changed, nil
+}
+
+func (r *pttRecognizer) Talking() bool {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	return r.talking
+}
+
+func TestPTTOps(t *testing.T) {
+	srv := newTestServer(t, nil)
+	rec := &pttRecognizer{}
+	srv.recognizer.Store(asr.Recognizer(rec))
+
+	if resp := srv.ptt("ptt_start"); !resp.OK || !rec.Talking() || !srv.status().Talking {
+		t.Fatalf("start: %+v", resp)
+	}
+	if resp := srv.ptt("ptt_start"); !resp.OK || resp.Message != "already talking" {
+		t.Fatalf("repeat start: %+v", resp)
+	}
+	if resp := srv.ptt("ptt_toggle"); !resp.OK || rec.Talking() {
+		t.Fatalf("toggle off: %+v", resp)
+	}
+	if resp := srv.ptt("ptt_toggle"); !resp.OK || !rec.Talking() {
+		t.Fatalf("toggle on: %+v", resp)
+	}
+	if resp := srv.ptt("ptt_stop"); !resp.OK || rec.Talking() {
+		t.Fatalf("stop: %+v", resp)
+	}
+}
@@ -2,6 +2,7 @@ package run
 
 import (
 	"bufio"
+	"bytes"
 	"context"
 	"encoding/json"
 	"errors"
@@ -20,21 +21,38 @@ import (
 	"brabble/internal/control"
 	"brabble/internal/hook"
 	"brabble/internal/logging"
+	"brabble/internal/responder"
 )
 
 // Server manages audio capture, hook dispatch, metrics, and control endpoints.
 type Server struct {
-	cfg       *config.Config
-	logger    *logging.Logger
-	hook      *hook.Runner
-	startedAt time.Time
-	lastHeard atomic.Int64
+	cfg        *config.Config
+	logger     *logging.Logger
+	hook       *hook.Runner
+	startedAt  time.Time
+	lastHeard  atomic.Int64
+	device     atomic.Value // string; active input device name
+	recognizer atomic.Value // asr.Recognizer; probed for optional stats interfaces
+	paused     timedFlag    // capture stopped via pause op
+	muted      timedFlag    // dispatch suppressed via mute_hooks op
+
+	opsOnce sync.Once
+	ops     map[string]controlOp // see controlOps
+	segMu   sync.Mutex           // serializes routeSegment
+	events  eventBus
 
 	transcriptsMu sync.Mutex
 	transcripts   []control.Transcript
 
-	metrics metrics
-	hookCh  chan hook.Job
+	resultsMu   sync.Mutex
+	hookResults []control.HookResult // last ui.status_tail hook jobs
+	conv        conversation
+
+	metrics   metrics
+	journal   *hook.Journal        // nil unless queue.persist
+	responder *responder.Responder // nil unless responder.enabled
+	poolsOnce sync.Once
+	pools     []*hookPool // see hookPools
 
 	wg sync.WaitGroup
 }
@@ -44,6 +62,12 @@ func Serve(cfg *config.Config, logger *logging.Logger) error {
 	if err := config.MustStatePaths(cfg); err != nil {
 		return err
 	}
+	if err := validateHooks(cfg); err != nil {
+		return err
+	}
+	if err := validateAPI(cfg); err != nil {
+		return err
+	}
 	// Write pid file.
 	if err := os.WriteFile(cfg.Paths.PidPath, []byte(fmt.Sprintf("%d", os.Getpid())), 0o600); err != nil {
 		return err
@@ -64,24 +88,50 @@ func Serve(cfg *config.Config, logger *logging.Logger) error {
 		hook:        hook.NewRunner(cfg, logger),
 		startedAt:   time.Now(),
 		transcripts: make([]control.Transcript, 0, cfg.UI.StatusTail),
-		hookCh:      make(chan hook.Job, max(1, hookQueueSize(cfg))),
 	}
 	srv.metrics.reset()
 
 	ctx, cancel := context.WithCancel(context.Background())
 	defer cancel()
 
+	// Persistent hook queue: replay what the last run left unfinished. This
+	// happens before any worker starts, so every job sees the journal.
+	if cfg.Queue.Persist {
+		journal, pending, err := hook.OpenJournal(cfg.Paths.JournalPath)
+		if err != nil {
+			return err
+		}
+		defer func() { _ = journal.Close() }()
+		srv.journal = journal
+		srv.replayJournal(pending)
+	}
+
+	// Audible feedback, ducking the mic while it plays. Built before the
+	// control socket, whose inject op can already play earcons.
+	if cfg.Responder.Enabled {
+		srv.responder = responder.New(cfg, logger, srv.duckCapture)
+	}
+
 	// Control socket
 	srv.goWorker(func() { srv.controlLoop(ctx) })
 
-	// Hook worker
-	srv.goWorker(func() { srv.hookWorker(ctx) })
+	if srv.responder != nil {
+		srv.goWorker(func() { srv.responder.Run(ctx) })
+	}
+
+	// Hook workers, per hook
+	srv.startHookWorkers(ctx)
 
 	// Metrics server
 	if cfg.Metrics.Enabled {
 		srv.goWorker(func() { srv.metricsServe(ctx.Done(), cfg.Metrics.Addr, logger) })
 	}
 
+	// HTTP/WebSocket API
+	if cfg.API.Enabled {
+		srv.goWorker(func() { srv.apiServe(ctx) })
+	}
+
 	// Watchdog
 	srv.goWorker(func() { srv.watchdog(ctx.Done()) })
 
@@ -115,8 +165,13 @@ func (s *Server) asrLoop(ctx context.Context) {
 	rec, err := asr.NewRecognizer(s.cfg, s.logger)
 	if err != nil {
 		s.logger.Errorf("asr init: %v", err)
+		s.publishError("asr init: %v", err)
 		return
 	}
+	if n, ok := rec.(deviceNotifier); ok {
+		n.OnDeviceChange(s.setDevice)
+	}
+	s.recognizer.Store(rec)
 	segCh := make(chan asr.Segment, 8)
 	runDone := make(chan error, 1)
 	go func() {
@@ -128,11 +183,13 @@ func (s *Server) asrLoop(ctx context.Context) {
 		case <-ctx.Done():
 			if err := <-runDone; err != nil && !errors.Is(err, context.Canceled) {
 				s.logger.Errorf("asr run: %v", err)
+				s.publishError("asr run: %v", err)
 			}
 			return
 		case err := <-runDone:
 			if err != nil && !errors.Is(err, context.Canceled) {
 				s.logger.Errorf("asr run: %v", err)
+				s.publishError("asr run: %v", err)
 			}
 			return
 		case seg := <-segCh:
@@ -142,117 +199,163 @@ func (s *Server) asrLoop(ctx context.Context) {
 }
 
 func (s *Server) handleSegment(ctx context.Context, seg asr.Segment) {
+	s.routeSegment(seg, seg.PushToTalk)
+}
+
+// routeSegment runs a transcript through wake gating, hook selection, and
+// dispatch, and returns what happened to it. skipWake bypasses the wake word
+// (push-to-talk, raw injection). Live and injected segments are serialized
+// so hook selection and cooldown see them one at a time.
+func (s *Server) routeSegment(seg asr.Segment, skipWake bool) string {
+	s.segMu.Lock()
+	defer s.segMu.Unlock()
 	text := strings.TrimSpace(seg.Text)
 	if text == "" {
-		return
+		return "empty"
 	}
 	original := text
+	wake := ""
+	convIdx, inConversation := 0, false
 	s.lastHeard.Store(time.Now().UnixNano())
 	s.metrics.incHeard()
 	s.logger.Infof("heard: %q", text)
-	if !seg.Partial {
+	if seg.Partial {
+		s.events.publish(control.Event{Type: control.EventPartial, Text: text})
+	} else {
+		s.events.publish(control.Event{Type: control.EventFinal, Text: text})
 		s.recordTranscript(text)
 	}
-	if s.cfg.Wake.Enabled {
-		if !wakeMatches(text, s.cfg.Wake.Word, s.cfg.Wake.Aliases) {
-			return
+	if skipWake {
+		s.logger.Info("wake word not required for this segment")
+		s.events.publish(control.Event{Type: control.EventWake, Text: text})
+	} else if s.cfg.Wake.Enabled {
+		var ok bool
+		if wake, ok = hook.MatchWake(text, s.cfg.Wake.Word, s.cfg.Wake.Aliases); ok {
+			s.logger.Infof("wake word matched: %q", s.cfg.Wake.Word)
+			text = hook.StripWake(text, s.cfg.Wake.Word, s.cfg.Wake.Aliases)
+		} else if convIdx, inConversation = s.conv.active(time.Now()); inConversation {
+			s.logger.Infof("conversation with hook #%d open; wake word not required", convIdx)
+		} else {
+			return "no wake word"
+		}
+		s.events.publish(control.Event{Type: control.EventWake, Text: text})
+	}
+	// Finals only: an earcon during a partial would duck the rest of the
+	// utterance away.
+	if (skipWake || s.cfg.Wake.Enabled) && !seg.Partial {
+		s.earcon(responder.Wake)
+	}
+	// Select hook by wake tokens or patterns (first match wins); a follow-up
+	// in an open conversation goes back to the hook that asked for it.
+	hk, idx, slots := hook.Route(s.cfg, original, text)
+	if inConversation {
+		hk, idx, slots = nil, convIdx, nil
+		if p := s.hookPool(idx); p != nil {
+			hk = p.hook
 		}
-		s.logger.Infof("wake word matched: %q", s.cfg.Wake.Word)
-		text = removeWakeWord(text, s.cfg.Wake.Word, s.cfg.Wake.Aliases)
 	}
-	// Select hook based on wake tokens (first match wins).
-	hk, idx := hook.SelectHookConfig(s.cfg, original)
 	if hk == nil {
 		s.logger.Warn("no matching hook configured; skipping")
-		return
+		return "no matching hook"
+	}
+	pool := s.hookPool(idx)
+	if pool == nil {
+		s.logger.Warnf("hook #%d has no queue; skipping", idx)
+		return "no matching hook"
 	}
-	s.hook.SelectHook(hk)
-	s.logger.Infof("hook selected: #%d cmd=%q", idx, hk.Command)
+	hk = pool.hook
+	s.logger.Infof("hook selected: #%d target=%q", idx, hook.Target(hk))
+	selected := fmt.Sprintf("hook #%d (%s)", idx, hook.Target(hk))
 
 	if seg.Partial {
-		return
+		return "partial; " + selected + " not run"
 	}
 	if hk.MinChars > 0 && len(text) < hk.MinChars {
-		return
+		return fmt.Sprintf("shorter than min_chars %d for %s", hk.MinChars, selected)
 	}
 
-	if !s.hook.ShouldRun() {
+	if s.hooksMuted() {
+		s.logger.Info("hooks muted; not dispatching")
+		s.metrics.incSkipped()
+		return "hooks muted; " + selected + " not run"
+	}
+	if !s.hook.ShouldRun(idx, hk) {
 		s.logger.Debug("hook skipped (cooldown)")
 		s.metrics.incSkipped()
-		return
+		return "cooldown; " + selected + " not run"
+	}
+	now := time.Now()
+	if !seg.End.IsZero() && !seg.Transcribed.IsZero() {
+		s.metrics.asrLatency.observe(seg.Transcribed.Sub(seg.End))
 	}
-	s.logger.Infof("dispatching hook payload: %q", text)
 	job := hook.Job{
-		Text:      text,
-		Timestamp: time.Now(),
+		Text:        text,
+		Timestamp:   now,
+		Hook:        hk,
+		HookIndex:   idx,
+		RawText:     original,
+		Wake:        wake,
+		Confidence:  seg.Confidence,
+		Language:    seg.Language,
+		Device:      s.activeDevice(),
+		Slots:       slots,
+		Captured:    seg.End,
+		Transcribed: seg.Transcribed,
+		StalePolicy: hk.StalePolicy,
+	}
+	if hk.MaxLatency > 0 && !seg.End.IsZero() {
+		job.Deadline = seg.End.Add(time.Duration(hk.MaxLatency) * time.Millisecond)
+	}
+	if !s.admitLatency(&job, now) {
+		return "stale; " + selected + " not run"
 	}
-	select {
-	case s.hookCh <- job:
-	default:
-		s.metrics.incDropped()
-		s.logger.Warn("hook queue full, dropping job")
+	s.logger.Infof("dispatching hook payload: %q", text)
+	queued := s.enqueueJob(pool, job)
+	s.fanOut(job, now)
+	if !queued {
+		return "queue full for " + selected + "; dropped"
 	}
-}
-
-func removeWakeWord(text, word string, aliases []string) string {
-	variants := wakeVariants(word, aliases)
-	fields := strings.Fields(text)
-	out := make([]string, 0, len(fields))
-	skipped := false
-	for _, f := range fields {
-		if !skipped && matchesAny(stripPunct(f), variants) {
-			skipped = true
-			continue
-		}
-		out = append(out, f)
+	msg := fmt.Sprintf("queued %q for %s", text, selected)
+	if n := len(hk.FanOut); n > 0 {
+		msg += fmt.Sprintf(" and %d fan-out hook(s)", n)
 	}
-	return strings.Join(out, " ")
-}
-
-func stripPunct(s string) string {
-	return strings.Trim(s, " ,.!?;:\"'")
+	return msg
 }
 
-func wakeMatches(text, word string, aliases []string) bool {
-	lower := strings.ToLower(text)
-	for _, v := range wakeVariants(word, aliases) {
-		if strings.Contains(lower, v) {
-			return true
+func validateHooks(cfg *config.Config) error {
+	hooks := cfg.EffectiveHooks()
+	for i, hk := range hooks {
+		switch hk.StalePolicy {
+		case "", config.StaleDrop, config.StaleMark:
+		default:
+			return fmt.Errorf("hooks[%d].stale_policy must be %q or %q (got %q)", i, config.StaleDrop, config.StaleMark, hk.StalePolicy)
 		}
-	}
-	return false
-}
-
-func wakeVariants(word string, aliases []string) []string {
-	v := []string{strings.ToLower(word)}
-	for _, a := range aliases {
-		a = strings.ToLower(strings.TrimSpace(a))
-		if a == "" {
-			continue
+		if err := hook.Validate(&hk); err != nil {
+			return fmt.Errorf("hooks[%d]: %w", i, err)
 		}
-		v = append(v, a)
-	}
-	return v
-}
-
-func matchesAny(token string, variants []string) bool {
-	for _, v := range variants {
-		if strings.EqualFold(token, v) {
-			return true
+		if r := hk.ReplyHook; r != nil && (*r < 0 || *r >= len(hooks) || *r == i) {
+			return fmt.Errorf("hooks[%d].reply_hook must be another hook's index (got %d of %d hooks)", i, *r, len(hooks))
 		}
-	}
-	return false
-}
-
-func hookQueueSize(cfg *config.Config) int {
-	maxQ := 16
-	hooks := cfg.EffectiveHooks()
-	for i := range hooks {
-		if hooks[i].QueueSize > maxQ {
-			maxQ = hooks[i].QueueSize
+		steps := make([]int, len(hk.Chain))
+		for n, step := range hk.Chain {
+			steps[n] = step.Hook
+		}
+		for _, ref := range []struct {
+			field   string
+			targets []int
+		}{{"fan_out", hk.FanOut}, {"chain", steps}} {
+			for _, t := range ref.targets {
+				if t < 0 || t >= len(hooks) || t == i {
+					return fmt.Errorf("hooks[%d].%s must list other hooks' indexes (got %d of %d hooks)", i, ref.field, t, len(hooks))
+				}
+				// One level only, so routing cannot loop.
+				if len(hooks[t].FanOut) > 0 || len(hooks[t].Chain) > 0 {
+					return fmt.Errorf("hooks[%d].%s: hooks[%d] has its own fan_out or chain", i, ref.field, t)
+				}
+			}
 		}
 	}
-	return maxQ
+	return nil
 }
 
 func (s *Server) recordTranscript(text string) {
@@ -343,35 +446,65 @@ func (s *Server) handleConn(ctx context.Context, conn net.Conn) {
 		}
 	}()
 	sc := bufio.NewScanner(conn)
-	if !sc.Scan() {
-		if err := sc.Err(); err != nil {
-			s.logger.Warnf("control read: %v", err)
+	enc := json.NewEncoder(conn)
+	for sc.Scan() {
+		line := bytes.TrimSpace(sc.Bytes())
+		if len(line) == 0 {
+			continue
 		}
-		return
-	}
-	var req control.Request
-	if err := json.Unmarshal(sc.Bytes(), &req); err != nil {
-		s.logger.Warnf("control unmarshal: %v", err)
-		return
-	}
-	switch req.Op {
-	case "status":
-		resp := control.Status{
-			Running:     true,
-			UptimeSec:   time.Since(s.startedAt).Seconds(),
-			Transcripts: s.copyTranscripts(),
+		reply, stream := s.handleRequest(line)
+		if err := enc.Encode(reply); err != nil {
+			if stream != nil {
+				stream.close()
+			}
+			if ctx.Err() == nil {
+				s.logger.Warnf("control write: %v", err)
+			}
+			return
 		}
-		if err := json.NewEncoder(conn).Encode(resp); err != nil {
-			s.logger.Warnf("control write status: %v", err)
+		if stream != nil {
+			s.followStream(ctx, sc, enc, stream)
+			return
 		}
-	case "health":
-		if err := json.NewEncoder(conn).Encode(control.SimpleResponse{OK: true, Message: "ok"}); err != nil {
-			s.logger.Warnf("control write health: %v", err)
+	}
+	if err := sc.Err(); err != nil && ctx.Err() == nil {
+		s.logger.Warnf("control read: %v", err)
+	}
+}
+
+// followStream hands the connection to a streaming op. Further input only
+// serves to detect the client going away.
+func (s *Server) followStream(ctx context.Context, sc *bufio.Scanner, enc *json.Encoder, stream *controlStream) {
+	defer stream.close()
+	streamCtx, cancel := context.WithCancel(ctx)
+	defer cancel()
+	go func() {
+		for sc.Scan() {
 		}
-	default:
-		// ignore unknown
+		cancel()
+	}()
+	if err := stream.follow(streamCtx, enc); err != nil && streamCtx.Err() == nil {
+		s.logger.Debugf("control stream: %v", err)
 	}
 }
+
+func (s *Server) status() control.Status {
+	st := control.Status{
+		Running:       true,
+		UptimeSec:     time.Since(s.startedAt).Seconds(),
+		Device:        s.activeDevice(),
+		Model:         s.activeModel(),
+		ModelUnloaded: s.modelUnloaded(),
+		Transcripts:   s.copyTranscripts(),
+	}
+	st.Paused, st.PausedUntil = s.paused.state()
+	st.HooksMuted, st.MutedUntil = s.muted.state()
+	st.Talking = s.talking()
+	st.ConversationUntil = s.conv.state()
+	st.HookResults = s.copyHookResults()
+	return st
+}
+
 func (s *Server) copyTranscripts() []control.Transcript {
 	s.transcriptsMu.Lock()
 	defer s.transcriptsMu.Unlock()
@@ -379,3 +512,81 @@ func (s *Server) copyTranscripts() []control.Transcript {
 	copy(out, s.transcripts)
 	return out
 }
+
+// admitLatency enforces max_latency_ms for job at now. It returns false when
+// the job should be discarded; under stale_policy "mark" the job is flagged
+// and kept.
+func (s *Server) admitLatency(job *hook.Job, now time.Time) bool {
+	if job.Stale || !job.Expired(now) {
+		return true
+	}
+	s.metrics.incStale()
+	latency := job.Latency(now).Milliseconds()
+	if job.StalePolicy == config.StaleMark {
+		s.logger.Warnf("hook job stale (%d ms since speech); running marked", latency)
+		job.Stale = true
+		return true
+	}
+	s.logger.Warnf("hook job stale (%d ms since speech); dropping", latency)
+	return false
+}
+
+// modelSwapper is implemented by recognizers that can replace their model
+// while running.
+type modelSwapper interface {
+	SwapModel(path string) error
+	ActiveModel() string
+}
+
+// useModel hot-swaps the ASR model; it blocks until the new model is loaded.
+func (s *Server) useModel(path string) control.SimpleResponse {
+	if strings.TrimSpace(path) == "" {
+		return control.SimpleResponse{Message: "model path required"}
+	}
+	swapper, ok := s.recognizer.Load().(modelSwapper)
+	if !ok {
+		return control.SimpleResponse{Message: "recognizer not running or cannot swap models"}
+	}
+	s.logger.Infof("loading model %s", path)
+	if err := swapper.SwapModel(path); err != nil {
+		s.logger.Warnf("model swap: %v", err)
+		return control.SimpleResponse{Message: err.Error()}
+	}
+	return control.SimpleResponse{OK: true, Message: path}
+}
+
+func (s *Server) modelUnloaded() bool {
+	if mr, ok := s.recognizer.Load().(modelStatsReporter); ok {
+		return !mr.ModelStats().Loaded
+	}
+	return false
+}
+
+func (s *Server) activeModel() string {
+	if swapper, ok := s.recognizer.Load().(modelSwapper); ok {
+		return swapper.ActiveModel()
+	}
+	return ""
+}
+
+// deviceNotifier is implemented by recognizers that report which input
+// device they capture from.
+type deviceNotifier interface {
+	OnDeviceChange(fn func(name string))
+}
+
+func (s *Server) setDevice(name string) {
+	s.device.Store(name)
+	s.logger.Infof("active input device: %s", name)
+	s.events.publish(control.Event{Type: control.EventDevice, Device: name})
+}
+
+// publishError surfaces a failure to event subscribers.
+func (s *Server) publishError(format string, args ...any) {
+	s.events.publish(control.Event{Type: control.EventError, Error: fmt.Sprintf(format, args...)})
+}
+
+func (s *Server) activeDevice() string {
+	name, _ := s.device.Load().(string)
+	return name
+}
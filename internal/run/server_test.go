@@ -3,19 +3,32 @@ package run
 import (
 	"bytes"
 	"context"
+	"encoding/json"
+	"errors"
 	"io"
 	"log/slog"
 	"net"
 	"os"
 	"path/filepath"
+	"strings"
 	"testing"
 	"time"
 
+	"brabble/internal/asr"
 	"brabble/internal/config"
+	"brabble/internal/control"
 	"brabble/internal/hook"
 	"brabble/internal/logging"
 )
 
+// newTestServer returns a Server for cfg with a hook runner, as Serve builds
+// it, but without a recognizer, sockets or running workers.
+func newTestServer(t *testing.T, cfg *config.Config) *Server {
+	t.Helper()
+	logger := logging.NewTestLogger()
+	return &Server{cfg: cfg, logger: logger, hook: hook.NewRunner(cfg, logger), startedAt: time.Now()}
+}
+
 func TestSelectHookConfigMatchesWakeTokens(t *testing.T) {
 	cfg, _ := config.Default()
 	cfg.Hooks = []config.HookConfig{
@@ -34,15 +47,6 @@ func TestSelectHookConfigMatchesWakeTokens(t *testing.T) {
 	}
 }
 
-func TestWakeMatchesAliases(t *testing.T) {
-	if !wakeMatches("hi Claude", "clawd", []string{"claude", "cloud"}) {
-		t.Fatalf("expected alias match")
-	}
-	if wakeMatches("hi there", "clawd", []string{"claude"}) {
-		t.Fatalf("expected no match")
-	}
-}
-
 func TestControlLoopStopsOnCancellation(t *testing.T) {
 	socketFile, err := os.CreateTemp("/tmp", "brabble-*.sock")
 	if err != nil {
@@ -156,3 +160,149 @@ func TestRecordTranscriptRestrictsPermissions(t *testing.T) {
 		t.Fatalf("transcript mode = %o, want 600", got)
 	}
 }
+
+func TestStatusReportsActiveDevice(t *testing.T) {
+	srv := newTestServer(t, nil)
+	srv.setDevice("AirPods")
+	serverConn, clientConn := net.Pipe()
+	go srv.handleConn(context.Background(), serverConn)
+	defer func() { _ = clientConn.Close() }()
+
+	if _, err := io.WriteString(clientConn, `{"op":"status"}`+"\n"); err != nil {
+		t.Fatalf("write request: %v", err)
+	}
+	var status control.Status
+	if err := json.NewDecoder(clientConn).Decode(&status); err != nil {
+		t.Fatalf("decode status: %v", err)
+	}
+	if status.Device != "AirPods" {
+		t.Fatalf("device=%q want AirPods", status.Device)
+	}
+}
+
+func TestAdmitLatencyDropsOrMarksStaleJobs(t *testing.T) {
+	srv := newTestServer(t, nil)
+	now := time.Now()
+	stale := hook.Job{Captured: now.Add(-3 * time.Second), Deadline: now.Add(-time.Second)}
+
+	drop := stale
+	drop.StalePolicy = config.StaleDrop
+	if srv.admitLatency(&drop, now) {
+		t.Fatal("stale job admitted under drop policy")
+	}
+	mark := stale
+	mark.StalePolicy = config.StaleMark
+	if !srv.admitLatency(&mark, now) || !mark.Stale {
+		t.Fatalf("mark policy should keep and flag job: %+v", mark)
+	}
+	fresh := hook.Job{Captured: now, Deadline: now.Add(time.Second)}
+	if !srv.admitLatency(&fresh, now) || fresh.Stale {
+		t.Fatalf("fresh job rejected or flagged: %+v", fresh)
+	}
+	if got := srv.metrics.stale.Load(); got != 2 {
+		t.Fatalf("stale counter=%d want 2", got)
+	}
+}
+
+func TestValidateStalePolicies(t *testing.T) {
+	cfg, _ := config.Default()
+	cfg.Hooks = []config.HookConfig{{Command: "/bin/true"}, {Command: "/bin/true", StalePolicy: "queue"}}
+	if err := validateHooks(cfg); err == nil {
+		t.Fatal("expected error for unknown stale_policy")
+	}
+	cfg.Hooks[1].StalePolicy = config.StaleMark
+	if err := validateHooks(cfg); err != nil {
+		t.Fatalf("validate: %v", err)
+	}
+}
+
+type swapRecognizer struct {
+	model string
+}
+
+func (r *swapRecognizer) Run(ctx context.Context, _ chan<- asr.Segment) error {
+	<-ctx.Done()
+	return ctx.Err()
+}
+
+func (r *swapRecognizer) SwapModel(path string) error {
+	if path == "bad.bin" {
+		return errors.New("load model: unable to load model")
+	}
+	r.model = path
+	return nil
+}
+
+func (r *swapRecognizer) ActiveModel() string { return r.model }
+
+func TestUseModelSwapsRecognizerModel(t *testing.T) {
+	srv := newTestServer(t, nil)
+	if resp := srv.useModel("small.bin"); resp.OK {
+		t.Fatal("swap without a recognizer should fail")
+	}
+	rec := &swapRecognizer{model: "large.bin"}
+	srv.recognizer.Store(asr.Recognizer(rec))
+	if resp := srv.useModel("bad.bin"); resp.OK || srv.activeModel() != "large.bin" {
+		t.Fatalf("failed swap changed model: %+v active=%q", resp, srv.activeModel())
+	}
+	if resp := srv.useModel("small.bin"); !resp.OK || srv.activeModel() != "small.bin" {
+		t.Fatalf("swap failed: %+v active=%q", resp, srv.activeModel())
+	}
+}
+
+func TestPushToTalkSegmentSkipsWakeWord(t *testing.T) {
+	cfg, _ := config.Default()
+	cfg.Hooks = []config.HookConfig{{Command: "/bin/true"}}
+	cfg.Transcripts.Enabled = false
+	srv := newTestServer(t, cfg)
+	text := "turn the kitchen lights off please"
+	srv.handleSegment(context.Background(), asr.Segment{Text: text})
+	if queuedJobs(srv) != 0 {
+		t.Fatal("segment without wake word dispatched")
+	}
+	srv.handleSegment(context.Background(), asr.Segment{Text: text, PushToTalk: true})
+	if queuedJobs(srv) != 1 {
+		t.Fatal("push-to-talk segment did not dispatch")
+	}
+	if job := <-srv.hookPools()[0].queue; job.Text != text {
+		t.Fatalf("job text = %q", job.Text)
+	}
+}
+
+func TestRoutedJobCarriesTemplateContext(t *testing.T) {
+	cfg, _ := config.Default()
+	cfg.Transcripts.Enabled = false
+	cfg.Wake.Aliases = []string{"claude"}
+	cfg.Hooks = []config.HookConfig{{Command: "/bin/true"}}
+	srv := newTestServer(t, cfg)
+	srv.device.Store("AirPods")
+	srv.handleSegment(context.Background(), asr.Segment{Text: "Claude, lights off please", Confidence: 0.8})
+	job := <-srv.hookPools()[0].queue
+	if job.Text != "lights off please" || job.RawText != "Claude, lights off please" || job.Wake != "claude" || job.Device != "AirPods" || job.Confidence != 0.8 {
+		t.Fatalf("job %+v", job)
+	}
+}
+
+func TestIntentRoutingCarriesSlots(t *testing.T) {
+	cfg, _ := config.Default()
+	cfg.Transcripts.Enabled = false
+	cfg.Hooks = []config.HookConfig{
+		{Wake: []string{"music"}, Command: "/bin/true"},
+		{Match: config.MatchIntent, Patterns: []string{"turn {state:on|off} the {device}"}, Command: "/bin/sh", Args: []string{"-c", `echo "$BRABBLE_SLOT_DEVICE"`}},
+	}
+	if err := validateHooks(cfg); err != nil {
+		t.Fatalf("validate: %v", err)
+	}
+	srv := newTestServer(t, cfg)
+	srv.routeSegment(asr.Segment{Text: "Clawd, turn off the hallway lights."}, false)
+	job := <-srv.hookPools()[1].queue
+	if job.Slots["state"] != "off" || job.Slots["device"] != "hallway lights" {
+		t.Fatalf("slots %v", job.Slots)
+	}
+
+	// test_hook strips the wake word too, so it can reach intent hooks.
+	res, err := srv.testHook("clawd turn on the fan")
+	if err != nil || !strings.HasSuffix(res.(control.SimpleResponse).Message, ": fan") {
+		t.Fatalf("test_hook: %+v %v", res, err)
+	}
+}